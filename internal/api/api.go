@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
 	"github.com/rs/zerolog"
 )
 
@@ -53,6 +54,7 @@ func Init() {
 	HandleFunc("api/exit", exitHandler)
 	HandleFunc("api/restart", restartHandler)
 	HandleFunc("api/log", logHandler)
+	HandleFunc("api/token", tokenHandler)
 
 	Handler = http.DefaultServeMux // 4th
 
@@ -60,7 +62,7 @@ func Init() {
 		Handler = middlewareCORS(Handler) // 3rd
 	}
 
-	if cfg.Mod.Username != "" {
+	if cfg.Mod.Username != "" || auth.Enabled() {
 		Handler = middlewareAuth(cfg.Mod.Username, cfg.Mod.Password, Handler) // 2nd
 	}
 
@@ -197,24 +199,35 @@ func middlewareLog(next http.Handler) http.Handler {
 
 func middlewareAuth(username, password string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := auth.Admin
+
 		if !strings.HasPrefix(r.RemoteAddr, "127.") && !strings.HasPrefix(r.RemoteAddr, "[::1]") && r.RemoteAddr != "@" {
-			user, pass, ok := r.BasicAuth()
-			if !ok || user != username || pass != password {
-				w.Header().Set("Www-Authenticate", `Basic realm="go2rtc"`)
+			if id = identify(r, username, password); id == nil {
+				if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+					w.Header().Set("Www-Authenticate", `Bearer realm="go2rtc", error="invalid_token"`)
+				} else {
+					w.Header().Set("Www-Authenticate", `Basic realm="go2rtc"`)
+				}
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
+
+			if !allowRequest(id, r) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), id)))
 	})
 }
 
 func middlewareCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "Location, ETag, Accept-Patch")
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/auth"
+)
+
+// identify - get identity from playback token or basic auth credentials
+func identify(r *http.Request, username, password string) *auth.Identity {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+
+	if token != "" {
+		id, err := auth.ParseToken(token)
+		if err != nil {
+			log.Debug().Err(err).Str("remote_addr", r.RemoteAddr).Msg("[api] token")
+		}
+		return id
+	}
+
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	if username != "" &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1 {
+		return auth.Admin
+	}
+
+	return auth.Login(user, pass)
+}
+
+// allowRequest - users and tokens can only use streams from their ACL.
+// The src param is used for viewing and the dst param is used for publishing.
+func allowRequest(id *auth.Identity, r *http.Request) bool {
+	if id.Admin {
+		return true
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, basePath)
+	if !strings.HasPrefix(path, "/api") {
+		return true // static files
+	}
+
+	query := r.URL.Query()
+
+	// HLS playlists and segments are protected by the random session ID
+	if strings.HasPrefix(path, "/api/hls/") && query.Has("id") && !query.Has("src") {
+		return true
+	}
+
+	// only admins can change streams or create new streams with the name
+	if (path == "/api/streams" && r.Method != "GET") || query.Has("name") {
+		return false
+	}
+
+	if query.Get("src") == "" && query.Get("dst") == "" {
+		return false
+	}
+
+	// some handlers use all values (ex. DOT graph for many streams)
+	for _, src := range query["src"] {
+		if !id.Allowed(src, auth.View) {
+			return false
+		}
+	}
+
+	for _, dst := range query["dst"] {
+		if !id.Allowed(dst, auth.Publish) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tokenHandler - create playback token for the stream with the scope of the current user
+func tokenHandler(w http.ResponseWriter, r *http.Request) {
+	id := auth.FromRequest(r)
+	if id.IsToken() {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+
+	claims := &auth.Claims{
+		Subject: id.User,
+		Stream:  query.Get("src"),
+	}
+
+	if claims.Stream == "" {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	if s := query.Get("scope"); s != "" {
+		claims.Scope = strings.Split(s, ",")
+	} else {
+		claims.Scope = []string{auth.View}
+	}
+
+	for _, action := range claims.Scope {
+		if !id.Allowed(claims.Stream, action) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	ttl := time.Hour
+	if s := query.Get("ttl"); s != "" {
+		if i, err := strconv.Atoi(s); err == nil {
+			ttl = time.Duration(i) * time.Second
+		} else if ttl, err = time.ParseDuration(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	claims.Expires = time.Now().Add(ttl).Unix()
+
+	token, err := auth.NewToken(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ResponseJSON(w, map[string]any{"token": token, "expires": claims.Expires})
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/rs/zerolog"
+)
+
+// stream actions
+const (
+	View        = "view"
+	Publish     = "publish"
+	Backchannel = "backchannel"
+)
+
+func Init() {
+	var cfg struct {
+		Mod struct {
+			Secret string           `yaml:"secret"`
+			Users  map[string]*user `yaml:"users"`
+			ACL    map[string]*acl  `yaml:"acl"`
+		} `yaml:"auth"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	log = app.GetLogger("auth")
+
+	secret = []byte(cfg.Mod.Secret)
+	users = cfg.Mod.Users
+	acls = cfg.Mod.ACL
+
+	if Enabled() {
+		log.Info().Int("users", len(users)).Int("acl", len(acls)).Msg("[auth] enabled")
+	}
+}
+
+var log zerolog.Logger
+
+var secret []byte
+var users map[string]*user
+var acls map[string]*acl
+
+type user struct {
+	Password string   `yaml:"password"`
+	Roles    []string `yaml:"roles"`
+}
+
+// acl - list of usernames or roles for each action, "*" - any user
+type acl struct {
+	View        []string `yaml:"view"`
+	Publish     []string `yaml:"publish"`
+	Backchannel []string `yaml:"backchannel"`
+}
+
+func (a *acl) names(action string) []string {
+	switch action {
+	case View:
+		return a.View
+	case Publish:
+		return a.Publish
+	case Backchannel:
+		return a.Backchannel
+	}
+	return nil
+}
+
+// Enabled - per-user access is configured, servers should check every client
+func Enabled() bool {
+	return len(secret) > 0 || len(users) > 0 || len(acls) > 0
+}
+
+// Identity - authorized client: admin, user from config or playback token
+type Identity struct {
+	User  string
+	Roles []string
+	Admin bool
+
+	claims *Claims
+}
+
+// Admin - localhost, global credentials of the api/rtsp modules or disabled auth
+var Admin = &Identity{Admin: true}
+
+// Login - check username and password of the user from config
+func Login(username, password string) *Identity {
+	return LoginFunc(username, func(s string) bool {
+		return subtle.ConstantTimeCompare([]byte(s), []byte(password)) == 1
+	})
+}
+
+// LoginFunc - check the user from config with custom password validation, ex. Digest
+func LoginFunc(username string, valid func(password string) bool) *Identity {
+	u := users[username]
+	if u == nil || !valid(u.Password) {
+		return nil
+	}
+
+	if slices.Contains(u.Roles, "admin") {
+		return &Identity{User: username, Roles: u.Roles, Admin: true}
+	}
+
+	return &Identity{User: username, Roles: u.Roles}
+}
+
+// Allowed - check if identity can do action with the stream
+func (i *Identity) Allowed(stream, action string) bool {
+	if i == nil {
+		return false
+	}
+
+	if i.Admin {
+		return true
+	}
+
+	if i.claims != nil {
+		return i.claims.allowed(stream, action)
+	}
+
+	rule := acls[stream]
+	if rule == nil && !isSource(stream) {
+		rule = acls["*"]
+	}
+	if rule == nil {
+		return false
+	}
+
+	for _, name := range rule.names(action) {
+		if name == "*" || name == i.User || slices.Contains(i.Roles, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsToken - identity from playback token, can't be used to create new tokens
+func (i *Identity) IsToken() bool {
+	return i.claims != nil
+}
+
+type ctxKey struct{}
+
+func WithIdentity(ctx context.Context, i *Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, i)
+}
+
+// FromRequest - identity of the request, authorized by the api module.
+// Requests without identity are only possible with disabled auth.
+func FromRequest(r *http.Request) *Identity {
+	if i, ok := r.Context().Value(ctxKey{}).(*Identity); ok {
+		return i
+	}
+	return Admin
+}
+
+// isSource - stream names can't have a scheme, temporary streams from
+// source URLs are only allowed for admins
+func isSource(s string) bool {
+	return strings.IndexByte(s, ':') > 0
+}
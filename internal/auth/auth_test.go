@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowed(t *testing.T) {
+	users = map[string]*user{
+		"alice": {Password: "123", Roles: []string{"tenant1"}},
+		"bob":   {Password: "456"},
+		"root":  {Password: "789", Roles: []string{"admin"}},
+	}
+	acls = map[string]*acl{
+		"camera1": {View: []string{"tenant1", "bob"}, Backchannel: []string{"alice"}},
+		"*":       {View: []string{"*"}},
+	}
+	defer func() { users, acls = nil, nil }()
+
+	require.Nil(t, Login("alice", "456"))
+	require.Nil(t, Login("carol", ""))
+
+	alice := Login("alice", "123")
+	require.True(t, alice.Allowed("camera1", View))
+	require.True(t, alice.Allowed("camera1", Backchannel))
+	require.False(t, alice.Allowed("camera1", Publish))
+
+	bob := Login("bob", "456")
+	require.True(t, bob.Allowed("camera1", View))
+	require.False(t, bob.Allowed("camera1", Backchannel))
+
+	// default ACL only for stream names
+	require.True(t, bob.Allowed("camera2", View))
+	require.False(t, bob.Allowed("rtsp://192.168.1.123/stream1", View))
+
+	require.True(t, Login("root", "789").Allowed("rtsp://192.168.1.123/stream1", Publish))
+}
+
+func TestToken(t *testing.T) {
+	secret = []byte("secret")
+	defer func() { secret = nil }()
+
+	token, err := NewToken(&Claims{Stream: "camera1", Expires: time.Now().Add(time.Minute).Unix()})
+	require.Nil(t, err)
+
+	id, err := ParseToken(token)
+	require.Nil(t, err)
+	require.True(t, id.IsToken())
+	require.True(t, id.Allowed("camera1", View))
+	require.False(t, id.Allowed("camera1", Publish))
+	require.False(t, id.Allowed("camera2", View))
+
+	// change stream in payload
+	parts := strings.Split(token, ".")
+	parts[1] = b64.EncodeToString([]byte(`{"stream":"camera2","exp":9999999999}`))
+	_, err = ParseToken(strings.Join(parts, "."))
+	require.ErrorIs(t, err, ErrWrongToken)
+
+	token, err = NewToken(&Claims{Stream: "camera1", Expires: time.Now().Unix() - 1})
+	require.Nil(t, err)
+	_, err = ParseToken(token)
+	require.ErrorIs(t, err, ErrTokenExpired)
+}
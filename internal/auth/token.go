@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Claims - payload of the playback token (JWT with HS256 signature)
+type Claims struct {
+	Subject string   `json:"sub,omitempty"`
+	Stream  string   `json:"stream"`
+	Scope   []string `json:"scope,omitempty"` // default - view only
+	Expires int64    `json:"exp"`
+}
+
+func (c *Claims) allowed(stream, action string) bool {
+	if c.Stream != stream {
+		return false
+	}
+	if c.Scope == nil {
+		return action == View
+	}
+	return slices.Contains(c.Scope, action)
+}
+
+var (
+	ErrNoSecret     = errors.New("auth: secret not set")
+	ErrWrongToken   = errors.New("auth: wrong token")
+	ErrTokenExpired = errors.New("auth: token expired")
+)
+
+// jwtHeader - {"alg":"HS256","typ":"JWT"}
+const jwtHeader = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9"
+
+var b64 = base64.RawURLEncoding
+
+// NewToken - sign claims with secret from config
+func NewToken(claims *Claims) (string, error) {
+	if len(secret) == 0 {
+		return "", ErrNoSecret
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	s := jwtHeader + "." + b64.EncodeToString(payload)
+	return s + "." + b64.EncodeToString(sign(s)), nil
+}
+
+// ParseToken - check signature and expiration of the token
+func ParseToken(token string) (*Identity, error) {
+	if len(secret) == 0 {
+		return nil, ErrNoSecret
+	}
+
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return nil, ErrWrongToken
+	}
+
+	sig, err := b64.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(sig, sign(token[:i])) {
+		return nil, ErrWrongToken
+	}
+
+	header, payload, ok := strings.Cut(token[:i], ".")
+	if !ok {
+		return nil, ErrWrongToken
+	}
+
+	// protection from "alg":"none" and other algorithms
+	b, err := b64.DecodeString(header)
+	if err != nil {
+		return nil, ErrWrongToken
+	}
+
+	var jwt struct {
+		Alg string `json:"alg"`
+	}
+	if err = json.Unmarshal(b, &jwt); err != nil || jwt.Alg != "HS256" {
+		return nil, ErrWrongToken
+	}
+
+	if b, err = b64.DecodeString(payload); err != nil {
+		return nil, ErrWrongToken
+	}
+
+	claims := &Claims{}
+	if err = json.Unmarshal(b, claims); err != nil || claims.Stream == "" {
+		return nil, ErrWrongToken
+	}
+
+	if time.Now().Unix() >= claims.Expires {
+		return nil, ErrTokenExpired
+	}
+
+	return &Identity{User: claims.Subject, claims: claims}, nil
+}
+
+func sign(s string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
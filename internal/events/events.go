@@ -0,0 +1,131 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api/ws"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var cfg struct {
+		Webhooks []*webhook `yaml:"webhooks"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	log = app.GetLogger("events")
+
+	for _, hook := range cfg.Webhooks {
+		if hook.URL == "" {
+			continue
+		}
+		hook.start()
+	}
+
+	ws.HandleFunc("events", wsHandler)
+}
+
+var log zerolog.Logger
+
+// Event - stream lifecycle event
+type Event struct {
+	Type   string         `json:"type"`
+	Time   time.Time      `json:"time"`
+	Stream string         `json:"stream,omitempty"`
+	Data   map[string]any `json:"data,omitempty"`
+}
+
+type Handler func(event *Event)
+
+// Publish - send event to all subscribers. Subscribers shouldn't block.
+func Publish(typ, stream string, data map[string]any) {
+	event := &Event{Type: typ, Time: time.Now(), Stream: stream, Data: data}
+
+	handlersMu.Lock()
+	handlers := make([]Handler, 0, len(subscribers))
+	for _, handler := range subscribers {
+		handlers = append(handlers, handler)
+	}
+	handlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// Subscribe - returns function for unsubscribe
+func Subscribe(handler Handler) func() {
+	handlersMu.Lock()
+	id := nextID
+	nextID++
+	subscribers[id] = handler
+	handlersMu.Unlock()
+
+	return func() {
+		handlersMu.Lock()
+		delete(subscribers, id)
+		handlersMu.Unlock()
+	}
+}
+
+var subscribers = map[int]Handler{}
+var nextID int
+var handlersMu sync.Mutex
+
+// match - empty filter matches all events
+func match(filter []string, typ string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, s := range filter {
+		if s == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// wsHandler - stream events over WebSocket, optional value with list of event types
+func wsHandler(tr *ws.Transport, msg *ws.Message) error {
+	var filter []string
+	_ = msg.Unmarshal(&filter)
+
+	// users get events only for their streams
+	id := auth.FromRequest(tr.Request)
+
+	ch := make(chan *Event, 100)
+
+	unsubscribe := Subscribe(func(event *Event) {
+		if !match(filter, event.Type) || !id.Allowed(event.Stream, auth.View) {
+			return
+		}
+		select {
+		case ch <- event:
+		default: // slow client
+		}
+	})
+
+	done := make(chan struct{})
+
+	tr.OnClose(func() {
+		unsubscribe()
+		close(done)
+	})
+
+	go func() {
+		for {
+			select {
+			case event := <-ch:
+				tr.Write(&ws.Message{Type: "event", Value: event})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
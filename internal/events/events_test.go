@@ -0,0 +1,46 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan *http.Request, 1)
+	body := make(chan []byte, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError) // first request fails
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		received <- r
+		body <- b
+	}))
+	defer server.Close()
+
+	hook := &webhook{URL: server.URL, Events: []string{"producer_stop"}, Secret: "secret"}
+	hook.start()
+
+	Publish("consumer_add", "camera1", nil) // filtered
+	Publish("producer_stop", "camera1", map[string]any{"url": "rtsp://localhost"})
+
+	r := <-received
+	b := <-body
+
+	require.Equal(t, "producer_stop", r.Header.Get("X-Go2rtc-Event"))
+	require.Equal(t, "sha256="+Sign("secret", b), r.Header.Get("X-Go2rtc-Signature"))
+
+	var event Event
+	require.Nil(t, json.Unmarshal(b, &event))
+	require.Equal(t, "camera1", event.Stream)
+	require.Equal(t, "rtsp://localhost", event.Data["url"])
+}
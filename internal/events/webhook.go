@@ -0,0 +1,112 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type webhook struct {
+	URL     string            `yaml:"url"`
+	Events  []string          `yaml:"events"`
+	Headers map[string]string `yaml:"headers"`
+	Secret  string            `yaml:"secret"`
+	Retries int               `yaml:"retries"`
+
+	queue chan *Event
+}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+func (w *webhook) start() {
+	if w.Retries == 0 {
+		w.Retries = 3
+	}
+
+	w.queue = make(chan *Event, 100)
+
+	Subscribe(func(event *Event) {
+		if !match(w.Events, event.Type) {
+			return
+		}
+		select {
+		case w.queue <- event:
+		default:
+			log.Warn().Str("url", w.URL).Msgf("[events] webhook queue is full, drop event=%s", event.Type)
+		}
+	})
+
+	go func() {
+		for event := range w.queue {
+			w.send(event)
+		}
+	}()
+}
+
+// send - POST event with exponential backoff: 1s, 2s, 4s... (max 1 min)
+func (w *webhook) send(event *Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	backoff := time.Second
+
+	for retry := 0; ; retry++ {
+		if err = w.post(event.Type, body); err == nil {
+			return
+		}
+
+		if retry >= w.Retries {
+			log.Warn().Err(err).Str("url", w.URL).Msgf("[events] webhook failed event=%s", event.Type)
+			return
+		}
+
+		log.Debug().Err(err).Str("url", w.URL).Msgf("[events] webhook retry=%d", retry+1)
+
+		time.Sleep(backoff)
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+func (w *webhook) post(typ string, body []byte) error {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Go2rtc-Event", typ)
+
+	if w.Secret != "" {
+		req.Header.Set("X-Go2rtc-Signature", "sha256="+Sign(w.Secret, body))
+	}
+
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("events: webhook status: %s", res.Status)
+	}
+
+	return nil
+}
+
+// Sign - HMAC-SHA256 of body in hex format
+func Sign(secret string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
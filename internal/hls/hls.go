@@ -1,8 +1,9 @@
 package hls
 
 import (
+	"errors"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
@@ -16,6 +17,25 @@ import (
 )
 
 func Init() {
+	var cfg struct {
+		Mod struct {
+			SegmentDuration time.Duration `yaml:"segment_duration"`
+			PartDuration    time.Duration `yaml:"part_duration"`
+			Segments        int           `yaml:"segments"`
+		} `yaml:"hls"`
+	}
+
+	// default config
+	cfg.Mod.SegmentDuration = time.Second
+	cfg.Mod.PartDuration = 500 * time.Millisecond
+	cfg.Mod.Segments = 6
+
+	app.LoadConfig(&cfg)
+
+	segmentDuration = cfg.Mod.SegmentDuration
+	partDuration = cfg.Mod.PartDuration
+	segmentsCount = cfg.Mod.Segments
+
 	log = app.GetLogger("hls")
 
 	api.HandleFunc("api/stream.m3u8", handlerStream)
@@ -35,9 +55,11 @@ var log zerolog.Logger
 
 const keepalive = 5 * time.Second
 
-// once I saw 404 on MP4 segment, so better to use mutex
-var sessions = map[string]*Session{}
-var sessionsMu sync.RWMutex
+var (
+	segmentDuration = time.Second
+	partDuration    = 500 * time.Millisecond
+	segmentsCount   = 6
+)
 
 func handlerStream(w http.ResponseWriter, r *http.Request) {
 	// CORS important for Chromecast
@@ -49,52 +71,80 @@ func handlerStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	src := r.URL.Query().Get("src")
+	query := r.URL.Query()
+
+	src := query.Get("src")
 	stream := streams.Get(src)
 	if stream == nil {
 		http.Error(w, api.StreamNotFound, http.StatusNotFound)
 		return
 	}
 
-	var cons core.Consumer
-
-	// use fMP4 with codecs filter and TS without
-	medias := mp4.ParseQuery(r.URL.Query())
-	if medias != nil {
-		c := mp4.NewConsumer(medias)
-		c.FormatName = "hls/fmp4"
-		c.WithRequest(r)
-		cons = c
+	var key, format string
+	var newCons func() core.Consumer
+
+	// use fMP4 with codecs filter and TS without, muxers are shared with other viewers
+	if medias := mp4.ParseQuery(query); medias != nil {
+		format = "hls/fmp4"
+		metadata := query.Has("metadata")
+
+		key = "fmp4"
+		if metadata {
+			key += ":metadata" // SEI metadata as emsg boxes
+		}
+		key = streams.SharedKey(key, medias)
+		newCons = func() core.Consumer {
+			c := streams.NewSharedConsumer(key, func() core.Consumer {
+				cons := mp4.NewConsumer(medias)
+				cons.Metadata = metadata
+				return cons
+			})
+			c.FormatName = format
+			c.WithRequest(r)
+			return c
+		}
 	} else {
-		c := mpegts.NewConsumer()
-		c.FormatName = "hls/mpegts"
-		c.WithRequest(r)
-		cons = c
+		key, format = "mpegts", "hls/mpegts"
+		newCons = func() core.Consumer {
+			c := streams.NewSharedConsumer(key, func() core.Consumer {
+				return mpegts.NewConsumer()
+			})
+			c.FormatName = format
+			c.WithRequest(r)
+			return c
+		}
 	}
 
-	if err := stream.AddConsumer(cons); err != nil {
+	main, err := addViewer(stream, key, newCons, streams.NewSession(format, r))
+	if err != nil {
 		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), streams.StatusCode(err))
 		return
 	}
 
-	session := NewSession(cons)
-	session.alive = time.AfterFunc(keepalive, func() {
-		sessionsMu.Lock()
-		delete(sessions, session.id)
-		sessionsMu.Unlock()
+	if _, err = w.Write(main); err != nil {
+		log.Error().Err(err).Caller().Send()
+	}
+}
 
-		stream.RemoveConsumer(cons)
-	})
+// addViewer - each viewer is counted by the stream limits with own session,
+// all viewers with the same key share one packager. Returns master playlist.
+func addViewer(stream *streams.Stream, key string, newCons func() core.Consumer, sess *streams.Session) ([]byte, error) {
+	if err := stream.AddSession(sess); err != nil {
+		return nil, err
+	}
 
-	sessionsMu.Lock()
-	sessions[session.id] = session
-	sessionsMu.Unlock()
+	p, err := getPackager(stream, key, newCons)
+	if err == nil {
+		if id, ok := p.addViewer(sess); ok {
+			return p.Main(id), nil
+		}
+		err = errors.New("hls: packager closed")
+	}
 
-	go session.Run()
+	stream.RemoveConsumer(sess)
 
-	if _, err := w.Write(session.Main()); err != nil {
-		log.Error().Err(err).Caller().Send()
-	}
+	return nil, err
 }
 
 func handlerPlaylist(w http.ResponseWriter, r *http.Request) {
@@ -106,16 +156,50 @@ func handlerPlaylist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sid := r.URL.Query().Get("id")
-	sessionsMu.RLock()
-	session := sessions[sid]
-	sessionsMu.RUnlock()
-	if session == nil {
+	query := r.URL.Query()
+
+	p := findPackager(query.Get("id"))
+	if p == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	if _, err := w.Write(session.Playlist()); err != nil {
+	p.touch()
+	p.touchViewer(query.Get("session"))
+
+	// blocking playlist reload (LL-HLS)
+	if s := query.Get("_HLS_msn"); s != "" {
+		msn, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		partN := -1
+		if s = query.Get("_HLS_part"); s != "" {
+			if partN, err = strconv.Atoi(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ok, err := p.WaitPlaylist(msn, partN)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !ok {
+			http.Error(w, "hls: playlist timeout", http.StatusServiceUnavailable)
+			return
+		}
+	} else {
+		p.WaitSegments()
+	}
+
+	p.touch()
+	p.touchViewer(query.Get("session"))
+
+	if _, err := w.Write(p.Playlist()); err != nil {
 		log.Error().Err(err).Caller().Send()
 	}
 }
@@ -129,27 +213,7 @@ func handlerSegmentTS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sid := r.URL.Query().Get("id")
-	sessionsMu.RLock()
-	session := sessions[sid]
-	sessionsMu.RUnlock()
-	if session == nil {
-		http.NotFound(w, r)
-		return
-	}
-
-	session.alive.Reset(keepalive)
-
-	data := session.Segment()
-	if data == nil {
-		log.Warn().Msgf("[hls] can't get segment %s", r.URL.RawQuery)
-		http.NotFound(w, r)
-		return
-	}
-
-	if _, err := w.Write(data); err != nil {
-		log.Error().Err(err).Caller().Send()
-	}
+	handlerSegment(w, r)
 }
 
 func handlerInit(w http.ResponseWriter, r *http.Request) {
@@ -161,16 +225,13 @@ func handlerInit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sid := r.URL.Query().Get("id")
-	sessionsMu.RLock()
-	session := sessions[sid]
-	sessionsMu.RUnlock()
-	if session == nil {
+	p := findPackager(r.URL.Query().Get("id"))
+	if p == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	data := session.Init()
+	data := p.Init()
 	if data == nil {
 		log.Warn().Msgf("[hls] can't get init %s", r.URL.RawQuery)
 		http.NotFound(w, r)
@@ -191,27 +252,48 @@ func handlerSegmentMP4(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	handlerSegment(w, r)
+}
+
+// handlerSegment - full segment (n) or partial segment (n and p)
+func handlerSegment(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
-	sid := query.Get("id")
-	sessionsMu.RLock()
-	session := sessions[sid]
-	sessionsMu.RUnlock()
-	if session == nil {
+	p := findPackager(query.Get("id"))
+	if p == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	session.alive.Reset(keepalive)
+	msn, err := strconv.Atoi(query.Get("n"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.touch()
+
+	var data []byte
+	if s := query.Get("p"); s != "" {
+		partN, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data = p.Part(msn, partN)
+	} else {
+		data = p.Segment(msn)
+	}
+
+	p.touch()
 
-	data := session.Segment()
 	if data == nil {
 		log.Warn().Msgf("[hls] can't get segment %s", r.URL.RawQuery)
 		http.NotFound(w, r)
 		return
 	}
 
-	if _, err := w.Write(data); err != nil {
+	if _, err = w.Write(data); err != nil {
 		log.Error().Err(err).Caller().Send()
 	}
 }
@@ -0,0 +1,631 @@
+package hls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/iso"
+	"github.com/AlexxIT/go2rtc/pkg/mp4"
+	"github.com/AlexxIT/go2rtc/pkg/mpegts"
+)
+
+// packager - HLS segments of the stream for all viewers with the same format and codecs.
+// Segments start from the keyframe and consist of the partial segments (LL-HLS).
+type packager struct {
+	id     string
+	key    string
+	stream *streams.Stream
+	cons   core.Consumer
+	fmp4   bool
+	video  bool
+
+	init      []byte // fMP4 init (ftyp+moov) or TS header (PAT+PMT)
+	mainID    uint32 // fMP4 track ID or TS PID of the main track (video or first audio)
+	timeScale uint32
+	lastTS    uint64 // main track timestamp in the timescale units
+	lastPTS   uint32 // TS main track PTS, for the timestamp overflow
+	delta     uint64 // main track last frame duration
+	emsg      []byte // fMP4 event messages for the next fragment
+
+	segments []*segment // sliding window, the last segment in progress
+	part     *part      // part in progress
+	msn      int        // next media sequence number
+
+	viewers map[string]*viewer // by session ID
+
+	notify chan struct{} // closed on each new part and on close
+	closed bool
+	alive  *time.Timer
+	mu     sync.Mutex
+}
+
+// viewer - HLS client session, counted by the stream limits. Removed after
+// keepalive without playlist requests.
+type viewer struct {
+	sess  *streams.Session
+	alive *time.Timer
+}
+
+type segment struct {
+	msn      int
+	time     time.Time // program date time
+	startTS  uint64
+	duration float64
+	parts    []*part
+	data     []byte // full segment, cached on first request
+	done     bool
+}
+
+type part struct {
+	data        []byte
+	startTS     uint64
+	duration    float64
+	independent bool
+}
+
+// packagers - by ID
+var packagers = map[string]*packager{}
+var packagersMu sync.Mutex
+
+type withCodecs interface {
+	Codecs() []*core.Codec
+}
+
+// getPackager - shared packager for the stream and the shared muxer key (format and codecs),
+// newCons is called only for the new packager
+func getPackager(stream *streams.Stream, key string, newCons func() core.Consumer) (*packager, error) {
+	if p := lookupPackager(stream, key); p != nil {
+		return p, nil
+	}
+
+	// stream may dial the source for some time, so without lock
+	cons := newCons()
+	if err := stream.AddSessionConsumer(cons); err != nil {
+		return nil, err
+	}
+
+	packagersMu.Lock()
+	defer packagersMu.Unlock()
+
+	// another viewer was faster
+	for _, p := range packagers {
+		if p.stream == stream && p.key == key {
+			stream.RemoveConsumer(cons)
+			p.touch()
+			return p, nil
+		}
+	}
+
+	p := &packager{
+		id:        core.RandString(8, 62),
+		key:       key,
+		stream:    stream,
+		cons:      cons,
+		fmp4:      strings.HasPrefix(key, "fmp4"),
+		timeScale: mpegts.ClockRate,
+		notify:    make(chan struct{}),
+	}
+
+	for _, codec := range cons.(withCodecs).Codecs() {
+		if codec.IsVideo() {
+			p.video = true
+		}
+	}
+
+	p.alive = time.AfterFunc(keepalive, p.close)
+
+	packagers[p.id] = p
+
+	go func() {
+		_, _ = cons.(io.WriterTo).WriteTo(p)
+	}()
+
+	return p, nil
+}
+
+func lookupPackager(stream *streams.Stream, key string) *packager {
+	packagersMu.Lock()
+	defer packagersMu.Unlock()
+
+	for _, p := range packagers {
+		if p.stream == stream && p.key == key {
+			p.touch()
+			return p
+		}
+	}
+	return nil
+}
+
+func findPackager(id string) *packager {
+	packagersMu.Lock()
+	defer packagersMu.Unlock()
+	return packagers[id]
+}
+
+func (p *packager) touch() {
+	p.alive.Reset(keepalive)
+}
+
+// addViewer - session ID for the playlist URL, false if packager already closed
+func (p *packager) addViewer(sess *streams.Session) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return "", false
+	}
+
+	id := core.RandString(8, 62)
+	v := &viewer{sess: sess}
+	v.alive = time.AfterFunc(keepalive, func() {
+		p.removeViewer(id)
+	})
+
+	if p.viewers == nil {
+		p.viewers = map[string]*viewer{}
+	}
+	p.viewers[id] = v
+
+	return id, true
+}
+
+func (p *packager) touchViewer(id string) {
+	p.mu.Lock()
+	v := p.viewers[id]
+	p.mu.Unlock()
+
+	if v != nil {
+		v.alive.Reset(keepalive)
+	}
+}
+
+func (p *packager) removeViewer(id string) {
+	p.mu.Lock()
+	v := p.viewers[id]
+	delete(p.viewers, id)
+	p.mu.Unlock()
+
+	if v != nil {
+		v.alive.Stop()
+		p.stream.RemoveConsumer(v.sess)
+	}
+}
+
+func (p *packager) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.notify)
+	viewers := p.viewers
+	p.viewers = nil
+	p.mu.Unlock()
+
+	packagersMu.Lock()
+	delete(packagers, p.id)
+	packagersMu.Unlock()
+
+	for _, v := range viewers {
+		v.alive.Stop()
+		p.stream.RemoveConsumer(v.sess)
+	}
+
+	p.stream.RemoveConsumer(p.cons)
+}
+
+func (p *packager) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if p.fmp4 {
+		if err := p.writeMP4(b); err != nil {
+			return 0, err
+		}
+	} else {
+		p.writeTS(b)
+	}
+
+	return len(b), nil
+}
+
+// writeMP4 - mp4.Consumer writes one moof+mdat for each frame, but it may flush several in one write
+func (p *packager) writeMP4(b []byte) error {
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return errors.New("hls: wrong atom size")
+		}
+
+		size := binary.BigEndian.Uint32(b)
+		if size < 8 || int(size) > len(b) {
+			return errors.New("hls: wrong atom size")
+		}
+
+		atom := b[:size]
+		b = b[size:]
+
+		switch string(atom[4:8]) {
+		case iso.Ftyp:
+			p.init = append([]byte{}, atom...)
+			continue
+		case iso.Moov:
+			p.init = append(p.init, atom...)
+			p.parseMoov(atom)
+			continue
+		case iso.Emsg:
+			// event message belongs to the next fragment
+			p.emsg = append(p.emsg, atom...)
+			continue
+		case iso.Moof:
+			p.parseMoof(atom)
+			if p.emsg != nil {
+				if p.part != nil {
+					p.part.data = append(p.part.data, p.emsg...)
+				}
+				p.emsg = nil
+			}
+		}
+
+		if p.part != nil {
+			p.part.data = append(p.part.data, atom...)
+		}
+	}
+
+	return nil
+}
+
+func (p *packager) parseMoov(moov []byte) {
+	atoms, err := iso.DecodeAtoms(moov)
+	if err != nil {
+		return
+	}
+
+	type track struct {
+		id, timeScale uint32
+		video         bool
+	}
+
+	var tracks []*track
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTkhd:
+			tracks = append(tracks, &track{id: atom.TrackID})
+		case *iso.AtomMdhd:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].timeScale = atom.TimeScale
+			}
+		case *iso.AtomVideo:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].video = true
+			}
+		}
+	}
+
+	if len(tracks) == 0 {
+		return
+	}
+
+	// video track or the first audio track
+	main := tracks[0]
+	for _, t := range tracks {
+		if t.video {
+			main = t
+			break
+		}
+	}
+	p.mainID, p.timeScale = main.id, main.timeScale
+}
+
+func (p *packager) parseMoof(moof []byte) {
+	atoms, err := iso.DecodeAtoms(moof)
+	if err != nil {
+		return
+	}
+
+	var trackID, flags uint32
+	var ts uint64
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTfhd:
+			trackID, flags = atom.TrackID, atom.SampleFlags
+		case *iso.AtomTfdt:
+			ts = atom.DecodeTime
+		}
+	}
+
+	if trackID == p.mainID {
+		p.boundary(ts, !p.video || flags == iso.SampleVideoIFrame)
+	}
+}
+
+// writeTS - mpegts.Consumer writes whole PES packets, video keyframes have random access flag
+func (p *packager) writeTS(b []byte) {
+	for ; len(b) >= mpegts.PacketSize; b = b[mpegts.PacketSize:] {
+		pkt := b[:mpegts.PacketSize]
+
+		pid, start, keyframe, pts := mpegts.ParsePacket(pkt)
+		if mpegts.IsPSI(pid) {
+			if p.segments == nil {
+				p.init = append(p.init, pkt...)
+			}
+			continue
+		}
+
+		if start {
+			if p.mainID == 0 && (keyframe || !p.video) {
+				p.mainID = uint32(pid)
+				p.lastPTS = pts
+			}
+			if uint32(pid) == p.mainID {
+				ts := p.lastTS + uint64(pts-p.lastPTS)
+				p.lastPTS = pts
+				p.boundary(ts, keyframe || !p.video)
+			}
+		}
+
+		if p.part != nil {
+			p.part.data = append(p.part.data, pkt...)
+		}
+	}
+}
+
+// boundary - new frame of the main track, time to start new part or segment
+func (p *packager) boundary(ts uint64, keyframe bool) {
+	if p.part != nil {
+		if ts < p.lastTS {
+			return // broken timestamps
+		}
+		p.delta = ts - p.lastTS
+	}
+	p.lastTS = ts
+
+	if p.part == nil {
+		if keyframe {
+			p.newSegment(ts)
+		}
+		return
+	}
+
+	seg := p.segments[len(p.segments)-1]
+
+	switch {
+	case keyframe && p.seconds(ts-seg.startTS) >= segmentDuration.Seconds():
+		p.finishPart(ts)
+		seg.duration = p.seconds(ts - seg.startTS)
+		seg.done = true
+		p.newSegment(ts)
+
+	case ts > p.part.startTS && p.seconds(ts-p.part.startTS+p.delta) > partDuration.Seconds():
+		// the part with the next frame will be longer than the target
+		p.finishPart(ts)
+		p.part = &part{startTS: ts, independent: keyframe}
+	}
+}
+
+func (p *packager) newSegment(ts uint64) {
+	p.segments = append(p.segments, &segment{msn: p.msn, time: time.Now(), startTS: ts})
+	if len(p.segments) > segmentsCount+1 {
+		p.segments = p.segments[1:]
+	}
+	p.msn++
+
+	p.part = &part{startTS: ts, independent: true}
+	if !p.fmp4 {
+		// for TS important to start new segment with header
+		p.part.data = append(p.part.data, p.init...)
+	}
+}
+
+func (p *packager) finishPart(ts uint64) {
+	seg := p.segments[len(p.segments)-1]
+	p.part.duration = p.seconds(ts - p.part.startTS)
+	seg.parts = append(seg.parts, p.part)
+	p.part = nil
+
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+func (p *packager) seconds(d uint64) float64 {
+	return float64(d) / float64(p.timeScale)
+}
+
+// wait - until ready (called under lock) returns true, false on timeout or close
+func (p *packager) wait(ready func() bool) bool {
+	p.mu.Lock()
+	timer := time.NewTimer(3 * time.Duration(p.targetDuration()) * time.Second)
+	p.mu.Unlock()
+
+	defer timer.Stop()
+
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return false
+		}
+		if ready() {
+			p.mu.Unlock()
+			return true
+		}
+		ch := p.notify
+		p.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// getSegment - segment from the window, nil if not exist
+func (p *packager) getSegment(msn int) *segment {
+	if len(p.segments) == 0 {
+		return nil
+	}
+	i := msn - p.segments[0].msn
+	if i < 0 || i >= len(p.segments) {
+		return nil
+	}
+	return p.segments[i]
+}
+
+// WaitPlaylist - blocking playlist reload until the segment msn or its part is ready,
+// error for the far future segments
+func (p *packager) WaitPlaylist(msn, partN int) (bool, error) {
+	p.mu.Lock()
+	next := p.msn
+	p.mu.Unlock()
+
+	if msn > next+1 {
+		return false, errors.New("hls: wrong _HLS_msn")
+	}
+
+	return p.wait(func() bool {
+		if seg := p.getSegment(msn); seg != nil {
+			return seg.done || (partN >= 0 && partN < len(seg.parts))
+		}
+		return msn < p.msn // old segment
+	}), nil
+}
+
+// WaitSegments - two full segments for players without LL-HLS support (important for Chromecast)
+func (p *packager) WaitSegments() bool {
+	return p.wait(func() bool {
+		return len(p.segments) > 2
+	})
+}
+
+// Segment - full segment, waits for the segment in progress
+func (p *packager) Segment(msn int) (data []byte) {
+	p.wait(func() bool {
+		seg := p.getSegment(msn)
+		if seg == nil {
+			return msn != p.msn // wait only for the next segment
+		}
+		if !seg.done {
+			return false
+		}
+		if seg.data == nil {
+			for _, part := range seg.parts {
+				seg.data = append(seg.data, part.data...)
+			}
+		}
+		data = seg.data
+		return true
+	})
+	return
+}
+
+// Part - partial segment, waits for the next part (preload hint)
+func (p *packager) Part(msn, partN int) (data []byte) {
+	p.wait(func() bool {
+		seg := p.getSegment(msn)
+		if seg == nil {
+			return msn != p.msn
+		}
+		if partN < len(seg.parts) {
+			data = seg.parts[partN].data
+			return true
+		}
+		return seg.done || partN > len(seg.parts)
+	})
+	return
+}
+
+// Init - fMP4 init segment
+func (p *packager) Init() (init []byte) {
+	p.wait(func() bool {
+		if len(p.segments) > 0 {
+			init = p.init
+			return true
+		}
+		return false
+	})
+	return
+}
+
+// Main - master playlist for the viewer session
+func (p *packager) Main(session string) []byte {
+	codecs := mp4.MimeCodecs(p.cons.(withCodecs).Codecs())
+	codecs = strings.Replace(codecs, mp4.MimeFlac, "fLaC", 1)
+
+	// bandwidth important for Safari, codecs useful for smooth playback
+	return []byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=192000,CODECS="` + codecs + `"
+hls/playlist.m3u8?id=` + p.id + `&session=` + session)
+}
+
+// Playlist - media playlist with parts for the last segments
+func (p *packager) Playlist() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var uri string
+	sb := &strings.Builder{}
+
+	if p.fmp4 {
+		uri = "segment.m4s?id=" + p.id
+		sb.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n")
+	} else {
+		uri = "segment.ts?id=" + p.id
+		sb.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	}
+
+	fmt.Fprintf(sb, "#EXT-X-TARGETDURATION:%d\n", p.targetDuration())
+	fmt.Fprintf(sb, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", 3*partDuration.Seconds())
+	fmt.Fprintf(sb, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", partDuration.Seconds())
+
+	if len(p.segments) > 0 {
+		fmt.Fprintf(sb, "#EXT-X-MEDIA-SEQUENCE:%d\n", p.segments[0].msn)
+	}
+
+	if p.fmp4 {
+		sb.WriteString(`#EXT-X-MAP:URI="init.mp4?id=` + p.id + "\"\n")
+	}
+
+	for i, seg := range p.segments {
+		sb.WriteString("#EXT-X-PROGRAM-DATE-TIME:" + seg.time.UTC().Format("2006-01-02T15:04:05.000Z") + "\n")
+
+		// parts only for the last segments
+		if i >= len(p.segments)-3 {
+			for j, part := range seg.parts {
+				fmt.Fprintf(sb, `#EXT-X-PART:DURATION=%.3f,URI="%s&n=%d&p=%d"`, part.duration, uri, seg.msn, j)
+				if part.independent {
+					sb.WriteString(",INDEPENDENT=YES")
+				}
+				sb.WriteByte('\n')
+			}
+		}
+
+		if seg.done {
+			fmt.Fprintf(sb, "#EXTINF:%.3f,\n%s&n=%d\n", seg.duration, uri, seg.msn)
+		} else {
+			fmt.Fprintf(sb, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s&n=%d&p=%d\"\n", uri, seg.msn, len(seg.parts))
+		}
+	}
+
+	return []byte(sb.String())
+}
+
+func (p *packager) targetDuration() int {
+	duration := segmentDuration.Seconds()
+	for _, seg := range p.segments {
+		duration = max(duration, seg.duration)
+	}
+	return int(math.Ceil(duration))
+}
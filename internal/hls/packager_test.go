@@ -0,0 +1,62 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/mpegts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackagerTS(t *testing.T) {
+	p := &packager{
+		id:        "test",
+		video:     true,
+		timeScale: mpegts.ClockRate,
+		notify:    make(chan struct{}),
+	}
+
+	muxer := mpegts.NewMuxer()
+	pid := muxer.AddTrack(mpegts.StreamTypeH264)
+
+	_, err := p.Write(muxer.GetHeader())
+	require.Nil(t, err)
+
+	// 30 fps, keyframe each second
+	for i := 0; i < 95; i++ {
+		frame := []byte{0, 0, 0, 2, 0x41, 0}
+		if i%30 == 0 {
+			frame[4] = 0x65
+		}
+		_, err = p.Write(muxer.GetPayload(pid, uint32(1000+i*3000), frame))
+		require.Nil(t, err)
+	}
+
+	playlist := string(p.Playlist())
+	require.Contains(t, playlist, "#EXT-X-PART-INF:PART-TARGET=0.500\n")
+	require.Contains(t, playlist, "#EXT-X-MEDIA-SEQUENCE:0\n")
+	require.Equal(t, 3, strings.Count(playlist, "#EXTINF:1.000,"))
+	require.Contains(t, playlist, `#EXT-X-PART:DURATION=0.500,URI="segment.ts?id=test&n=2&p=0",INDEPENDENT=YES`)
+	require.Contains(t, playlist, `#EXT-X-PART:DURATION=0.500,URI="segment.ts?id=test&n=2&p=1"`+"\n")
+	require.Contains(t, playlist, `#EXT-X-PRELOAD-HINT:TYPE=PART,URI="segment.ts?id=test&n=3&p=0"`)
+
+	// segment starts with PAT, PMT and the keyframe
+	data := p.Segment(1)
+	require.Len(t, data, 32*mpegts.PacketSize)
+
+	pktPID, _, _, _ := mpegts.ParsePacket(data)
+	require.True(t, mpegts.IsPSI(pktPID))
+
+	pktPID, start, keyframe, pts := mpegts.ParsePacket(data[2*mpegts.PacketSize:])
+	require.Equal(t, pid, pktPID)
+	require.True(t, start)
+	require.True(t, keyframe)
+	require.Equal(t, uint32(30*3000), pts)
+
+	ok, err := p.WaitPlaylist(2, 1)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	_, err = p.WaitPlaylist(10, -1)
+	require.NotNil(t, err)
+}
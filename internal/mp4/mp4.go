@@ -24,10 +24,34 @@ func Init() {
 
 	api.HandleFunc("api/frame.mp4", handlerKeyframe)
 	api.HandleFunc("api/stream.mp4", handlerMP4)
+	api.HandleFunc("api/clip.mp4", handlerClip)
+
+	streams.HandleFunc("file", handlerFile)
+	streams.HandleFunc("mp4", handlerFile)
 }
 
 var log zerolog.Logger
 
+// handlerFile - file:///path/video.mp4#loop#start=1m30s or mp4:/path/video.mp4
+func handlerFile(source string) (core.Producer, error) {
+	path, rawQuery, _ := strings.Cut(source, "#")
+	path = strings.TrimPrefix(path, "file:")
+	path = strings.TrimPrefix(path, "mp4:")
+	path = strings.TrimPrefix(path, "//")
+
+	query := streams.ParseQuery(rawQuery)
+
+	prod, err := mp4.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	prod.Loop = query.Has("loop")
+	prod.Seek = streams.ParseDuration(query.Get("start"))
+
+	return prod, nil
+}
+
 func handlerKeyframe(w http.ResponseWriter, r *http.Request) {
 	// Chrome 105 does two requests: without Range and with `Range: bytes=0-`
 	ua := r.UserAgent()
@@ -51,7 +75,7 @@ func handlerKeyframe(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.StatusCode(err))
 		return
 	}
 
@@ -98,28 +122,40 @@ func handlerMP4(w http.ResponseWriter, r *http.Request) {
 	}
 
 	medias := mp4.ParseQuery(r.URL.Query())
-	cons := mp4.NewConsumer(medias)
+	rotate, scale := query.Get("rotate"), query.Get("scale")
+	metadata := query.Has("metadata")
+
+	// one muxer for all viewers with the same codecs, init patches are part of the key
+	format := "fmp4"
+	if rotate != "" || scale != "" {
+		format += ":rotate=" + rotate + ":scale=" + scale
+	}
+	if metadata {
+		format += ":metadata"
+	}
+
+	cons := streams.NewSharedConsumer(streams.SharedKey(format, medias), func() core.Consumer {
+		c := mp4.NewConsumer(medias)
+		c.Metadata = metadata
+		if rotate != "" {
+			c.Rotate = core.Atoi(rotate)
+		}
+		if sx, sy, ok := strings.Cut(scale, ":"); ok {
+			c.ScaleX = core.Atoi(sx)
+			c.ScaleY = core.Atoi(sy)
+		}
+		return c
+	})
 	cons.FormatName = "mp4"
 	cons.Protocol = "http"
 	cons.WithRequest(r)
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.StatusCode(err))
 		return
 	}
 
-	if rotate := query.Get("rotate"); rotate != "" {
-		cons.Rotate = core.Atoi(rotate)
-	}
-
-	if scale := query.Get("scale"); scale != "" {
-		if sx, sy, ok := strings.Cut(scale, ":"); ok {
-			cons.ScaleX = core.Atoi(sx)
-			cons.ScaleY = core.Atoi(sy)
-		}
-	}
-
 	header := w.Header()
 	header.Set("Content-Type", mp4.ContentType(cons.Codecs()))
 
@@ -144,3 +180,53 @@ func handlerMP4(w http.ResponseWriter, r *http.Request) {
 
 	_, _ = cons.WriteTo(w)
 }
+
+// handlerClip - MP4 from the stream buffer, starts from keyframe before
+// (now - before) and continues with live packets during after
+func handlerClip(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	stream := streams.Get(query.Get("src"))
+	if stream == nil {
+		http.Error(w, api.StreamNotFound, http.StatusNotFound)
+		return
+	}
+
+	before, after := 10*time.Second, 10*time.Second
+	if s := query.Get("before"); s != "" {
+		before = streams.ParseDuration(s)
+	}
+	if s := query.Get("after"); s != "" {
+		after = streams.ParseDuration(s)
+	}
+
+	medias := mp4.ParseQuery(query)
+	cons := mp4.NewConsumer(medias)
+	cons.FormatName = "mp4"
+	cons.Protocol = "http"
+	cons.WithRequest(r)
+
+	if err := stream.AddBufferedConsumer(cons, before); err != nil {
+		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), streams.StatusCode(err))
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", mp4.ContentType(cons.Codecs()))
+
+	if filename := query.Get("filename"); filename != "" {
+		header.Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), after)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		_ = cons.Stop()
+		stream.RemoveConsumer(cons)
+	}()
+
+	_, _ = cons.WriteTo(w)
+}
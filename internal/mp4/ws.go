@@ -22,7 +22,19 @@ func handlerWSMSE(tr *ws.Transport, msg *ws.Message) error {
 		medias = mp4.ParseCodecs(codecs, true)
 	}
 
-	cons := mp4.NewConsumer(medias)
+	// SEI metadata as emsg boxes
+	format := "fmp4"
+	metadata := tr.Request.URL.Query().Has("metadata")
+	if metadata {
+		format += ":metadata"
+	}
+
+	// one muxer for all viewers with the same codecs
+	cons := streams.NewSharedConsumer(streams.SharedKey(format, medias), func() core.Consumer {
+		c := mp4.NewConsumer(medias)
+		c.Metadata = metadata
+		return c
+	})
 	cons.FormatName = "mse/fmp4"
 	cons.WithRequest(tr.Request)
 
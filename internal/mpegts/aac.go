@@ -20,7 +20,7 @@ func apiStreamAAC(w http.ResponseWriter, r *http.Request) {
 	cons.WithRequest(r)
 
 	if err := stream.AddConsumer(cons); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.StatusCode(err))
 		return
 	}
 
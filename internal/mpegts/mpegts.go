@@ -5,6 +5,7 @@ import (
 
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/mpegts"
 )
 
@@ -29,11 +30,15 @@ func outputMpegTS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cons := mpegts.NewConsumer()
+	// one muxer for all viewers
+	cons := streams.NewSharedConsumer("mpegts", func() core.Consumer {
+		return mpegts.NewConsumer()
+	})
+	cons.FormatName = "mpegts"
 	cons.WithRequest(r)
 
 	if err := stream.AddConsumer(cons); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.StatusCode(err))
 		return
 	}
 
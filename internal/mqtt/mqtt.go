@@ -0,0 +1,236 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/events"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/internal/webrtc"
+	"github.com/AlexxIT/go2rtc/pkg/mqtt"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var cfg struct {
+		Mod struct {
+			URL       string `yaml:"url"`
+			ClientID  string `yaml:"client_id"`
+			Topic     string `yaml:"topic"`
+			KeepAlive int    `yaml:"keepalive"`
+		} `yaml:"mqtt"`
+	}
+
+	cfg.Mod.ClientID = "go2rtc"
+	cfg.Mod.Topic = "go2rtc"
+	cfg.Mod.KeepAlive = 30
+
+	app.LoadConfig(&cfg)
+
+	if cfg.Mod.URL == "" {
+		return
+	}
+
+	log = app.GetLogger("mqtt")
+
+	u, err := url.Parse(cfg.Mod.URL)
+	if err != nil {
+		log.Error().Err(err).Caller().Send()
+		return
+	}
+
+	prefix = cfg.Mod.Topic
+
+	session = &mqtt.Session{
+		Dial:        dialer(u),
+		ClientID:    cfg.Mod.ClientID,
+		Username:    u.User.Username(),
+		KeepAlive:   time.Duration(cfg.Mod.KeepAlive) * time.Second,
+		WillTopic:   prefix + "/status",
+		WillPayload: []byte("offline"),
+		OnConnect:   onConnect,
+		OnMessage:   onMessage,
+		OnError: func(err error) {
+			log.Warn().Err(err).Msg("[mqtt] connection")
+		},
+	}
+	session.Password, _ = u.User.Password()
+
+	_ = session.Subscribe(prefix + "/streams/+/publish")
+	_ = session.Subscribe(prefix + "/streams/+/unpublish")
+	_ = session.Subscribe(prefix + "/webrtc/pause")
+	_ = session.Subscribe(prefix + "/webrtc/resume")
+
+	events.Subscribe(func(event *events.Event) {
+		select {
+		case queue <- event:
+		default:
+		}
+	})
+
+	go worker(queue)
+	go session.Run()
+}
+
+var log zerolog.Logger
+
+var session *mqtt.Session
+var prefix string
+
+var queue = make(chan *events.Event, 100)
+
+// online state of streams
+var states = map[string]string{}
+var statesMu sync.Mutex
+
+func dialer(u *url.URL) func() (net.Conn, error) {
+	host := u.Host
+	secure := u.Scheme == "mqtts" || u.Scheme == "ssl" || u.Scheme == "tls"
+
+	if u.Port() == "" {
+		if secure {
+			host += ":8883"
+		} else {
+			host += ":1883"
+		}
+	}
+
+	return func() (net.Conn, error) {
+		if secure {
+			return tls.DialWithDialer(&net.Dialer{Timeout: mqtt.Timeout}, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+		}
+		return net.DialTimeout("tcp", host, mqtt.Timeout)
+	}
+}
+
+func onConnect() {
+	log.Debug().Msgf("[mqtt] connected")
+
+	publish("status", "online")
+
+	for _, name := range streams.GetAllNames() {
+		statesMu.Lock()
+		state, ok := states[name]
+		statesMu.Unlock()
+
+		if !ok {
+			state = "offline"
+		}
+
+		publish("streams/"+name+"/state", state)
+		publishConsumers(name)
+	}
+}
+
+// worker - publish stream state and consumers count from the events until the channel is closed
+func worker(ch <-chan *events.Event) {
+	for event := range ch {
+		if event.Stream == "" {
+			continue
+		}
+
+		var state string
+
+		switch event.Type {
+		case "producer_dial", "producer_start", "producer_failover", "producer_failback":
+			state = "online"
+		case "producer_dial_failed", "producer_reconnect", "producer_give_up", "producer_stop":
+			state = "offline"
+		case "consumer_add", "consumer_remove":
+			publishConsumers(event.Stream)
+			continue
+		default:
+			continue
+		}
+
+		statesMu.Lock()
+		changed := states[event.Stream] != state
+		states[event.Stream] = state
+		statesMu.Unlock()
+
+		if changed {
+			publish("streams/"+event.Stream+"/state", state)
+		}
+	}
+}
+
+func publishConsumers(name string) {
+	if stream := streams.Get(name); stream != nil {
+		publish("streams/"+name+"/consumers", strconv.Itoa(stream.ConsumersCount()))
+	}
+}
+
+// publish - retained message, will be published again on reconnect
+func publish(topic, payload string) {
+	if err := session.Publish(prefix+"/"+topic, []byte(payload), true); err != nil && err != mqtt.ErrNotConnected {
+		log.Debug().Err(err).Str("topic", topic).Msg("[mqtt] publish")
+	}
+}
+
+func onMessage(topic string, payload []byte) {
+	log.Trace().Msgf("[mqtt] message topic=%s", topic)
+
+	topic, ok := strings.CutPrefix(topic, prefix+"/")
+	if !ok {
+		return
+	}
+
+	value := string(payload)
+
+	var err error
+
+	switch topic {
+	case "webrtc/pause":
+		if !webrtc.PauseSession(value) {
+			err = errors.New("session not found: " + value)
+		}
+	case "webrtc/resume":
+		if !webrtc.ResumeSession(value) {
+			err = errors.New("session not found: " + value)
+		}
+	default:
+		// streams/{name}/publish or streams/{name}/unpublish
+		name, action, ok := parseStreamTopic(topic)
+		if !ok {
+			return
+		}
+
+		stream := streams.Get(name)
+		if stream == nil {
+			err = errors.New("stream not found: " + name)
+			break
+		}
+
+		switch action {
+		case "publish":
+			// same check as for dynamic sources from the API
+			if err = streams.Validate(value); err == nil {
+				err = stream.Publish(value)
+			}
+		case "unpublish":
+			err = stream.StopPublish(value)
+		}
+	}
+
+	if err != nil {
+		log.Warn().Err(err).Str("topic", topic).Msg("[mqtt] command")
+	}
+}
+
+func parseStreamTopic(topic string) (name, action string, ok bool) {
+	s, ok := strings.CutPrefix(topic, "streams/")
+	if !ok {
+		return
+	}
+	if i := strings.LastIndexByte(s, '/'); i > 0 {
+		return s[:i], s[i+1:], true
+	}
+	return "", "", false
+}
@@ -0,0 +1,115 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/events"
+	"github.com/AlexxIT/go2rtc/pkg/mqtt"
+	"github.com/stretchr/testify/require"
+)
+
+// broker - accepts one client and sends "topic=payload" of each retained publish
+func broker(ln net.Listener, published chan<- string, errs chan<- error) {
+	conn, err := ln.Accept()
+	if err != nil {
+		errs <- err
+		return
+	}
+	defer conn.Close()
+
+	for {
+		b := make([]byte, 1)
+		if _, err = io.ReadFull(conn, b); err != nil {
+			return // client closed connection
+		}
+		size, err := mqtt.ReadLen(conn)
+		if err != nil {
+			errs <- err
+			return
+		}
+		body := make([]byte, size)
+		if _, err = io.ReadFull(conn, body); err != nil {
+			errs <- err
+			return
+		}
+
+		switch b[0] & 0xF0 {
+		case mqtt.CONNECT:
+			_, _ = conn.Write([]byte{mqtt.CONNACK, 2, 0, 0})
+		case mqtt.PUBLISH:
+			if b[0]&mqtt.RETAIN == 0 {
+				errs <- errors.New("publish without retain")
+				return
+			}
+			// topic, message ID (QoS 1), payload
+			i := 2 + binary.BigEndian.Uint16(body)
+			published <- string(body[2:i]) + "=" + string(body[i+2:])
+			_, _ = conn.Write([]byte{mqtt.PUBACK, 2, body[i], body[i+1]})
+		case mqtt.PINGREQ:
+			_, _ = conn.Write([]byte{mqtt.PINGRESP, 0})
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+
+	published := make(chan string, 10)
+	errs := make(chan error, 10)
+
+	go broker(ln, published, errs)
+
+	prefix = "go2rtc"
+	session = &mqtt.Session{
+		Dial: func() (net.Conn, error) {
+			return net.Dial("tcp", ln.Addr().String())
+		},
+		ClientID:  "go2rtc",
+		KeepAlive: time.Minute,
+		OnConnect: onConnect,
+	}
+
+	ch := make(chan *events.Event, 10)
+	go worker(ch)
+
+	done := make(chan struct{})
+	go func() {
+		session.Run()
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		close(ch)
+		_ = session.Close()
+		<-done
+	})
+
+	next := func() string {
+		select {
+		case s := <-published:
+			return s
+		case err := <-errs:
+			require.Nil(t, err)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "publish timeout")
+		}
+		return ""
+	}
+
+	require.Equal(t, "go2rtc/status=online", next())
+
+	// state is published only on change
+	ch <- &events.Event{Type: "producer_start", Stream: "camera1"}
+	ch <- &events.Event{Type: "producer_dial", Stream: "camera1"}
+	ch <- &events.Event{Type: "producer_stop", Stream: "camera1"}
+
+	require.Equal(t, "go2rtc/streams/camera1/state=online", next())
+	require.Equal(t, "go2rtc/streams/camera1/state=offline", next())
+}
@@ -0,0 +1,84 @@
+package onvif
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/pkg/onvif"
+)
+
+// PTZ - command for the ONVIF source of the stream
+type PTZ struct {
+	Action string  `json:"action"` // move, stop, preset
+	X      float64 `json:"x"`      // pan speed -1..1
+	Y      float64 `json:"y"`      // tilt speed -1..1
+	Zoom   float64 `json:"zoom"`   // zoom speed -1..1
+	Preset string  `json:"preset"` // preset token
+}
+
+type ptzClient struct {
+	client *onvif.Client
+	token  string
+}
+
+// clients are cached, because each new client makes GetCapabilities request
+var ptzClients = map[string]*ptzClient{}
+var ptzMu sync.Mutex
+
+// SendPTZ - send command to the first ONVIF source from the list
+func SendPTZ(sources []string, cmd *PTZ) error {
+	for _, source := range sources {
+		if !strings.HasPrefix(source, "onvif:") {
+			continue
+		}
+
+		ptz, err := getPTZClient(source)
+		if err != nil {
+			return err
+		}
+
+		log.Trace().Msgf("[onvif] ptz %+v", cmd)
+
+		switch cmd.Action {
+		case "move":
+			_, err = ptz.client.ContinuousMove(ptz.token, cmd.X, cmd.Y, cmd.Zoom)
+		case "stop":
+			_, err = ptz.client.Stop(ptz.token)
+		case "preset":
+			_, err = ptz.client.GotoPreset(ptz.token, cmd.Preset)
+		default:
+			err = errors.New("onvif: unknown PTZ action: " + cmd.Action)
+		}
+		return err
+	}
+
+	return errors.New("onvif: stream without ONVIF source")
+}
+
+func getPTZClient(rawURL string) (*ptzClient, error) {
+	ptzMu.Lock()
+	defer ptzMu.Unlock()
+
+	if ptz, ok := ptzClients[rawURL]; ok {
+		return ptz, nil
+	}
+
+	client, err := onvif.NewClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.HasPTZ() {
+		return nil, errors.New("onvif: camera without PTZ service")
+	}
+
+	token, err := client.GetProfileToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ptz := &ptzClient{client: client, token: token}
+	ptzClients[rawURL] = ptz
+	return ptz, nil
+}
@@ -0,0 +1,333 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/mp4"
+)
+
+func initAPI() {
+	api.HandleFunc("api/record/list", apiList)
+	api.HandleFunc("api/record/stream.mp4", apiStreamMP4)
+
+	// HLS (fMP4) VOD
+	api.HandleFunc("api/record/stream.m3u8", apiStreamM3U8)
+	api.HandleFunc("api/record/init.mp4", apiInit)
+	api.HandleFunc("api/record/segment.m4s", apiSegment)
+}
+
+func apiList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	segments, err := findSegments(query.Get("src"), query.Get("from"), query.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var response = struct {
+		Segments []*Segment `json:"segments"`
+	}{
+		Segments: segments,
+	}
+	api.ResponseJSON(w, response)
+}
+
+// apiStreamMP4 - join recorded fragments from start (nearest previous keyframe)
+// to start+duration into one fMP4 file with continuous timestamps
+func apiStreamMP4(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	start, err := parseTime(query.Get("start"))
+	if err != nil || start.IsZero() {
+		http.Error(w, "wrong start param", http.StatusBadRequest)
+		return
+	}
+
+	end := start.Add(time.Hour)
+	if i := core.Atoi(query.Get("duration")); i > 0 {
+		end = start.Add(time.Second * time.Duration(i))
+	}
+
+	segments, err := findSegments(query.Get("src"), query.Get("start"), strconv.FormatInt(end.Unix(), 10))
+	if err != nil || len(segments) == 0 {
+		http.Error(w, "no records", http.StatusNotFound)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "video/mp4")
+
+	if filename := query.Get("filename"); filename != "" {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+
+	if err = writeRange(w, segments, start, end); err != nil {
+		log.Debug().Err(err).Caller().Send()
+	}
+}
+
+func writeRange(w io.Writer, segments []*Segment, start, end time.Time) error {
+	var init []byte
+	var zero time.Time // time of first output fragment
+	var seq uint32
+
+	for _, segment := range segments {
+		f, err := readFile(segment)
+		if err != nil {
+			return err
+		}
+
+		done, err := writeFile(w, f, &init, &zero, &seq, start, end)
+		_ = f.Close()
+
+		if err != nil || done {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile - fragments of one recorded file, returns true when the range is finished
+func writeFile(w io.Writer, f *file, init *[]byte, zero *time.Time, seq *uint32, start, end time.Time) (bool, error) {
+	frags := f.fragments
+
+	if *init == nil {
+		// start from the last keyframe before start time
+		i := -1
+		for j, frag := range frags {
+			if !frag.keyframe {
+				continue
+			}
+			if i >= 0 && frag.time.After(start) {
+				break
+			}
+			i = j
+		}
+		if i < 0 {
+			return false, nil
+		}
+
+		frags = frags[i:]
+		*zero = frags[0].time
+		*init = f.init
+
+		if _, err := w.Write(f.init); err != nil {
+			return true, err
+		}
+	} else if !bytes.Equal(*init, f.init) {
+		return true, nil // codecs changed, can't join files
+	}
+
+	for _, frag := range frags {
+		if !frag.time.Before(end) {
+			return true, nil
+		}
+
+		b, err := f.readFragment(frag)
+		if err != nil {
+			return true, err
+		}
+
+		var dts uint64
+		if d := frag.time.Sub(*zero); d > 0 {
+			timeScale := time.Duration(f.tracks[frag.trackID].timeScale)
+			dts = uint64(d * timeScale / time.Second)
+		}
+
+		*seq++
+		mp4.PatchFragment(b, *seq, dts)
+
+		if _, err = w.Write(b); err != nil {
+			return true, err
+		}
+	}
+
+	return false, nil
+}
+
+// apiStreamM3U8 - HLS VOD playlist, one HLS segment for each recorded file
+func apiStreamM3U8(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+		return
+	}
+
+	query := r.URL.Query()
+	src := query.Get("src")
+
+	to := query.Get("to")
+	if i := core.Atoi(query.Get("duration")); i > 0 {
+		if start, err := parseTime(query.Get("start")); err == nil {
+			to = strconv.FormatInt(start.Unix()+int64(i), 10)
+		}
+	}
+
+	segments, err := findSegments(src, query.Get("start"), to)
+	if err != nil || len(segments) == 0 {
+		http.Error(w, "no records", http.StatusNotFound)
+		return
+	}
+
+	var maxDuration float64
+	for _, segment := range segments {
+		maxDuration = max(maxDuration, segment.Duration().Seconds())
+	}
+
+	src = url.QueryEscape(src)
+
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+	sb.WriteString("#EXT-X-TARGETDURATION:" + strconv.Itoa(int(math.Ceil(maxDuration))) + "\n")
+
+	for i, segment := range segments {
+		if i > 0 {
+			sb.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		file := url.QueryEscape(segment.name)
+		sb.WriteString(`#EXT-X-MAP:URI="init.mp4?src=` + src + "&file=" + file + "\"\n")
+		sb.WriteString("#EXT-X-PROGRAM-DATE-TIME:" + segment.Start.Format(time.RFC3339Nano) + "\n")
+		sb.WriteString(fmt.Sprintf("#EXTINF:%.3f,\n", segment.Duration().Seconds()))
+		sb.WriteString("segment.m4s?src=" + src + "&file=" + file + "\n")
+	}
+
+	sb.WriteString("#EXT-X-ENDLIST\n")
+
+	_, _ = w.Write([]byte(sb.String()))
+}
+
+func apiInit(w http.ResponseWriter, r *http.Request) {
+	apiFile(w, r, true)
+}
+
+func apiSegment(w http.ResponseWriter, r *http.Request) {
+	apiFile(w, r, false)
+}
+
+func apiFile(w http.ResponseWriter, r *http.Request, init bool) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+		return
+	}
+
+	query := r.URL.Query()
+
+	segment, err := getSegment(query.Get("src"), query.Get("file"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	f, err := readFile(segment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	if init {
+		w.Header().Set("Content-Type", "video/mp4")
+		http.ServeContent(w, r, "", segment.End, bytes.NewReader(f.init))
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/iso.segment")
+
+	// all complete fragments, the last one may be still recording
+	var rd io.ReadSeeker = bytes.NewReader(nil)
+	if n := len(f.fragments); n > 0 {
+		first, last := f.fragments[0], f.fragments[n-1]
+		rd = io.NewSectionReader(f, first.offset, last.offset+last.size-first.offset)
+	}
+
+	http.ServeContent(w, r, "", segment.End, rd)
+}
+
+// findSegments - segments of stream that intersect with time range
+func findSegments(src, from, to string) ([]*Segment, error) {
+	if streams.Get(src) == nil {
+		return nil, errors.New(api.StreamNotFound)
+	}
+
+	fromTime, err := parseTime(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toTime, err := parseTime(to)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := ListSegments(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Segment
+	for _, segment := range segments {
+		if !fromTime.IsZero() && segment.End.Before(fromTime) {
+			continue
+		}
+		if !toTime.IsZero() && !segment.Start.Before(toTime) {
+			continue
+		}
+		result = append(result, segment)
+	}
+
+	return result, nil
+}
+
+func getSegment(src, name string) (*Segment, error) {
+	if streams.Get(src) == nil {
+		return nil, errors.New(api.StreamNotFound)
+	}
+
+	// only valid file names, protects from path traversal
+	if _, ok := parseFilename(name); !ok {
+		return nil, fmt.Errorf("record: wrong file: %s", name)
+	}
+
+	segments, err := ListSegments(src)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, segment := range segments {
+		if segment.name == name {
+			return segment, nil
+		}
+	}
+
+	return nil, fmt.Errorf("record: file not found: %s", name)
+}
+
+// parseTime - support empty string, unix time in seconds and RFC 3339 format
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(i, 0), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,260 @@
+package record
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/iso"
+)
+
+type Segment struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Size  int64     `json:"size"`
+
+	name string
+	path string
+}
+
+func (s *Segment) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// ListSegments - recorded files of the stream, sorted by start time.
+// End time of the segment is the last modification time of the file.
+func ListSegments(name string) ([]*Segment, error) {
+	dir := StreamDir(name)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []*Segment
+
+	for _, entry := range entries {
+		start, ok := parseFilename(entry.Name())
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		segments = append(segments, &Segment{
+			Start: start,
+			End:   info.ModTime(),
+			Size:  info.Size(),
+			name:  entry.Name(),
+			path:  filepath.Join(dir, entry.Name()),
+		})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Start.Before(segments[j].Start)
+	})
+
+	return segments, nil
+}
+
+func parseFilename(name string) (time.Time, bool) {
+	s, ok := strings.CutSuffix(name, ".mp4")
+	if !ok {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(fileLayout, s)
+	return ts, err == nil
+}
+
+type track struct {
+	timeScale uint32
+	video     bool
+}
+
+// parseTracks - get tracks info from init (moov atom)
+func parseTracks(moov []byte) map[uint32]*track {
+	atoms, err := iso.DecodeAtoms(moov)
+	if err != nil {
+		return nil
+	}
+
+	var trackID uint32
+	tracks := map[uint32]*track{}
+
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTkhd:
+			trackID = atom.TrackID
+			tracks[trackID] = &track{}
+		case *iso.AtomMdhd:
+			if t := tracks[trackID]; t != nil {
+				t.timeScale = atom.TimeScale
+			}
+		case *iso.AtomVideo:
+			if t := tracks[trackID]; t != nil {
+				t.video = true
+			}
+		}
+	}
+
+	return tracks
+}
+
+func hasVideo(tracks map[uint32]*track) bool {
+	for _, t := range tracks {
+		if t.video {
+			return true
+		}
+	}
+	return false
+}
+
+type fragment struct {
+	trackID  uint32
+	dts      uint64
+	time     time.Time
+	keyframe bool
+	offset   int64 // moof position in the file
+	size     int64 // moof + mdat
+}
+
+type file struct {
+	*os.File
+	init      []byte
+	tracks    map[uint32]*track
+	fragments []*fragment
+}
+
+// readFile - open recorded file and index its fragments, only moov and moof
+// atoms are loaded into memory. Wall clock time of each fragment calculated
+// from file start time and fragment decode time. File should be closed.
+func readFile(segment *Segment) (*file, error) {
+	fd, err := os.Open(segment.path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := indexFile(fd, segment)
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func indexFile(fd *os.File, segment *Segment) (*file, error) {
+	info, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &file{File: fd}
+	first := map[uint32]uint64{}
+	video := false
+
+	var frag *fragment
+
+	header := make([]byte, 8)
+
+	for offset, total := int64(0), info.Size(); offset+8 <= total; {
+		if _, err = fd.ReadAt(header, offset); err != nil {
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		if size < 8 || offset+size > total {
+			break // file may be truncated
+		}
+
+		name := string(header[4:])
+
+		var atom []byte
+		switch name {
+		case iso.Ftyp, iso.Moov, iso.Moof:
+			atom = make([]byte, size)
+			if _, err = fd.ReadAt(atom, offset); err != nil {
+				return nil, err
+			}
+		}
+
+		switch name {
+		case iso.Ftyp:
+			f.init = atom
+		case iso.Moov:
+			f.init = append(f.init[:len(f.init):len(f.init)], atom...)
+			f.tracks = parseTracks(atom)
+			video = hasVideo(f.tracks)
+		case iso.Moof:
+			frag = &fragment{offset: offset}
+
+			atoms, err := iso.DecodeAtoms(atom)
+			if err != nil {
+				frag = nil
+				break
+			}
+
+			var sampleFlags uint32
+			for _, atom := range atoms {
+				switch atom := atom.(type) {
+				case *iso.AtomTfhd:
+					frag.trackID = atom.TrackID
+					sampleFlags = atom.SampleFlags
+				case *iso.AtomTfdt:
+					frag.dts = atom.DecodeTime
+				}
+			}
+
+			t := f.tracks[frag.trackID]
+			if t == nil || t.timeScale == 0 {
+				frag = nil
+				break
+			}
+
+			if t.video {
+				frag.keyframe = sampleFlags == iso.SampleVideoIFrame
+			} else {
+				frag.keyframe = !video
+			}
+
+			dts0, ok := first[frag.trackID]
+			if !ok {
+				dts0 = frag.dts
+				first[frag.trackID] = dts0
+			}
+
+			d := time.Duration(frag.dts-dts0) * time.Second / time.Duration(t.timeScale)
+			frag.time = segment.Start.Add(d)
+		case iso.Mdat:
+			if frag != nil {
+				frag.size = offset + size - frag.offset
+				f.fragments = append(f.fragments, frag)
+				frag = nil
+			}
+		}
+
+		offset += size
+	}
+
+	if f.tracks == nil {
+		return nil, errors.New("record: wrong file: " + segment.name)
+	}
+
+	return f, nil
+}
+
+// readFragment - moof and mdat atoms of the fragment
+func (f *file) readFragment(frag *fragment) ([]byte, error) {
+	b := make([]byte, frag.size)
+	if _, err := f.ReadAt(b, frag.offset); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
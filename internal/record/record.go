@@ -0,0 +1,198 @@
+package record
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/mp4"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var cfg struct {
+		Mod struct {
+			Path            string        `yaml:"path"`
+			SegmentDuration time.Duration `yaml:"segment_duration"`
+			MaxAge          time.Duration `yaml:"max_age"`
+			MaxSize         int64         `yaml:"max_size"` // in megabytes
+			Streams         []string      `yaml:"streams"`
+		} `yaml:"record"`
+	}
+
+	// default config
+	cfg.Mod.Path = "record"
+	cfg.Mod.SegmentDuration = time.Minute
+
+	app.LoadConfig(&cfg)
+
+	if len(cfg.Mod.Streams) == 0 {
+		return
+	}
+
+	log = app.GetLogger("record")
+
+	path = cfg.Mod.Path
+	segmentDuration = cfg.Mod.SegmentDuration
+
+	log.Info().Str("path", path).Strs("streams", cfg.Mod.Streams).Msg("[record] start")
+
+	initAPI()
+
+	for _, name := range cfg.Mod.Streams {
+		rec := &recorder{name: name, dir: StreamDir(name)}
+		recorders = append(recorders, rec)
+		go rec.run()
+	}
+
+	if cfg.Mod.MaxAge > 0 || cfg.Mod.MaxSize > 0 {
+		go func() {
+			for range time.Tick(time.Minute) {
+				cleanup(cfg.Mod.MaxAge, cfg.Mod.MaxSize<<20)
+			}
+		}()
+	}
+}
+
+// StreamDir - folder with segments of the stream
+func StreamDir(name string) string {
+	return filepath.Join(path, url.PathEscape(name))
+}
+
+var log zerolog.Logger
+
+var path string
+var segmentDuration time.Duration
+
+var recorders []*recorder
+
+const retryDelay = 10 * time.Second
+
+type recorder struct {
+	name string
+	dir  string
+
+	seg *segmenter
+	mu  sync.Mutex
+}
+
+func (r *recorder) run() {
+	for {
+		if err := r.record(); err != nil {
+			log.Warn().Err(err).Str("stream", r.name).Msg("[record] stop")
+		}
+		time.Sleep(retryDelay)
+	}
+}
+
+// record - blocks until write error. Producer reconnects are handled by the
+// streams module, so the consumer (and current segment) survives them.
+func (r *recorder) record() error {
+	stream := streams.Get(r.name)
+	if stream == nil {
+		return errors.New(api.StreamNotFound)
+	}
+
+	// all codecs supported by MP4 (FLAC, Opus and MP3 audio)
+	medias := mp4.ParseQuery(map[string][]string{"mp4": {"all"}})
+
+	cons := mp4.NewConsumer(medias)
+	cons.FormatName = "mp4"
+	cons.Protocol = "file"
+	cons.RemoteAddr = r.dir
+
+	if err := stream.AddConsumer(cons); err != nil {
+		return err
+	}
+
+	seg := &segmenter{dir: r.dir, duration: segmentDuration}
+
+	r.mu.Lock()
+	r.seg = seg
+	r.mu.Unlock()
+
+	log.Debug().Str("stream", r.name).Msg("[record] start")
+
+	_, err := cons.WriteTo(seg)
+
+	stream.RemoveConsumer(cons)
+
+	r.mu.Lock()
+	r.seg = nil
+	r.mu.Unlock()
+
+	_ = seg.Close()
+
+	return err
+}
+
+// activeFile - current writing segment, that shouldn't be removed
+func (r *recorder) activeFile() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seg != nil {
+		return r.seg.Filename()
+	}
+	return ""
+}
+
+type fileInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func cleanup(maxAge time.Duration, maxSize int64) {
+	active := map[string]bool{}
+	for _, rec := range recorders {
+		if name := rec.activeFile(); name != "" {
+			active[name] = true
+		}
+	}
+
+	var files []fileInfo
+	var total int64
+
+	_ = filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(name, ".mp4") {
+			return nil
+		}
+		if !active[name] {
+			files = append(files, fileInfo{path: name, size: info.Size(), modTime: info.ModTime()})
+		}
+		total += info.Size()
+		return nil
+	})
+
+	// oldest files first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	now := time.Now()
+
+	for _, file := range files {
+		expired := maxAge > 0 && now.Sub(file.modTime) > maxAge
+		oversize := maxSize > 0 && total > maxSize
+		if !expired && !oversize {
+			break
+		}
+
+		log.Trace().Str("path", file.path).Msg("[record] remove")
+
+		if err := os.Remove(file.path); err != nil {
+			log.Warn().Err(err).Caller().Send()
+			continue
+		}
+
+		total -= file.size
+	}
+}
@@ -0,0 +1,158 @@
+package record
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/iso"
+)
+
+// fileLayout - segment filename in UTC, sortable and without colons (Windows)
+const fileLayout = "20060102T150405.000Z"
+
+// segmenter - io.Writer for mp4.Consumer output. Splits the fMP4 stream into
+// files on keyframe boundaries. Each file starts with the same init (ftyp+moov).
+type segmenter struct {
+	dir      string
+	duration time.Duration
+
+	init   []byte
+	tracks map[uint32]*track
+
+	file  *os.File
+	start time.Time
+	mu    sync.Mutex
+}
+
+func (s *segmenter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// mp4.Consumer may flush several fragments in one write
+	for b := p; len(b) > 0; {
+		if len(b) < 8 {
+			return 0, errors.New("record: wrong atom size")
+		}
+
+		size := binary.BigEndian.Uint32(b)
+		if size < 8 || int(size) > len(b) {
+			return 0, errors.New("record: wrong atom size")
+		}
+
+		atom := b[:size]
+		b = b[size:]
+
+		switch string(atom[4:8]) {
+		case iso.Ftyp:
+			s.init = append([]byte{}, atom...)
+			continue
+		case iso.Moov:
+			s.init = append(s.init, atom...)
+			s.tracks = parseTracks(atom)
+			continue
+		case iso.Moof:
+			if s.needSplit(atom) {
+				if err := s.split(); err != nil {
+					return 0, err
+				}
+			}
+		}
+
+		if s.file == nil {
+			continue // wait for first keyframe
+		}
+
+		if _, err := s.file.Write(atom); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (s *segmenter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Filename - current segment path
+func (s *segmenter) Filename() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return ""
+	}
+	return s.file.Name()
+}
+
+func (s *segmenter) needSplit(moof []byte) bool {
+	if s.file != nil && time.Since(s.start) < s.duration {
+		return false
+	}
+
+	// audio only stream can be split on any fragment
+	if !hasVideo(s.tracks) {
+		return true
+	}
+
+	atoms, err := iso.DecodeAtoms(moof)
+	if err != nil {
+		return false
+	}
+
+	for _, atom := range atoms {
+		if tfhd, ok := atom.(*iso.AtomTfhd); ok {
+			t := s.tracks[tfhd.TrackID]
+			return t != nil && t.video && tfhd.SampleFlags == iso.SampleVideoIFrame
+		}
+	}
+
+	return false
+}
+
+func (s *segmenter) split() error {
+	if s.init == nil {
+		return errors.New("record: init segment not received")
+	}
+
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	s.start = time.Now()
+
+	name := filepath.Join(s.dir, s.start.UTC().Format(fileLayout)+".mp4")
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(s.init); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	log.Trace().Str("path", name).Msg("[record] new segment")
+
+	s.file = f
+
+	return nil
+}
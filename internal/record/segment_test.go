@@ -0,0 +1,111 @@
+package record
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/iso"
+	"github.com/AlexxIT/go2rtc/pkg/mp4"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmenter(t *testing.T) {
+	muxer := &mp4.Muxer{}
+	muxer.AddTrack(&core.Codec{Name: core.CodecH264, ClockRate: 90000})
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	iframe := &rtp.Packet{Payload: []byte{0, 0, 0, 1, 0x65}}
+	pframe := &rtp.Packet{Payload: []byte{0, 0, 0, 1, 0x41}}
+
+	seg := &segmenter{dir: t.TempDir()}
+
+	// first P-frame should be skipped, init and first fragment in one write
+	_, err = seg.Write(append(init, muxer.GetPayload(0, pframe)...))
+	require.Nil(t, err)
+	require.Equal(t, "", seg.Filename())
+
+	_, err = seg.Write(muxer.GetPayload(0, iframe))
+	require.Nil(t, err)
+	require.NotEqual(t, "", seg.Filename())
+
+	_, err = seg.Write(muxer.GetPayload(0, pframe))
+	require.Nil(t, err)
+
+	// zero duration - new file on each keyframe (filename with ms precision)
+	time.Sleep(time.Millisecond)
+	_, err = seg.Write(muxer.GetPayload(0, iframe))
+	require.Nil(t, err)
+
+	require.Nil(t, seg.Close())
+
+	files, err := os.ReadDir(seg.dir)
+	require.Nil(t, err)
+	require.Len(t, files, 2)
+
+	for _, file := range files {
+		b, err := os.ReadFile(seg.dir + "/" + file.Name())
+		require.Nil(t, err)
+		require.Equal(t, init, b[:len(init)])
+	}
+}
+
+func TestWriteRange(t *testing.T) {
+	path = t.TempDir()
+
+	muxer := &mp4.Muxer{}
+	muxer.AddTrack(&core.Codec{Name: core.CodecH264, ClockRate: 90000})
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	seg := &segmenter{dir: StreamDir("camera1")}
+	_, err = seg.Write(init)
+	require.Nil(t, err)
+
+	for i := 0; i < 4; i++ {
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Timestamp: uint32(i * 9000)},
+			Payload: []byte{0, 0, 0, 1, 0x41},
+		}
+		if i%2 == 0 {
+			packet.Payload[4] = 0x65
+			time.Sleep(time.Millisecond)
+		}
+		_, err = seg.Write(muxer.GetPayload(0, packet))
+		require.Nil(t, err)
+	}
+
+	require.Nil(t, seg.Close())
+
+	segments, err := ListSegments("camera1")
+	require.Nil(t, err)
+	require.Len(t, segments, 2)
+
+	buf := bytes.NewBuffer(nil)
+	err = writeRange(buf, segments[1:], segments[1].Start, segments[1].Start.Add(time.Minute))
+	require.Nil(t, err)
+
+	// init + keyframe + frame from second file
+	b := buf.Bytes()
+	require.Equal(t, init, b[:len(init)])
+
+	atoms, err := iso.DecodeAtoms(b[len(init):])
+	require.Nil(t, err)
+
+	var seqs []uint32
+	for _, atom := range atoms {
+		if mfhd, ok := atom.(*iso.AtomMfhd); ok {
+			seqs = append(seqs, mfhd.Sequence)
+		}
+		if tfdt, ok := atom.(*iso.AtomTfdt); ok && len(seqs) == 1 {
+			require.Equal(t, uint64(0), tfdt.DecodeTime)
+		}
+	}
+	require.Equal(t, []uint32{1, 2}, seqs)
+}
@@ -1,13 +1,17 @@
 package rtmp
 
 import (
+	"crypto/subtle"
 	"errors"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/flv"
@@ -18,7 +22,8 @@ import (
 func Init() {
 	var conf struct {
 		Mod struct {
-			Listen string `yaml:"listen" json:"listen"`
+			Listen string            `yaml:"listen" json:"listen"`
+			Keys   map[string]string `yaml:"keys" json:"-"` // stream name => publish key
 		} `yaml:"rtmp"`
 	}
 
@@ -26,6 +31,8 @@ func Init() {
 
 	log = app.GetLogger("rtmp")
 
+	keys = conf.Mod.Keys
+
 	streams.HandleFunc("rtmp", streamsHandle)
 	streams.HandleFunc("rtmps", streamsHandle)
 	streams.HandleFunc("rtmpx", streamsHandle)
@@ -75,11 +82,26 @@ func tcpHandle(netConn net.Conn) error {
 		return err
 	}
 
+	// rtmp://host/{name}?token={token}
+	name, rawQuery, _ := strings.Cut(rtmpConn.App, "?")
+
+	if rtmpConn.Intent == rtmp.CommandPublish && len(keys) > 0 {
+		// rtmp://host/{app}/{key} - stream selected by the publish key
+		if name = streamByKey(rtmpConn.Stream); name == "" {
+			_ = rtmpConn.WriteError("NetStream.Publish.BadName", "Wrong stream key")
+			return errors.New("rtmp: wrong publish key from " + netConn.RemoteAddr().String())
+		}
+	} else if auth.Enabled() && !netConn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback() {
+		if err = authorize(name, rawQuery, rtmpConn.Stream, rtmpConn.Intent); err != nil {
+			return err
+		}
+	}
+
 	switch rtmpConn.Intent {
 	case rtmp.CommandPlay:
-		stream := streams.Get(rtmpConn.App)
+		stream := streams.Get(name)
 		if stream == nil {
-			return errors.New("stream not found: " + rtmpConn.App)
+			return errors.New("stream not found: " + name)
 		}
 
 		cons := flv.NewConsumer()
@@ -98,9 +120,9 @@ func tcpHandle(netConn net.Conn) error {
 		return nil
 
 	case rtmp.CommandPublish:
-		stream := streams.Get(rtmpConn.App)
+		stream := streams.Get(name)
 		if stream == nil {
-			return errors.New("stream not found: " + rtmpConn.App)
+			return errors.New("stream not found: " + name)
 		}
 
 		if err = rtmpConn.WriteStart(); err != nil {
@@ -126,6 +148,42 @@ func tcpHandle(netConn net.Conn) error {
 
 var log zerolog.Logger
 
+var keys map[string]string
+
+// streamByKey - stream name for the publish key or empty string
+func streamByKey(key string) string {
+	for name, secret := range keys {
+		if secret != "" && subtle.ConstantTimeCompare([]byte(key), []byte(secret)) == 1 {
+			return name
+		}
+	}
+	return ""
+}
+
+// authorize - token from the app query or from the stream key
+func authorize(name, rawQuery, key, intent string) error {
+	token := key
+	if query, err := url.ParseQuery(rawQuery); err == nil && query.Has("token") {
+		token = query.Get("token")
+	}
+
+	id, err := auth.ParseToken(token)
+	if err != nil {
+		return err
+	}
+
+	action := auth.View
+	if intent == rtmp.CommandPublish {
+		action = auth.Publish
+	}
+
+	if !id.Allowed(name, action) {
+		return errors.New("rtmp: access denied to stream: " + name)
+	}
+
+	return nil
+}
+
 func streamsHandle(url string) (core.Producer, error) {
 	return rtmp.DialPlay(url)
 }
@@ -164,6 +222,7 @@ func outputFLV(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), streams.StatusCode(err))
 		return
 	}
 
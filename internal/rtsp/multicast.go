@@ -0,0 +1,91 @@
+package rtsp
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/rtsp"
+)
+
+// multicast group address for each stream from config
+var multicasts map[string]*net.UDPAddr
+
+func initMulticast(config map[string]string) {
+	for name, address := range config {
+		addr, err := net.ResolveUDPAddr("udp4", address)
+		if err != nil || !addr.IP.IsMulticast() || addr.Port == 0 {
+			log.Warn().Str("stream", name).Str("addr", address).Msg("[rtsp] wrong multicast address")
+			continue
+		}
+
+		if multicasts == nil {
+			multicasts = map[string]*net.UDPAddr{}
+		}
+		multicasts[name] = addr
+	}
+}
+
+type group struct {
+	conn    *rtsp.Conn
+	viewers int
+}
+
+var groups = map[string]*group{}
+var groupsMu sync.Mutex
+
+// joinMulticast - shared multicast consumer of the stream, created by the first viewer
+func joinMulticast(name string, stream *streams.Stream, medias []*core.Media) (*rtsp.Conn, error) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+
+	g := groups[name]
+	if g == nil {
+		addr := multicasts[name]
+		if addr == nil {
+			return nil, errors.New("rtsp: multicast not enabled for stream")
+		}
+
+		clone := make([]*core.Media, len(medias))
+		for i, media := range medias {
+			clone[i] = media.Clone()
+		}
+
+		conn := rtsp.NewMulticast(addr, clone)
+		if err := stream.AddConsumer(conn); err != nil {
+			_ = conn.Stop()
+			return nil, err
+		}
+
+		log.Debug().Str("stream", name).Str("addr", addr.String()).Msg("[rtsp] multicast start")
+
+		g = &group{conn: conn}
+		groups[name] = g
+	}
+
+	g.viewers++
+
+	return g.conn, nil
+}
+
+func leaveMulticast(name string, stream *streams.Stream) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+
+	g := groups[name]
+	if g == nil {
+		return
+	}
+
+	if g.viewers--; g.viewers > 0 {
+		return
+	}
+
+	delete(groups, name)
+
+	stream.RemoveConsumer(g.conn)
+
+	log.Debug().Str("stream", name).Msg("[rtsp] multicast stop")
+}
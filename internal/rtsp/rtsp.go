@@ -1,6 +1,9 @@
 package rtsp
 
 import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"io"
 	"net"
@@ -8,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/rtsp"
@@ -23,6 +27,12 @@ func Init() {
 			Password     string `yaml:"password" json:"-"`
 			DefaultQuery string `yaml:"default_query" json:"default_query"`
 			PacketSize   uint16 `yaml:"pkt_size" json:"pkt_size,omitempty"`
+			TLSListen    string `yaml:"tls_listen" json:"tls_listen,omitempty"`
+			TLSCert      string `yaml:"tls_cert" json:"-"`
+			TLSKey       string `yaml:"tls_key" json:"-"`
+			BasicAuth    bool   `yaml:"basic_auth" json:"basic_auth,omitempty"`
+
+			Multicast map[string]string `yaml:"multicast" json:"multicast,omitempty"`
 		} `yaml:"rtsp"`
 	}
 
@@ -35,32 +45,18 @@ func Init() {
 
 	log = app.GetLogger("rtsp")
 
+	initMulticast(conf.Mod.Multicast)
+
 	// RTSP client support
 	streams.HandleFunc("rtsp", rtspHandler)
 	streams.HandleFunc("rtsps", rtspHandler)
 	streams.HandleFunc("rtspx", rtspHandler)
 
-	// RTSP server support
-	address := conf.Mod.Listen
-	if address == "" {
-		return
-	}
-
-	ln, err := net.Listen("tcp", address)
-	if err != nil {
-		log.Error().Err(err).Msg("[rtsp] listen")
-		return
-	}
-
-	_, Port, _ = net.SplitHostPort(address)
-
-	log.Info().Str("addr", address).Msg("[rtsp] listen")
-
 	if query, err := url.ParseQuery(conf.Mod.DefaultQuery); err == nil {
 		defaultMedias = ParseQuery(query)
 	}
 
-	go func() {
+	serve := func(ln net.Listener, protocol string) {
 		for {
 			conn, err := ln.Accept()
 			if err != nil {
@@ -69,13 +65,67 @@ func Init() {
 
 			c := rtsp.NewServer(conn)
 			c.PacketSize = conf.Mod.PacketSize
+			c.Protocol = protocol
+			// Basic sends password in the clear, so only with TLS or explicit option
+			c.BasicAuth = conf.Mod.BasicAuth || protocol == "rtsp+tls"
 			// skip check auth for localhost
-			if conf.Mod.Username != "" && !conn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback() {
-				c.Auth(conf.Mod.Username, conf.Mod.Password)
+			if !conn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback() {
+				if auth.Enabled() {
+					c.AuthFunc(func(req *tcp.Request) bool {
+						return authorize(req, conf.Mod.Username, conf.Mod.Password)
+					})
+				} else if conf.Mod.Username != "" {
+					c.Auth(conf.Mod.Username, conf.Mod.Password)
+				}
 			}
 			go tcpHandler(c)
 		}
-	}()
+	}
+
+	// RTSP server support
+	if address := conf.Mod.Listen; address != "" {
+		if ln, err := net.Listen("tcp", address); err == nil {
+			_, Port, _ = net.SplitHostPort(address)
+
+			log.Info().Str("addr", address).Msg("[rtsp] listen")
+
+			go serve(ln, "rtsp+tcp")
+		} else {
+			log.Error().Err(err).Msg("[rtsp] listen")
+		}
+	}
+
+	// RTSPS server support
+	if address := conf.Mod.TLSListen; address != "" && conf.Mod.TLSCert != "" && conf.Mod.TLSKey != "" {
+		if ln, err := tlsListen(address, conf.Mod.TLSCert, conf.Mod.TLSKey); err == nil {
+			log.Info().Str("addr", address).Msg("[rtsp] tls listen")
+
+			go serve(ln, "rtsp+tls")
+		} else {
+			log.Error().Err(err).Msg("[rtsp] tls listen")
+		}
+	}
+}
+
+// tlsListen - cert and key can be file paths or PEM-encoded content, like in the api module
+func tlsListen(address, certFile, keyFile string) (net.Listener, error) {
+	var cert tls.Certificate
+	var err error
+	if strings.IndexByte(certFile, '\n') < 0 && strings.IndexByte(keyFile, '\n') < 0 {
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	} else {
+		cert, err = tls.X509KeyPair([]byte(certFile), []byte(keyFile))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}}), nil
 }
 
 type Handler func(conn *rtsp.Conn) bool
@@ -146,6 +196,7 @@ func rtspHandler(rawURL string) (core.Producer, error) {
 func tcpHandler(conn *rtsp.Conn) {
 	var name string
 	var closer func()
+	var multicast *rtsp.Conn
 
 	trace := log.Trace().Enabled()
 	level := zerolog.WarnLevel
@@ -218,11 +269,30 @@ func tcpHandler(conn *rtsp.Conn) {
 
 			if err := stream.AddConsumer(conn); err != nil {
 				log.WithLevel(level).Err(err).Str("stream", name).Msg("[rtsp]")
+				if errors.Is(err, streams.ErrLimit) {
+					conn.RejectStatus = rtsp.StatusNotEnoughBandwidth
+				}
 				return
 			}
 
+			if multicasts[name] != nil {
+				medias := conn.Medias
+				conn.OnMulticast = func() (*rtsp.Conn, error) {
+					if multicast == nil {
+						var err error
+						if multicast, err = joinMulticast(name, stream, medias); err != nil {
+							return nil, err
+						}
+					}
+					return multicast, nil
+				}
+			}
+
 			closer = func() {
 				stream.RemoveConsumer(conn)
+				if multicast != nil {
+					leaveMulticast(name, stream)
+				}
 			}
 
 		case rtsp.MethodAnnounce:
@@ -285,6 +355,67 @@ func tcpHandler(conn *rtsp.Conn) {
 	_ = conn.Close()
 }
 
+// authorize - check playback token, basic or digest credentials of the request.
+// DESCRIBE requires view access to the stream, ANNOUNCE - publish access.
+func authorize(req *tcp.Request, username, password string) bool {
+	var id *auth.Identity
+
+	header := req.Header.Get("Authorization")
+
+	if token := req.URL.Query().Get("token"); token != "" {
+		id, _ = auth.ParseToken(token)
+	} else if user := tcp.DigestUsername(header); user != "" {
+		if username != "" && user == username && tcp.ValidateDigest(req, rtsp.Realm, username, password) {
+			id = auth.Admin
+		} else {
+			id = auth.LoginFunc(user, func(pass string) bool {
+				return tcp.ValidateDigest(req, rtsp.Realm, user, pass)
+			})
+		}
+	} else if user, pass, ok := parseBasic(header); ok {
+		if username != "" &&
+			subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1 {
+			id = auth.Admin
+		} else {
+			id = auth.Login(user, pass)
+		}
+	}
+
+	if id == nil {
+		return false
+	}
+
+	var name string
+	if len(req.URL.Path) > 0 {
+		name = req.URL.Path[1:]
+	}
+
+	switch req.Method {
+	case rtsp.MethodDescribe:
+		if req.URL.Query().Get("backchannel") == "1" && !id.Allowed(name, auth.Backchannel) {
+			return false
+		}
+		return id.Allowed(name, auth.View)
+	case rtsp.MethodAnnounce:
+		return id.Allowed(name, auth.Publish)
+	}
+
+	return true
+}
+
+func parseBasic(header string) (username, password string, ok bool) {
+	s, ok := strings.CutPrefix(header, "Basic ")
+	if !ok {
+		return
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(b), ":")
+}
+
 func ParseQuery(query map[string][]string) []*core.Media {
 	if v := query["mp4"]; v != nil {
 		return []*core.Media{
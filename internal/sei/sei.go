@@ -0,0 +1,143 @@
+package sei
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/api/ws"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/sei"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	ws.HandleFunc("metadata", handlerWS)
+
+	log = app.GetLogger("sei")
+}
+
+var log zerolog.Logger
+
+// channel - one SEI consumer of the stream for all subscribers
+type channel struct {
+	stream   *streams.Stream
+	cons     *sei.Consumer
+	handlers map[int]func(md *sei.Metadata)
+	nextID   int
+}
+
+var channels = map[*streams.Stream]*channel{}
+var channelsMu sync.Mutex
+
+// Subscribe - metadata from the stream video SEI messages, handler shouldn't block
+func Subscribe(stream *streams.Stream, handler func(md *sei.Metadata)) (func(), error) {
+	for {
+		channelsMu.Lock()
+		if ch := channels[stream]; ch != nil {
+			unsubscribe := ch.add(handler)
+			channelsMu.Unlock()
+			return unsubscribe, nil
+		}
+		channelsMu.Unlock()
+
+		ch := &channel{stream: stream, cons: sei.NewConsumer(), handlers: map[int]func(md *sei.Metadata){}}
+		ch.cons.Listen(func(msg any) {
+			if md, ok := msg.(*sei.Metadata); ok {
+				ch.fire(md)
+			}
+		})
+
+		// stream may dial the source for some time, so without lock,
+		// shared consumer is internal, like HLS packager, and doesn't take a viewer slot
+		if err := stream.AddSessionConsumer(ch.cons); err != nil {
+			return nil, err
+		}
+
+		channelsMu.Lock()
+		if channels[stream] == nil {
+			channels[stream] = ch
+			unsubscribe := ch.add(handler)
+			channelsMu.Unlock()
+			return unsubscribe, nil
+		}
+		channelsMu.Unlock()
+
+		stream.RemoveConsumer(ch.cons) // another subscriber was faster
+	}
+}
+
+// add - called under lock
+func (ch *channel) add(handler func(md *sei.Metadata)) func() {
+	id := ch.nextID
+	ch.nextID++
+	ch.handlers[id] = handler
+
+	return func() {
+		channelsMu.Lock()
+		delete(ch.handlers, id)
+		last := len(ch.handlers) == 0 && channels[ch.stream] == ch
+		if last {
+			delete(channels, ch.stream)
+		}
+		channelsMu.Unlock()
+
+		if last {
+			ch.stream.RemoveConsumer(ch.cons)
+		}
+	}
+}
+
+func (ch *channel) fire(md *sei.Metadata) {
+	channelsMu.Lock()
+	handlers := make([]func(md *sei.Metadata), 0, len(ch.handlers))
+	for _, handler := range ch.handlers {
+		handlers = append(handlers, handler)
+	}
+	channelsMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(md)
+	}
+}
+
+func handlerWS(tr *ws.Transport, _ *ws.Message) error {
+	stream := streams.GetOrPatch(tr.Request.URL.Query())
+	if stream == nil {
+		return errors.New(api.StreamNotFound)
+	}
+
+	queue := make(chan *sei.Metadata, 100)
+
+	unsubscribe, err := Subscribe(stream, func(md *sei.Metadata) {
+		select {
+		case queue <- md:
+		default: // slow client
+		}
+	})
+	if err != nil {
+		log.Debug().Err(err).Msg("[sei] add consumer")
+		return err
+	}
+
+	done := make(chan struct{})
+
+	tr.OnClose(func() {
+		unsubscribe()
+		close(done)
+	})
+
+	go func() {
+		for {
+			select {
+			case md := <-queue:
+				tr.Write(&ws.Message{Type: "metadata", Value: md})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package srt
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/mpegts"
+	"github.com/AlexxIT/go2rtc/pkg/srt"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var conf struct {
+		Mod struct {
+			Listen     string `yaml:"listen" json:"listen"`
+			Latency    int    `yaml:"latency" json:"latency"` // ms
+			Passphrase string `yaml:"passphrase" json:"-"`
+		} `yaml:"srt"`
+	}
+
+	app.LoadConfig(&conf)
+
+	log = app.GetLogger("srt")
+
+	streams.HandleFunc("srt", streamsHandle)
+
+	streams.HandleConsumerFunc("srt", streamsConsumerHandle)
+
+	address := conf.Mod.Listen
+	if address == "" {
+		return
+	}
+
+	if conf.Mod.Passphrase != "" {
+		if err := srt.CheckPassphrase(conf.Mod.Passphrase); err != nil {
+			log.Error().Err(err).Caller().Send()
+			return
+		}
+	}
+
+	ln, err := srt.Listen(address, srt.Config{
+		Latency:    time.Duration(conf.Mod.Latency) * time.Millisecond,
+		Passphrase: conf.Mod.Passphrase,
+		Validate:   validate,
+	})
+	if err != nil {
+		log.Error().Err(err).Caller().Send()
+		return
+	}
+
+	log.Info().Str("addr", address).Msg("[srt] listen")
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				if err = handle(conn); err != nil {
+					log.Debug().Err(err).Caller().Send()
+				}
+				_ = conn.Close()
+			}()
+		}
+	}()
+}
+
+var log zerolog.Logger
+
+// parseStreamID - SRT access control "#!::r=camera1,m=publish,token=...",
+// simple "publish:camera1", "read:camera1" or "camera1?token=..."
+func parseStreamID(streamID string) (name string, publish bool, token string) {
+	if s, ok := strings.CutPrefix(streamID, "#!::"); ok {
+		for _, kv := range strings.Split(s, ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "r":
+				name = v
+			case "m":
+				publish = v == "publish"
+			case "token":
+				token = v
+			}
+		}
+		return
+	}
+
+	if s, ok := strings.CutPrefix(streamID, "publish:"); ok {
+		streamID, publish = s, true
+	} else {
+		streamID = strings.TrimPrefix(streamID, "read:")
+	}
+
+	name, rawQuery, _ := strings.Cut(streamID, "?")
+	if query, err := url.ParseQuery(rawQuery); err == nil {
+		token = query.Get("token")
+	}
+
+	return
+}
+
+// validate - reject unknown streams and unauthorized clients before the handshake ends
+func validate(streamID string, addr net.Addr) srt.RejectReason {
+	name, publish, token := parseStreamID(streamID)
+
+	if streams.Get(name) == nil {
+		return srt.RejectNotFound
+	}
+
+	if auth.Enabled() && !addr.(*net.UDPAddr).IP.IsLoopback() {
+		if err := authorize(name, publish, token); err != nil {
+			log.Debug().Err(err).Stringer("addr", addr).Send()
+			return srt.RejectForbidden
+		}
+	}
+
+	return 0
+}
+
+func authorize(name string, publish bool, token string) error {
+	id, err := auth.ParseToken(token)
+	if err != nil {
+		return err
+	}
+
+	action := auth.View
+	if publish {
+		action = auth.Publish
+	}
+
+	if !id.Allowed(name, action) {
+		return errors.New("srt: access denied to stream: " + name)
+	}
+
+	return nil
+}
+
+func handle(conn *srt.Conn) error {
+	name, publish, _ := parseStreamID(conn.StreamID)
+
+	stream := streams.Get(name)
+	if stream == nil {
+		return errors.New("srt: stream not found: " + name)
+	}
+
+	if publish {
+		prod, err := mpegts.Open(conn)
+		if err != nil {
+			return err
+		}
+
+		prod.Protocol = "srt"
+		prod.RemoteAddr = conn.RemoteAddr().String()
+
+		stream.AddProducer(prod)
+
+		defer stream.RemoveProducer(prod)
+
+		_ = prod.Start()
+
+		return nil
+	}
+
+	cons := mpegts.NewConsumer()
+	cons.Protocol = "srt"
+	cons.RemoteAddr = conn.RemoteAddr().String()
+
+	if err := stream.AddConsumer(cons); err != nil {
+		return err
+	}
+
+	defer stream.RemoveConsumer(cons)
+
+	_, _ = cons.WriteTo(conn)
+
+	return nil
+}
+
+func streamsHandle(rawURL string) (core.Producer, error) {
+	conn, err := srt.Dial(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	prod, err := mpegts.Open(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	prod.Protocol = "srt"
+	prod.RemoteAddr = conn.RemoteAddr().String()
+
+	return prod, nil
+}
+
+func streamsConsumerHandle(rawURL string) (core.Consumer, func(), error) {
+	cons := mpegts.NewConsumer()
+	run := func() {
+		conn, err := srt.Dial(rawURL)
+		if err != nil {
+			return
+		}
+		_, _ = cons.WriteTo(conn)
+		_ = conn.Close()
+	}
+
+	return cons, run, nil
+}
@@ -7,7 +7,23 @@ import (
 	"github.com/AlexxIT/go2rtc/pkg/core"
 )
 
-func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
+func (s *Stream) AddConsumer(cons core.Consumer) error {
+	release, err := s.checkLimits(cons)
+	if err != nil {
+		log.Debug().Err(err).Msg("[streams] add consumer")
+		return err
+	}
+	defer release()
+
+	if viewer, ok := cons.(*SharedConsumer); ok {
+		return s.addSharedConsumer(viewer)
+	}
+
+	return s.addConsumer(cons)
+}
+
+// addConsumer - add consumer without limits check (buffer, publish)
+func (s *Stream) addConsumer(cons core.Consumer) (err error) {
 	// support for multiple simultaneous pending from different consumers
 	consN := s.pending.Add(1) - 1
 
@@ -106,6 +122,8 @@ func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
 	s.consumers = append(s.consumers, cons)
 	s.mu.Unlock()
 
+	s.publish("consumer_add", cons)
+
 	// there may be duplicates, but that's not a problem
 	for _, prod := range prodStarts {
 		prod.start()
@@ -1,10 +1,12 @@
 package streams
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
 	"github.com/AlexxIT/go2rtc/pkg/probe"
 )
 
@@ -31,15 +33,15 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 		if len(cons.Medias) != 0 {
 			cons.WithRequest(r)
 			if err := stream.AddConsumer(cons); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				http.Error(w, err.Error(), StatusCode(err))
 				return
 			}
 
-			api.ResponsePrettyJSON(w, stream)
+			responseStream(w, r, stream)
 
 			stream.RemoveConsumer(cons)
 		} else {
-			api.ResponsePrettyJSON(w, streams[src])
+			responseStream(w, r, stream)
 		}
 
 	case "PUT":
@@ -48,12 +50,20 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 			name = src
 		}
 
-		if New(name, query["src"]...) == nil {
+		stream := New(name, query["src"]...)
+		if stream == nil {
 			http.Error(w, "", http.StatusBadRequest)
 			return
 		}
 
-		if err := app.PatchConfig([]string{"streams", name}, query["src"]); err != nil {
+		var value any = query["src"]
+
+		if buffer := query.Get("buffer"); buffer != "" {
+			stream.StartBuffer(ParseDuration(buffer))
+			value = map[string]any{"url": query["src"], "buffer": buffer}
+		}
+
+		if err := app.PatchConfig([]string{"streams", name}, value); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 		}
 
@@ -94,7 +104,7 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "DELETE":
-		delete(streams, src)
+		Delete(src)
 
 		if err := app.PatchConfig([]string{"streams", src}, nil); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -122,3 +132,48 @@ func apiStreamsDOT(w http.ResponseWriter, r *http.Request) {
 
 	api.Response(w, dot, "text/vnd.graphviz")
 }
+
+// responseStream - only admins can see sources (may have credentials) and remote addresses
+func responseStream(w http.ResponseWriter, r *http.Request, stream *Stream) {
+	if auth.FromRequest(r).Admin {
+		api.ResponsePrettyJSON(w, stream)
+		return
+	}
+
+	info, err := redactStream(stream)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.ResponsePrettyJSON(w, info)
+}
+
+var redactKeys = []string{"url", "source", "remote_addr", "sdp", "user_agent", "last_error"}
+
+func redactStream(stream *Stream) (map[string]any, error) {
+	b, err := json.Marshal(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var info map[string]any
+	if err = json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+
+	delete(info, "active")
+
+	for _, key := range []string{"producers", "consumers"} {
+		items, _ := info[key].([]any)
+		for _, item := range items {
+			if conn, ok := item.(map[string]any); ok {
+				for _, k := range redactKeys {
+					delete(conn, k)
+				}
+			}
+		}
+	}
+
+	return info, nil
+}
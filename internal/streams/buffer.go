@@ -0,0 +1,285 @@
+package streams
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/av1"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
+	"github.com/AlexxIT/go2rtc/pkg/vp9"
+	"github.com/pion/rtp"
+)
+
+// Buffer - consumer that keeps the last packets of the stream in memory.
+// Video tracks are stored from keyframe (GOP aligned). Any consumer can be
+// attached to the buffer and get packets from the past and then live packets.
+type Buffer struct {
+	core.Connection
+
+	duration time.Duration
+	tracks   []*bufferTrack
+	mu       sync.Mutex
+}
+
+type bufferTrack struct {
+	media *core.Media
+	codec *core.Codec // codec of stored packets
+
+	keyframe func([]byte) bool // nil for audio tracks
+	packets  []*bufferPacket
+
+	// live receivers of attached consumers
+	receivers []*core.Receiver
+}
+
+type bufferPacket struct {
+	*rtp.Packet
+	time     time.Time
+	keyframe bool
+}
+
+func NewBuffer(duration time.Duration) *Buffer {
+	return &Buffer{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: "buffer",
+			Protocol:   "memory",
+			Medias: []*core.Media{
+				{
+					Kind:      core.KindVideo,
+					Direction: core.DirectionSendonly,
+					Codecs: []*core.Codec{
+						{Name: core.CodecH264},
+						{Name: core.CodecH265},
+						{Name: core.CodecAV1},
+						{Name: core.CodecVP9},
+					},
+				},
+				{
+					Kind:      core.KindAudio,
+					Direction: core.DirectionSendonly,
+					Codecs: []*core.Codec{
+						{Name: core.CodecAAC},
+						{Name: core.CodecOpus},
+						{Name: core.CodecPCMA},
+						{Name: core.CodecPCMU},
+						{Name: core.CodecPCM},
+						{Name: core.CodecPCML},
+						{Name: core.CodecMP3},
+					},
+				},
+			},
+		},
+		duration: duration,
+	}
+}
+
+func (b *Buffer) AddTrack(media *core.Media, _ *core.Codec, track *core.Receiver) error {
+	t := &bufferTrack{media: media, codec: track.Codec}
+
+	sender := core.NewSender(media, track.Codec)
+	sender.Handler = func(packet *rtp.Packet) {
+		b.push(t, packet)
+	}
+
+	switch track.Codec.Name {
+	case core.CodecH264:
+		t.keyframe = h264.IsKeyframe
+		if track.Codec.IsRTP() {
+			sender.Handler = h264.RTPDepay(track.Codec, sender.Handler)
+		} else {
+			sender.Handler = h264.RepairAVCC(track.Codec, sender.Handler)
+		}
+	case core.CodecH265:
+		t.keyframe = h265.IsKeyframe
+		if track.Codec.IsRTP() {
+			sender.Handler = h265.RTPDepay(track.Codec, sender.Handler)
+		} else {
+			sender.Handler = h265.RepairAVCC(track.Codec, sender.Handler)
+		}
+	case core.CodecAV1:
+		t.keyframe = av1.IsKeyframe
+		if track.Codec.IsRTP() {
+			sender.Handler = av1.RTPDepay(sender.Handler)
+		}
+	case core.CodecVP9:
+		t.keyframe = vp9.IsKeyframe
+		if track.Codec.IsRTP() {
+			sender.Handler = vp9.RTPDepay(sender.Handler)
+		}
+	}
+
+	if t.keyframe != nil {
+		// video stored in AVCC (or raw) format after depay
+		t.codec = track.Codec.Clone()
+		t.codec.PayloadType = core.PayloadTypeRAW
+	}
+
+	sender.HandleRTP(track)
+
+	b.mu.Lock()
+	b.Senders = append(b.Senders, sender)
+	b.tracks = append(b.tracks, t)
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *Buffer) push(t *bufferTrack, packet *rtp.Packet) {
+	now := time.Now()
+
+	p := &bufferPacket{Packet: packet, time: now}
+
+	if t.keyframe != nil {
+		// depay reuses payload memory
+		clone := *packet
+		clone.Payload = append([]byte(nil), packet.Payload...)
+		p.Packet = &clone
+		p.keyframe = t.keyframe(clone.Payload)
+	}
+
+	b.mu.Lock()
+
+	t.packets = append(t.packets, p)
+	b.trim(t, now)
+
+	for _, receiver := range t.receivers {
+		receiver.Input(p.Packet)
+	}
+
+	b.mu.Unlock()
+}
+
+func (b *Buffer) trim(t *bufferTrack, now time.Time) {
+	deadline := now.Add(-b.duration)
+
+	if t.keyframe != nil {
+		// video: keep the latest keyframe older than deadline
+		if !t.packets[len(t.packets)-1].keyframe {
+			// protection from sources without keyframes
+			if now.Sub(t.packets[0].time) > 3*b.duration {
+				t.packets = t.packets[len(t.packets)-1:]
+			}
+			return
+		}
+
+		i := 0
+		for j, p := range t.packets {
+			if p.time.After(deadline) {
+				break
+			}
+			if p.keyframe {
+				i = j
+			}
+		}
+		t.packets = t.packets[i:]
+		return
+	}
+
+	// audio: don't need more than oldest video
+	for _, track := range b.tracks {
+		if track.keyframe != nil && len(track.packets) > 0 && track.packets[0].time.Before(deadline) {
+			deadline = track.packets[0].time
+		}
+	}
+
+	i := 0
+	for i < len(t.packets) && t.packets[i].time.Before(deadline) {
+		i++
+	}
+	t.packets = t.packets[i:]
+}
+
+// Attach - add consumer to the buffer. Consumer will get buffered packets
+// starting from the last keyframe before (now - before) and then live packets.
+func (b *Buffer) Attach(cons core.Consumer, before time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// start from the last keyframe before (now - before) or from the first keyframe
+	target := time.Now().Add(-before)
+
+	var start time.Time
+	for _, t := range b.tracks {
+		if t.keyframe == nil {
+			continue
+		}
+		for _, p := range t.packets {
+			if !p.keyframe {
+				continue
+			}
+			if start.IsZero() || !p.time.After(target) {
+				start = p.time
+			} else {
+				break
+			}
+		}
+		break // first video track is enough
+	}
+
+	if start.IsZero() {
+		start = target
+	}
+
+	type replay struct {
+		track    *bufferTrack
+		receiver *core.Receiver
+	}
+
+	var replays []replay
+
+	for _, consMedia := range cons.GetMedias() {
+		for _, t := range b.tracks {
+			codec, consCodec := t.media.MatchMedia(consMedia)
+			if codec == nil {
+				continue
+			}
+
+			receiver := core.NewReceiver(t.media, t.codec)
+			if err := cons.AddTrack(consMedia, consCodec, receiver); err != nil {
+				continue
+			}
+
+			replays = append(replays, replay{track: t, receiver: receiver})
+
+			if !consMedia.MatchAll() {
+				break
+			}
+		}
+	}
+
+	if replays == nil {
+		return errors.New("streams: buffer: codecs not matched")
+	}
+
+	for _, r := range replays {
+		for _, p := range r.track.packets {
+			if p.time.Before(start) {
+				continue
+			}
+			// sender buffer too small for the whole GOP
+			r.receiver.Replay(p.Packet)
+		}
+		r.track.receivers = append(r.track.receivers, r.receiver)
+	}
+
+	return nil
+}
+
+// Detach - remove closed receivers of the consumer
+func (b *Buffer) Detach() {
+	b.mu.Lock()
+	for _, t := range b.tracks {
+		receivers := t.receivers[:0]
+		for _, receiver := range t.receivers {
+			if len(receiver.Senders()) > 0 {
+				receivers = append(receivers, receiver)
+			}
+		}
+		t.receivers = receivers
+	}
+	b.mu.Unlock()
+}
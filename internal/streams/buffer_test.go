@@ -0,0 +1,82 @@
+package streams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferTrim(t *testing.T) {
+	b := NewBuffer(time.Second)
+
+	video := &bufferTrack{keyframe: func([]byte) bool { return false }}
+	audio := &bufferTrack{}
+	b.tracks = []*bufferTrack{video, audio}
+
+	now := time.Now()
+	for i, keyframe := range []bool{true, false, true, false, true} {
+		ts := now.Add(time.Duration(i-4) * time.Second) // -4s ... 0s
+		video.packets = append(video.packets, &bufferPacket{Packet: &rtp.Packet{}, time: ts, keyframe: keyframe})
+		audio.packets = append(audio.packets, &bufferPacket{Packet: &rtp.Packet{}, time: ts})
+	}
+
+	// keep latest keyframe older than deadline (-2s)
+	b.trim(video, now)
+	require.Len(t, video.packets, 3)
+	require.True(t, video.packets[0].keyframe)
+
+	// audio not older than video
+	b.trim(audio, now)
+	require.Len(t, audio.packets, 3)
+	require.Equal(t, video.packets[0].time, audio.packets[0].time)
+}
+
+func TestParseDuration(t *testing.T) {
+	require.Equal(t, 30*time.Second, ParseDuration("30s"))
+	require.Equal(t, 10*time.Second, ParseDuration(10))
+	require.Equal(t, 1500*time.Millisecond, ParseDuration("1.5"))
+	require.Equal(t, time.Duration(0), ParseDuration(nil))
+
+	stream := NewStream(map[string]any{"url": "rtsp://example.com", "buffer": "1m"})
+	require.Equal(t, time.Minute, stream.bufferDuration)
+	stream.StopBuffer()
+}
+
+func TestBufferDelete(t *testing.T) {
+	prods := make(chan *fakeProducer, 1)
+	HandleFunc("buffer", func(url string) (core.Producer, error) {
+		prod := newFakeProducer(nil)
+		prods <- prod
+		return prod, nil
+	})
+
+	// streams can start the sources
+	select {
+	case <-ready:
+	default:
+		close(ready)
+	}
+
+	stream := NewStream(map[string]any{"url": "buffer:", "buffer": "10s"})
+	streamsMu.Lock()
+	streams["buffer1"] = stream
+	streamsMu.Unlock()
+
+	var prod *fakeProducer
+	select {
+	case prod = <-prods:
+	case <-time.After(time.Second):
+		require.FailNow(t, "buffer didn't start the producer")
+	}
+
+	Delete("buffer1")
+
+	select {
+	case <-prod.done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "producer wasn't stopped")
+	}
+}
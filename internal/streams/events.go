@@ -0,0 +1,50 @@
+package streams
+
+import (
+	"github.com/AlexxIT/go2rtc/internal/events"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// lookupName - stream can have aliases, so use the first name in sort order
+func lookupName(match func(stream *Stream) bool) (name string) {
+	streamsMu.Lock()
+	for key, stream := range streams {
+		if (name == "" || key < name) && match(stream) {
+			name = key
+		}
+	}
+	streamsMu.Unlock()
+	return
+}
+
+func (s *Stream) publish(typ string, cons core.Consumer) {
+	name := lookupName(func(stream *Stream) bool {
+		return stream == s
+	})
+
+	data := map[string]any{}
+	if c, err := marshalConn(cons); err == nil {
+		data["id"] = c.ID
+		data["format_name"] = c.FormatName
+		data["protocol"] = c.Protocol
+		data["remote_addr"] = c.RemoteAddr
+		data["user_agent"] = c.UserAgent
+	}
+
+	events.Publish(typ, name, data)
+}
+
+// publish - can be called under the producer and the stream locks,
+// so the stream is known from the owner, not from the stream producers
+func (p *Producer) publish(typ string, data map[string]any) {
+	name := lookupName(func(stream *Stream) bool {
+		return stream == p.owner
+	})
+
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["url"] = redactURL(p.url)
+
+	events.Publish(typ, name, data)
+}
@@ -0,0 +1,153 @@
+package streams
+
+import (
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// failover - move consumers from failed producer to the next working one.
+// Called when the producer starts reconnecting.
+func (s *Stream) failover(from *Producer) {
+	s.mu.Lock()
+	if _, ok := s.failovers[from]; ok {
+		s.mu.Unlock()
+		return
+	}
+
+	var candidates []*Producer
+	for _, prod := range s.producers {
+		if prod == from {
+			continue
+		}
+		if _, failed := s.failovers[prod]; failed {
+			continue
+		}
+		candidates = append(candidates, prod)
+	}
+	s.mu.Unlock()
+
+	for _, to := range candidates {
+		if !moveTracks(from, to) {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.failovers == nil {
+			s.failovers = map[*Producer]*Producer{}
+		}
+		s.failovers[from] = to
+		s.mu.Unlock()
+
+		log.Info().Msgf("[streams] failover from url=%s to url=%s", from.url, to.url)
+
+		to.start()
+		to.publish("producer_failover", map[string]any{"from": redactURL(from.url)})
+
+		// failed producer may be reconnected while we were moving tracks
+		if from.getHealth() == healthOnline {
+			s.failback(from)
+			return
+		}
+		break
+	}
+
+	// stop candidates that were dialed but not used
+	s.stopProducers()
+}
+
+// failback - move consumers back when the higher priority producer is working again
+func (s *Stream) failback(from *Producer) {
+	s.mu.Lock()
+	to := s.failovers[from]
+	delete(s.failovers, from)
+	fromN, toN := s.indexOf(from), s.indexOf(to)
+	s.mu.Unlock()
+
+	if to == nil || toN < fromN {
+		return
+	}
+
+	if !moveTracks(to, from) {
+		return
+	}
+
+	log.Info().Msgf("[streams] failover back to url=%s", from.url)
+
+	from.publish("producer_failback", map[string]any{"from": redactURL(to.url)})
+
+	s.stopProducers()
+}
+
+func (s *Stream) indexOf(prod *Producer) int {
+	for i, p := range s.producers {
+		if p == prod {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveTracks - move consumers of active tracks of one producer to another
+func moveTracks(from, to *Producer) (moved bool) {
+	if err := to.Dial(); err != nil {
+		return false
+	}
+
+	from.mu.Lock()
+	receivers := append([]*core.Receiver(nil), from.receivers...)
+	from.mu.Unlock()
+
+	for _, receiver := range receivers {
+		if len(receiver.Senders()) == 0 {
+			continue
+		}
+
+		for _, media := range to.GetMedias() {
+			if media.Direction != core.DirectionRecvonly {
+				continue
+			}
+
+			codec := media.MatchCodec(receiver.Codec)
+			if codec == nil {
+				continue
+			}
+
+			track, err := to.GetTrack(media, codec)
+			if err != nil || track == receiver {
+				continue
+			}
+
+			receiver.Replace(track)
+			moved = true
+			break
+		}
+	}
+
+	return
+}
+
+// activeSource - first producer with consumers
+func (s *Stream) activeSource() string {
+	for _, prod := range s.producers {
+		if prod.hasSenders() {
+			return prod.url
+		}
+	}
+	return ""
+}
+
+func (p *Producer) hasSenders() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, track := range p.receivers {
+		if len(track.Senders()) > 0 {
+			return true
+		}
+	}
+	for _, track := range p.senders {
+		if len(track.Senders()) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package streams
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProducer struct {
+	core.Connection
+	done chan struct{}
+	kill chan struct{}
+}
+
+func newFakeProducer(kill chan struct{}) *fakeProducer {
+	return &fakeProducer{
+		Connection: core.Connection{
+			Medias: []*core.Media{
+				{
+					Kind:      core.KindVideo,
+					Direction: core.DirectionRecvonly,
+					Codecs:    []*core.Codec{{Name: core.CodecH264, ClockRate: 90000}},
+				},
+			},
+		},
+		done: make(chan struct{}),
+		kill: kill,
+	}
+}
+
+func (f *fakeProducer) Start() error {
+	select {
+	case <-f.done:
+		return nil
+	case <-f.kill:
+		return errors.New("fake: connection lost")
+	}
+}
+
+func (f *fakeProducer) Stop() error {
+	select {
+	case <-f.done:
+	default:
+		close(f.done)
+	}
+	return nil
+}
+
+func TestFailover(t *testing.T) {
+	var dead atomic.Bool
+	kill := make(chan struct{})
+
+	HandleFunc("fake1", func(url string) (core.Producer, error) {
+		if dead.Load() {
+			return nil, errors.New("fake: dial error")
+		}
+		return newFakeProducer(kill), nil
+	})
+	HandleFunc("fake2", func(url string) (core.Producer, error) {
+		return newFakeProducer(nil), nil
+	})
+
+	stream := NewStream(map[string]any{
+		"url":      []any{"fake1:", "fake2:"},
+		"failover": true,
+	})
+
+	cons := NewBuffer(time.Second)
+	require.Nil(t, stream.AddConsumer(cons))
+	require.Equal(t, "fake1:", stream.activeSource())
+
+	waitSource := func(url string) {
+		require.Eventually(t, func() bool {
+			stream.mu.Lock()
+			defer stream.mu.Unlock()
+			return stream.activeSource() == url
+		}, 5*time.Second, 50*time.Millisecond)
+	}
+
+	// first source lost and can't be dialed
+	dead.Store(true)
+	close(kill)
+	waitSource("fake2:")
+
+	// first source alive again
+	dead.Store(false)
+	waitSource("fake1:")
+
+	stream.RemoveConsumer(cons)
+}
@@ -2,7 +2,9 @@ package streams
 
 import (
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func ParseQuery(s string) url.Values {
@@ -20,3 +22,21 @@ func ParseQuery(s string) url.Values {
 	}
 	return params
 }
+
+// ParseDuration - support duration string ("10s", "1m30s") and number in seconds
+func ParseDuration(v any) time.Duration {
+	switch v := v.(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(f * float64(time.Second))
+		}
+	case int:
+		return time.Duration(v) * time.Second
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	}
+	return 0
+}
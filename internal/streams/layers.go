@@ -0,0 +1,234 @@
+package streams
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/av1"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
+	"github.com/AlexxIT/go2rtc/pkg/vp9"
+)
+
+// LayerMain - default layer, the first source of the stream if not set in the config
+const LayerMain = "main"
+
+// simulcast - producer with several encodings of the same media (ex. WebRTC/WHIP with RIDs)
+type simulcast interface {
+	Layers() []string
+	GetLayer(rid string, codec *core.Codec) *core.Receiver
+}
+
+// parseLayers - layer name => source index from the stream config,
+// value can be the source URL or the source index
+func parseLayers(v any, sources []string) map[string]int {
+	m, ok := v.(map[string]any)
+	if !ok || m == nil {
+		return nil
+	}
+
+	layers := map[string]int{}
+	for name, v := range m {
+		i := -1
+		switch v := v.(type) {
+		case int:
+			i = v
+		case string:
+			i = slices.Index(sources, v)
+		}
+		if i < 0 || i >= len(sources) {
+			log.Warn().Msgf("[streams] unknown source for layer=%s", name)
+			continue
+		}
+		layers[name] = i
+	}
+	return layers
+}
+
+// Layers - qualities of the stream: "main" (the first source by default), layers
+// from the config in the order of sources and RIDs of the simulcast producers
+func (s *Stream) Layers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var layers []string
+	if len(s.producers) > 0 {
+		layers = append(layers, LayerMain)
+	}
+
+	names := make([]string, 0, len(s.layers))
+	for name := range s.layers {
+		if name != LayerMain {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if d := s.layers[a] - s.layers[b]; d != 0 {
+			return d
+		}
+		return strings.Compare(a, b)
+	})
+	layers = append(layers, names...)
+
+	for i, prod := range s.producers {
+		if conn := prod.simulcast(); conn != nil {
+			rids := conn.Layers()
+			if i == 0 && len(rids) > 0 {
+				rids = rids[1:] // default encoding of the first source is the main layer
+			}
+			for _, rid := range rids {
+				if !core.Contains(layers, rid) {
+					layers = append(layers, rid)
+				}
+			}
+		}
+	}
+	return layers
+}
+
+// SwitchLayer - move consumer senders to the layer source. Video senders are moved
+// at the first keyframe of the new source, so the consumer doesn't need renegotiation.
+func (s *Stream) SwitchLayer(senders []*core.Sender, layer string) error {
+	prod, rid := s.layerProducer(layer)
+	if prod == nil {
+		return errors.New("streams: unknown layer: " + layer)
+	}
+
+	if err := prod.Dial(); err != nil {
+		return err
+	}
+
+	var switched bool
+	for _, sender := range senders {
+		codec := sender.ParentCodec()
+		if codec == nil {
+			continue
+		}
+
+		track := prod.layerTrack(rid, codec)
+		if track == nil {
+			continue
+		}
+
+		sender.Switch(track, keyframeFunc(codec))
+		switched = true
+	}
+
+	if !switched {
+		s.stopProducers()
+		return errors.New("streams: can't switch to layer: " + layer)
+	}
+
+	log.Debug().Msgf("[streams] switch layer=%s url=%s", layer, prod.url)
+
+	prod.start()
+
+	// stop unused source after the switch
+	time.AfterFunc(layerSwitchTimeout, s.stopProducers)
+
+	return nil
+}
+
+const layerSwitchTimeout = 10 * time.Second
+
+func (s *Stream) layerProducer(layer string) (*Producer, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i, ok := s.layers[layer]; ok && i < len(s.producers) {
+		return s.producers[i], ""
+	}
+
+	if layer == LayerMain && len(s.producers) > 0 {
+		return s.producers[0], ""
+	}
+
+	for _, prod := range s.producers {
+		if conn := prod.simulcast(); conn != nil && core.Contains(conn.Layers(), layer) {
+			return prod, layer
+		}
+	}
+
+	return nil, ""
+}
+
+// layerTrack - producer track for the codec, RTP and AVCC tracks are not interchangeable
+// because the consumer handler was built for the first one
+func (p *Producer) layerTrack(rid string, codec *core.Codec) *core.Receiver {
+	if rid != "" {
+		if conn := p.simulcast(); conn != nil {
+			return conn.GetLayer(rid, codec)
+		}
+		return nil
+	}
+
+	for _, media := range p.GetMedias() {
+		if media.Direction != core.DirectionRecvonly {
+			continue
+		}
+
+		match := media.MatchCodec(codec)
+		if match == nil || match.IsRTP() != codec.IsRTP() {
+			continue
+		}
+
+		if track, err := p.GetTrack(media, match); err == nil {
+			return track
+		}
+	}
+
+	return nil
+}
+
+func (p *Producer) simulcast() simulcast {
+	p.mu.Lock()
+	conn, _ := p.conn.(simulcast)
+	p.mu.Unlock()
+	return conn
+}
+
+func keyframeFunc(codec *core.Codec) func(packet *core.Packet) bool {
+	switch codec.Name {
+	case core.CodecH264:
+		if codec.IsRTP() {
+			return func(packet *core.Packet) bool {
+				return h264.IsKeyframeRTP(packet.Payload)
+			}
+		}
+		return func(packet *core.Packet) bool {
+			return h264.IsKeyframe(packet.Payload)
+		}
+	case core.CodecH265:
+		if codec.IsRTP() {
+			return func(packet *core.Packet) bool {
+				return h265.IsKeyframeRTP(packet.Payload)
+			}
+		}
+		return func(packet *core.Packet) bool {
+			return h265.IsKeyframe(packet.Payload)
+		}
+	case core.CodecAV1:
+		if codec.IsRTP() {
+			return func(packet *core.Packet) bool {
+				return av1.IsKeyframeRTP(packet.Payload)
+			}
+		}
+		return func(packet *core.Packet) bool {
+			return av1.IsKeyframe(packet.Payload)
+		}
+	case core.CodecVP9:
+		if codec.IsRTP() {
+			return func(packet *core.Packet) bool {
+				return vp9.IsKeyframeRTP(packet.Payload)
+			}
+		}
+		return func(packet *core.Packet) bool {
+			return vp9.IsKeyframe(packet.Payload)
+		}
+	}
+	// audio can be switched at any packet
+	return nil
+}
@@ -0,0 +1,42 @@
+package streams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwitchLayer(t *testing.T) {
+	HandleFunc("layer", func(url string) (core.Producer, error) {
+		return newFakeProducer(nil), nil
+	})
+
+	// second source is not a layer without config
+	stream := NewStream([]string{"layer:main", "layer:sub"})
+	require.Equal(t, []string{LayerMain}, stream.Layers())
+
+	stream = NewStream(map[string]any{
+		"url":    []any{"layer:main", "layer:sub", "layer:audio"},
+		"layers": map[string]any{"sub": "layer:sub", "low": 2, "wrong": "layer:wrong"},
+	})
+	require.Equal(t, []string{LayerMain, "sub", "low"}, stream.Layers())
+
+	cons := NewBuffer(time.Second)
+	require.Nil(t, stream.AddConsumer(cons))
+
+	main, sub := stream.producers[0], stream.producers[1]
+	require.True(t, main.hasSenders())
+
+	require.Nil(t, stream.SwitchLayer(cons.Senders, "sub"))
+	require.NotNil(t, stream.SwitchLayer(cons.Senders, "unknown"))
+
+	// consumer stays on the main source until the keyframe from the sub source
+	sub.receivers[0].Input(&core.Packet{Payload: []byte{0x41, 0x00}})
+	require.True(t, main.hasSenders())
+
+	sub.receivers[0].Input(&core.Packet{Payload: []byte{0x65, 0x00}})
+	require.False(t, main.hasSenders())
+	require.True(t, sub.hasSenders())
+}
@@ -0,0 +1,360 @@
+package streams
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// Limits - max number of consumers and outbound bitrate, zero - unlimited
+type Limits struct {
+	Consumers int            // all consumers
+	PerIP     int            // consumers from one client IP
+	Protocols map[string]int // consumers by format: rtsp, webrtc, mse, hls, mjpeg...
+	Bitrate   int            // bits per second, estimated as input bitrate * consumers
+}
+
+// ErrLimit - servers should answer with 429 (HTTP) or 453 (RTSP)
+var ErrLimit = errors.New("streams: limit exceeded")
+
+// StatusCode - HTTP status for AddConsumer error
+func StatusCode(err error) int {
+	if errors.Is(err, ErrLimit) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+func parseLimits(v any) *Limits {
+	m, ok := v.(map[string]any)
+	if !ok || m == nil {
+		return nil
+	}
+
+	l := &Limits{}
+	l.Consumers, _ = m["consumers"].(int)
+	l.PerIP, _ = m["per_ip"].(int)
+	l.Bitrate = parseBitrate(m["bitrate"])
+
+	if protocols, ok := m["protocols"].(map[string]any); ok {
+		l.Protocols = map[string]int{}
+		for k, v := range protocols {
+			l.Protocols[k], _ = v.(int)
+		}
+	}
+
+	return l
+}
+
+// parseBitrate - support 4000000, "4000K", "4M" (bits per second)
+func parseBitrate(v any) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case string:
+		mul := 1.0
+		switch {
+		case strings.HasSuffix(v, "K"):
+			mul = 1e3
+		case strings.HasSuffix(v, "M"):
+			mul = 1e6
+		case strings.HasSuffix(v, "G"):
+			mul = 1e9
+		}
+		if mul > 1 {
+			v = v[:len(v)-1]
+		}
+		f, _ := strconv.ParseFloat(v, 64)
+		return int(f * mul)
+	}
+	return 0
+}
+
+// global limits for all streams
+var limits *Limits
+
+// limitsMu - serialize check and reserve of the global limits
+var limitsMu sync.Mutex
+
+// reservation - consumer that passed the limits check and is not added yet
+type reservation struct {
+	protocol string
+	ip       string
+}
+
+// usage - consumers of the stream that are counted by limits
+type usage struct {
+	consumers int
+	protocol  int
+	ip        int
+	bitrate   int
+}
+
+func (u *usage) add(v usage) {
+	u.consumers += v.consumers
+	u.protocol += v.protocol
+	u.ip += v.ip
+	u.bitrate += v.bitrate
+}
+
+func (l *Limits) check(u usage, protocol, ip string, bitrate int, scope string) error {
+	if l == nil {
+		return nil
+	}
+	if l.Consumers > 0 && u.consumers >= l.Consumers {
+		return fmt.Errorf("%w: %s consumers=%d", ErrLimit, scope, l.Consumers)
+	}
+	if n := l.Protocols[protocol]; n > 0 && u.protocol >= n {
+		return fmt.Errorf("%w: %s %s consumers=%d", ErrLimit, scope, protocol, n)
+	}
+	if l.PerIP > 0 && ip != "" && u.ip >= l.PerIP {
+		return fmt.Errorf("%w: %s consumers per ip=%d", ErrLimit, scope, l.PerIP)
+	}
+	if l.Bitrate > 0 && bitrate > 0 && u.bitrate+bitrate > l.Bitrate {
+		return fmt.Errorf("%w: %s bitrate=%d", ErrLimit, scope, l.Bitrate)
+	}
+	return nil
+}
+
+// checkLimits - check limits and reserve the slot for the consumer, so parallel
+// requests can't exceed limits. Release should be called after adding the consumer.
+func (s *Stream) checkLimits(cons core.Consumer) (release func(), err error) {
+	if limits == nil && s.limits == nil {
+		return func() {}, nil
+	}
+
+	protocol, ip := clientInfo(cons)
+
+	var total usage
+
+	if limits != nil {
+		limitsMu.Lock()
+		defer limitsMu.Unlock()
+
+		for _, stream := range copyStreams() {
+			stream.mu.Lock()
+			total.add(stream.usage(protocol, ip))
+			stream.mu.Unlock()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = s.limits.check(s.usage(protocol, ip), protocol, ip, s.bitrate, "stream"); err != nil {
+		return nil, err
+	}
+
+	if err = limits.check(total, protocol, ip, s.bitrate, "global"); err != nil {
+		return nil, err
+	}
+
+	r := &reservation{protocol: protocol, ip: ip}
+	s.reserved = append(s.reserved, r)
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if i := slices.Index(s.reserved, r); i >= 0 {
+			s.reserved = slices.Delete(s.reserved, i, i+1)
+		}
+	}, nil
+}
+
+// usage - count consumers and reservations of the stream, except buffer, publish
+// and shared muxer consumers, should be called under the stream lock
+func (s *Stream) usage(protocol, ip string) (u usage) {
+	for _, r := range s.reserved {
+		u.consumers++
+		u.bitrate += s.bitrate
+
+		if r.protocol == protocol {
+			u.protocol++
+		}
+		if ip != "" && r.ip == ip {
+			u.ip++
+		}
+	}
+
+	for _, cons := range s.consumers {
+		if s.internal(cons) {
+			continue
+		}
+
+		u.consumers++
+		u.bitrate += s.bitrate
+
+		p, i := clientInfo(cons)
+		if p == protocol {
+			u.protocol++
+		}
+		if ip != "" && i == ip {
+			u.ip++
+		}
+	}
+
+	return
+}
+
+func (s *Stream) internal(cons core.Consumer) bool {
+	if s.buffer != nil && cons == s.buffer {
+		return true
+	}
+	for _, publish := range s.publishes {
+		if publish == cons {
+			return true
+		}
+	}
+	for _, m := range s.shared {
+		if m.cons == cons {
+			return true
+		}
+	}
+	return slices.Contains(s.sessionConsumers, cons)
+}
+
+// clientInfo - protocol from format name (webrtc/ws => webrtc) and client IP
+func clientInfo(cons core.Consumer) (protocol, ip string) {
+	c, err := marshalConn(cons)
+	if err != nil {
+		return
+	}
+
+	protocol, _, _ = strings.Cut(c.FormatName, "/")
+
+	if c.ClientIP != "" {
+		return protocol, c.ClientIP
+	}
+
+	// remote_addr can be "host:port forwarded ..." or "host:port typ ..."
+	addr, _, _ := strings.Cut(c.RemoteAddr, " ")
+	if ip, _, err = net.SplitHostPort(addr); err != nil {
+		ip = addr
+	}
+
+	return
+}
+
+// copyStreams - unique streams (without aliases), so each can be locked separately
+func copyStreams() []*Stream {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+
+	items := make([]*Stream, 0, len(streams))
+	for _, stream := range streams {
+		if !slices.Contains(items, stream) {
+			items = append(items, stream)
+		}
+	}
+	return items
+}
+
+// sampleBitrates - update input bitrate of all streams
+func sampleBitrates(interval time.Duration) {
+	for now := range time.Tick(interval) {
+		for _, stream := range copyStreams() {
+			stream.sampleBitrate(now)
+		}
+	}
+}
+
+func (s *Stream) sampleBitrate(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bytes int
+	for _, prod := range s.producers {
+		prod.mu.Lock()
+		for _, receiver := range prod.receivers {
+			bytes += receiver.Bytes
+		}
+		prod.mu.Unlock()
+	}
+
+	// receivers can be replaced after reconnect
+	if !s.sampleTime.IsZero() && bytes >= s.sampleBytes {
+		s.bitrate = int(float64(bytes-s.sampleBytes) * 8 / now.Sub(s.sampleTime).Seconds())
+	}
+
+	s.sampleBytes = bytes
+	s.sampleTime = now
+}
+
+func hasBitrateLimits() bool {
+	if limits != nil && limits.Bitrate > 0 {
+		return true
+	}
+	for _, stream := range streams {
+		if stream.limits != nil && stream.limits.Bitrate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Session - viewer of the consumer, which is shared outside of the stream (ex. HLS packager).
+// Session doesn't have tracks, it's counted by limits instead of the shared consumer.
+type Session struct {
+	core.Connection
+}
+
+func NewSession(format string, r *http.Request) *Session {
+	sess := &Session{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: format,
+		},
+	}
+	sess.WithRequest(r)
+	return sess
+}
+
+func (s *Session) AddTrack(*core.Media, *core.Codec, *core.Receiver) error {
+	return errors.New("streams: session can't have tracks")
+}
+
+// AddSession - check limits and add the session to the stream consumers
+func (s *Stream) AddSession(sess *Session) error {
+	release, err := s.checkLimits(sess)
+	if err != nil {
+		log.Debug().Err(err).Msg("[streams] add session")
+		return err
+	}
+
+	s.mu.Lock()
+	s.consumers = append(s.consumers, sess)
+	s.mu.Unlock()
+
+	release()
+
+	s.publish("consumer_add", sess)
+
+	return nil
+}
+
+// AddSessionConsumer - add consumer for the sessions without limits check,
+// the consumer is not counted by limits, its sessions are counted instead
+func (s *Stream) AddSessionConsumer(cons core.Consumer) (err error) {
+	if viewer, ok := cons.(*SharedConsumer); ok {
+		err = s.addSharedConsumer(viewer)
+	} else {
+		err = s.addConsumer(cons)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.sessionConsumers = append(s.sessionConsumers, cons)
+	s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package streams
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBitrate(t *testing.T) {
+	require.Equal(t, 4000000, parseBitrate(4000000))
+	require.Equal(t, 4000000, parseBitrate("4M"))
+	require.Equal(t, 2500000, parseBitrate("2.5M"))
+	require.Equal(t, 500000, parseBitrate("500K"))
+}
+
+func TestLimits(t *testing.T) {
+	stream := NewStream(map[string]any{
+		"url": "fake2:",
+		"limits": map[string]any{
+			"consumers": 3,
+			"per_ip":    1,
+			"protocols": map[string]any{"mse": 1},
+			"bitrate":   "10M",
+		},
+	})
+
+	client := func(addr, format string) *Buffer {
+		cons := NewBuffer(0)
+		cons.RemoteAddr = addr
+		cons.FormatName = format
+		return cons
+	}
+
+	check := func(cons *Buffer) error {
+		release, err := stream.checkLimits(cons)
+		if err == nil {
+			release()
+		}
+		return err
+	}
+
+	stream.consumers = append(stream.consumers, client("10.0.0.1:5000", "mse/fmp4"))
+
+	require.ErrorIs(t, check(client("10.0.0.1:5001", "rtsp")), ErrLimit)
+	require.ErrorIs(t, check(client("10.0.0.2:5000", "mse/fmp4")), ErrLimit)
+	require.Nil(t, check(client("10.0.0.2:5000", "rtsp")))
+
+	// 2 consumers with 4 Mbit/s and the new one
+	stream.consumers = append(stream.consumers, client("10.0.0.2:5000", "rtsp"))
+	stream.bitrate = 4000000
+	require.ErrorIs(t, check(client("10.0.0.3:5000", "rtsp")), ErrLimit)
+
+	stream.bitrate = 3000000
+	require.Nil(t, check(client("10.0.0.3:5000", "rtsp")))
+
+	// buffer is not counted
+	stream.buffer = client("", "buffer")
+	stream.consumers = append(stream.consumers, stream.buffer)
+	require.Nil(t, check(client("10.0.0.3:5000", "rtsp")))
+
+	// reserved slot is counted until the consumer is added
+	release, err := stream.checkLimits(client("10.0.0.3:5000", "rtsp"))
+	require.Nil(t, err)
+	require.ErrorIs(t, check(client("10.0.0.3:5001", "rtsp")), ErrLimit)
+	release()
+	require.Nil(t, check(client("10.0.0.3:5001", "rtsp")))
+}
+
+func TestLimitsSessions(t *testing.T) {
+	stream := NewStream(map[string]any{
+		"url":    "fake2:",
+		"limits": map[string]any{"per_ip": 2},
+	})
+
+	// consumer of the sessions is not counted
+	packager := NewBuffer(0)
+	stream.consumers = append(stream.consumers, packager)
+	stream.sessionConsumers = append(stream.sessionConsumers, packager)
+
+	session := func() *Session {
+		r := httptest.NewRequest("GET", "/api/stream.m3u8", nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		return NewSession("hls/mpegts", r)
+	}
+
+	require.Nil(t, stream.AddSession(session()))
+	require.Nil(t, stream.AddSession(session()))
+	require.ErrorIs(t, stream.AddSession(session()), ErrLimit)
+}
@@ -0,0 +1,278 @@
+package streams
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// apiMetrics - Prometheus text format (version 0.0.4)
+func apiMetrics(w http.ResponseWriter, r *http.Request) {
+	m := newMetrics()
+
+	streamsMu.Lock()
+	names := make([]string, 0, len(streams))
+	items := make(map[string]*Stream, len(streams))
+	for name, stream := range streams {
+		names = append(names, name)
+		items[name] = stream
+	}
+	streamsMu.Unlock()
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		m.appendStream(name, items[name])
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(m.bytes())
+}
+
+type metric struct {
+	name, typ, help string
+	samples         []string
+}
+
+type metrics struct {
+	list   []*metric
+	byName map[string]*metric
+
+	consumers map[[2]string]int // format_name, protocol
+}
+
+func newMetrics() *metrics {
+	m := &metrics{byName: map[string]*metric{}, consumers: map[[2]string]int{}}
+	m.add("go2rtc_stream_producers", "gauge", "Number of stream producers")
+	m.add("go2rtc_stream_consumers", "gauge", "Number of stream consumers")
+	m.add("go2rtc_producer_state", "gauge", "Producer state (none, medias, tracks, start, external, internal)")
+	m.add("go2rtc_producer_reconnects_total", "counter", "Producer reconnect attempts")
+	m.add("go2rtc_producer_bytes_total", "counter", "Bytes received by producer track")
+	m.add("go2rtc_producer_packets_total", "counter", "Packets received by producer track")
+	m.add("go2rtc_consumer_bytes_total", "counter", "Bytes sent by consumer tracks of the stream")
+	m.add("go2rtc_consumer_packets_total", "counter", "Packets sent by consumer tracks of the stream")
+	m.add("go2rtc_consumer_drops_total", "counter", "Packets dropped by consumer tracks of the stream")
+	m.add("go2rtc_consumers", "gauge", "Number of consumers by format and protocol")
+	return m
+}
+
+func (m *metrics) add(name, typ, help string) {
+	v := &metric{name: name, typ: typ, help: help}
+	m.list = append(m.list, v)
+	m.byName[name] = v
+}
+
+// sample - labels is a list of key/value pairs
+func (m *metrics) sample(name string, value int, labels ...string) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(labels) > 0 {
+		sb.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(labels[i] + `="` + labelEscaper.Replace(labels[i+1]) + `"`)
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteString(fmt.Sprintf(" %d", value))
+
+	v := m.byName[name]
+	v.samples = append(v.samples, sb.String())
+}
+
+func (m *metrics) appendStream(name string, stream *Stream) {
+	stream.mu.Lock()
+	producers := stream.producers
+	consumers := stream.consumers
+	stream.mu.Unlock()
+
+	m.sample("go2rtc_stream_producers", len(producers), "stream", name)
+	m.sample("go2rtc_stream_consumers", len(consumers), "stream", name)
+
+	for i, prod := range producers {
+		// index keeps samples unique when redacted URLs are the same
+		index := strconv.Itoa(i)
+
+		prod.mu.Lock()
+		source := redactURL(prod.url)
+		st := prod.state
+		reconnects := prod.reconnects
+		conn := prod.conn
+		prod.mu.Unlock()
+
+		for s := stateNone; s <= stateInternal; s++ {
+			var value int
+			if s == st {
+				value = 1
+			}
+			m.sample("go2rtc_producer_state", value, "stream", name, "source", index, "url", source, "state", s.String())
+		}
+		m.sample("go2rtc_producer_reconnects_total", reconnects, "stream", name, "source", index, "url", source)
+
+		if conn == nil {
+			continue
+		}
+
+		c, err := marshalConn(conn)
+		if err != nil {
+			continue
+		}
+
+		for _, recv := range c.Receivers {
+			labels := []string{"stream", name, "source", index, "url", source, "format_name", c.FormatName, "codec", recv.name()}
+			m.sample("go2rtc_producer_bytes_total", recv.Bytes, labels...)
+			m.sample("go2rtc_producer_packets_total", recv.Packets, labels...)
+		}
+	}
+
+	// sum consumers by format, protocol and codec, so labels don't grow with each viewer,
+	// start from the removed consumers, so counters don't go down when a viewer leaves
+	sums := map[consumerKey]*[3]int{}
+
+	stream.mu.Lock()
+	for k, total := range stream.consumerTotals {
+		sum := *total
+		sums[k] = &sum
+	}
+	stream.mu.Unlock()
+
+	for _, cons := range consumers {
+		c, err := marshalConn(cons)
+		if err != nil {
+			continue
+		}
+
+		m.consumers[[2]string{c.FormatName, c.Protocol}]++
+
+		addConsumerStats(sums, c)
+	}
+
+	keys := make([]consumerKey, 0, len(sums))
+	for k := range sums {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.formatName != b.formatName {
+			return a.formatName < b.formatName
+		}
+		if a.protocol != b.protocol {
+			return a.protocol < b.protocol
+		}
+		return a.codec < b.codec
+	})
+
+	for _, k := range keys {
+		labels := []string{"stream", name, "format_name", k.formatName, "protocol", k.protocol, "codec", k.codec}
+		sum := sums[k]
+		m.sample("go2rtc_consumer_bytes_total", sum[0], labels...)
+		m.sample("go2rtc_consumer_packets_total", sum[1], labels...)
+		m.sample("go2rtc_consumer_drops_total", sum[2], labels...)
+	}
+}
+
+// consumerKey - consumer metrics labels
+type consumerKey struct{ formatName, protocol, codec string }
+
+// addConsumerStats - add bytes, packets and drops of the consumer senders to the sums
+func addConsumerStats(sums map[consumerKey]*[3]int, c *conn) {
+	for _, send := range c.Senders {
+		k := consumerKey{c.FormatName, c.Protocol, send.name()}
+		sum := sums[k]
+		if sum == nil {
+			sum = new([3]int)
+			sums[k] = sum
+		}
+		sum[0] += send.Bytes
+		sum[1] += send.Packets
+		sum[2] += send.Drops
+	}
+}
+
+func (m *metrics) bytes() []byte {
+	keys := make([][2]string, 0, len(m.consumers))
+	for k := range m.consumers {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		m.sample("go2rtc_consumers", m.consumers[k], "format_name", k[0], "protocol", k[1])
+	}
+
+	var b []byte
+	for _, v := range m.list {
+		if v.samples == nil {
+			continue
+		}
+		b = fmt.Appendf(b, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.typ)
+		for _, sample := range v.samples {
+			b = append(b, sample...)
+			b = append(b, '\n')
+		}
+	}
+	return b
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// redactURL - remove credentials from source for labels: userinfo, secret query params
+// and fragment. Only the scheme is left for commands (ex. exec), because secrets may be anywhere.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		if i := strings.IndexByte(s, ':'); i > 0 {
+			return s[:i+1]
+		}
+		return ""
+	}
+
+	if u.Opaque != "" {
+		// ex. ffmpeg:rtsp://... - redact the inner source
+		inner := u.Opaque
+		if u.RawQuery != "" {
+			inner += "?" + u.RawQuery
+		}
+		if strings.Contains(inner, "://") && !strings.Contains(inner, " ") {
+			return u.Scheme + ":" + redactURL(inner)
+		}
+		return u.Scheme + ":"
+	}
+
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for k := range query {
+			if isSecretParam(k) {
+				query[k] = []string{"xxxxx"}
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+var secretParams = []string{"user", "pass", "pwd", "token", "key", "secret", "auth", "sign", "session"}
+
+func isSecretParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range secretParams {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -111,7 +111,7 @@ func (s *Stream) Play(urlOrProd any) error {
 func (s *Stream) AddInternalProducer(conn core.Producer) {
 	producer := &Producer{conn: conn, state: stateInternal, url: "internal"}
 	s.mu.Lock()
-	s.producers = append(s.producers, producer)
+	s.addProducer(producer)
 	s.mu.Unlock()
 }
 
@@ -21,6 +21,24 @@ const (
 	stateInternal
 )
 
+func (s state) String() string {
+	switch s {
+	case stateNone:
+		return "none"
+	case stateMedias:
+		return "medias"
+	case stateTracks:
+		return "tracks"
+	case stateStart:
+		return "start"
+	case stateExternal:
+		return "external"
+	case stateInternal:
+		return "internal"
+	}
+	return "unknown"
+}
+
 type Producer struct {
 	core.Listener
 
@@ -34,6 +52,21 @@ type Producer struct {
 	state    state
 	mu       sync.Mutex
 	workerID int
+
+	reconnects int
+
+	policy   *Reconnect
+	watchdog time.Duration
+
+	// stream with enabled failover
+	stream *Stream
+	// stream of the producer, for events without the stream lock
+	owner *Stream
+
+	health    string
+	lastError string
+	retry     int
+	infoMu    sync.Mutex
 }
 
 const SourceTemplate = "{input}"
@@ -61,9 +94,13 @@ func (p *Producer) Dial() error {
 	if p.state == stateNone {
 		conn, err := GetProducer(p.url)
 		if err != nil {
+			p.setError(err)
+			p.publish("producer_dial_failed", map[string]any{"error": err.Error()})
 			return err
 		}
 
+		p.publish("producer_dial", nil)
+
 		p.conn = conn
 		p.state = stateMedias
 	}
@@ -132,11 +169,59 @@ func (p *Producer) AddTrack(media *core.Media, codec *core.Codec, track *core.Re
 }
 
 func (p *Producer) MarshalJSON() ([]byte, error) {
-	if conn := p.conn; conn != nil {
-		return json.Marshal(conn)
+	var b []byte
+	var err error
+
+	p.mu.Lock()
+	conn := p.conn
+	st := p.state
+	p.mu.Unlock()
+
+	if conn != nil {
+		b, err = json.Marshal(conn)
+	} else {
+		info := map[string]string{"url": p.url}
+		b, err = json.Marshal(info)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Health    string `json:"health"`
+		LastError string `json:"last_error,omitempty"`
+		Retry     int    `json:"retry,omitempty"`
+	}
+
+	p.infoMu.Lock()
+	info.Health = p.health
+	info.LastError = p.lastError
+	info.Retry = p.retry
+	p.infoMu.Unlock()
+
+	if info.Health == "" {
+		if st == stateExternal {
+			info.Health = healthOnline
+		} else {
+			info.Health = healthIdle
+		}
+	}
+
+	extra, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	// append health info to the connection info
+	if n := len(b); n > 2 && b[n-1] == '}' {
+		b = append(b[:n-1], ',')
+		b = append(b, extra[1:]...)
+	} else if n == 2 {
+		b = extra
 	}
-	info := map[string]string{"url": p.url}
-	return json.Marshal(info)
+
+	return b, nil
 }
 
 // internals
@@ -154,13 +239,26 @@ func (p *Producer) start() {
 	p.state = stateStart
 	p.workerID++
 
+	p.setHealth(healthOnline, 0)
+	p.publish("producer_start", nil)
+
 	go p.worker(p.conn, p.workerID)
 }
 
 func (p *Producer) worker(conn core.Producer, workerID int) {
-	if err := conn.Start(); err != nil {
+	done := make(chan struct{})
+	if p.watchdog > 0 {
+		go p.watch(workerID, done)
+	}
+
+	err := conn.Start()
+
+	close(done)
+
+	if err != nil {
 		p.mu.Lock()
-		closed := p.workerID != workerID
+		// producer stopped or connection replaced by watchdog
+		closed := p.workerID != workerID || p.conn != conn
 		p.mu.Unlock()
 
 		if closed {
@@ -168,6 +266,8 @@ func (p *Producer) worker(conn core.Producer, workerID int) {
 		}
 
 		log.Warn().Err(err).Str("url", p.url).Caller().Send()
+
+		p.setError(err)
 	}
 
 	p.reconnect(workerID, 0)
@@ -182,27 +282,49 @@ func (p *Producer) reconnect(workerID, retry int) {
 		return
 	}
 
+	if p.policy != nil && p.policy.MaxAttempts > 0 && retry >= p.policy.MaxAttempts {
+		p.infoMu.Lock()
+		lastError := p.lastError
+		p.infoMu.Unlock()
+
+		log.Warn().Str("url", p.url).Msgf("[streams] give up after retry=%d error=%s", retry, lastError)
+
+		p.setHealth(healthFailed, retry)
+		p.publish("producer_give_up", map[string]any{"retry": retry, "error": lastError})
+
+		go p.giveUp()
+		return
+	}
+
 	log.Debug().Msgf("[streams] retry=%d to url=%s", retry, p.url)
 
+	p.reconnects++
+	p.setHealth(healthReconnecting, retry)
+
+	p.publish("producer_reconnect", map[string]any{"retry": retry})
+
 	conn, err := GetProducer(p.url)
 	if err != nil {
 		log.Debug().Msgf("[streams] producer=%s", err)
 
-		timeout := time.Minute
-		if retry < 5 {
-			timeout = time.Second
-		} else if retry < 10 {
-			timeout = time.Second * 5
-		} else if retry < 20 {
-			timeout = time.Second * 10
+		p.setError(err)
+		p.publish("producer_dial_failed", map[string]any{"error": err.Error(), "retry": retry})
+
+		if retry == 0 && p.stream != nil {
+			go p.stream.failover(p)
 		}
 
+		timeout := p.retryDelay(retry)
+
 		time.AfterFunc(timeout, func() {
 			p.reconnect(workerID, retry+1)
 		})
 		return
 	}
 
+	p.setHealth(healthOnline, 0)
+	p.publish("producer_dial", map[string]any{"retry": retry})
+
 	for _, media := range conn.GetMedias() {
 		switch media.Direction {
 		case core.DirectionRecvonly:
@@ -240,6 +362,10 @@ func (p *Producer) reconnect(workerID, retry int) {
 	p.conn = conn
 
 	go p.worker(conn, workerID)
+
+	if p.stream != nil {
+		go p.stream.failback(p)
+	}
 }
 
 func (p *Producer) stop() {
@@ -259,6 +385,14 @@ func (p *Producer) stop() {
 
 	log.Debug().Msgf("[streams] stop producer url=%s", p.url)
 
+	p.infoMu.Lock()
+	if p.health != healthFailed {
+		p.health = healthIdle
+	}
+	p.infoMu.Unlock()
+
+	p.publish("producer_stop", nil)
+
 	if p.conn != nil {
 		_ = p.conn.Stop()
 		p.conn = nil
@@ -1,29 +1,84 @@
 package streams
 
-import "time"
+import (
+	"errors"
+	"time"
 
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// Publish - push stream to destination, restarts on errors until StopPublish
 func (s *Stream) Publish(url string) error {
+	s.mu.Lock()
+	_, ok := s.publishes[url]
+	s.mu.Unlock()
+
+	if ok {
+		return nil // already publishing
+	}
+
+	return s.startPublish(url)
+}
+
+func (s *Stream) startPublish(url string) error {
 	cons, run, err := GetConsumer(url)
 	if err != nil {
 		return err
 	}
 
-	if err = s.AddConsumer(cons); err != nil {
+	if err = s.addConsumer(cons); err != nil {
 		return err
 	}
 
+	s.mu.Lock()
+	if s.publishes == nil {
+		s.publishes = map[string]core.Consumer{}
+	}
+	s.publishes[url] = cons
+	s.mu.Unlock()
+
 	go func() {
 		run()
 		s.RemoveConsumer(cons)
 
 		// TODO: more smart retry
 		time.Sleep(5 * time.Second)
-		_ = s.Publish(url)
+
+		s.mu.Lock()
+		active := s.publishes[url] == cons
+		s.mu.Unlock()
+
+		if !active {
+			return // stopped with StopPublish
+		}
+
+		if err := s.startPublish(url); err != nil {
+			s.mu.Lock()
+			if s.publishes[url] == cons {
+				delete(s.publishes, url)
+			}
+			s.mu.Unlock()
+		}
 	}()
 
 	return nil
 }
 
+func (s *Stream) StopPublish(url string) error {
+	s.mu.Lock()
+	cons, ok := s.publishes[url]
+	delete(s.publishes, url)
+	s.mu.Unlock()
+
+	if !ok {
+		return errors.New("streams: publish not found: " + url)
+	}
+
+	s.RemoveConsumer(cons)
+
+	return nil
+}
+
 func Publish(stream *Stream, destination any) {
 	switch v := destination.(type) {
 	case string:
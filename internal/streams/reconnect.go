@@ -0,0 +1,198 @@
+package streams
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// Reconnect - producer reconnect policy, can be configured for each stream
+type Reconnect struct {
+	Delay       time.Duration // first retry delay
+	MaxDelay    time.Duration // delay doubles on each retry up to this value
+	Jitter      float64       // random part of the delay (0.2 - up to +20%)
+	MaxAttempts int           // 0 - unlimited
+	GiveUp      string        // close (default) - close consumers, stop - stop producer only
+}
+
+const (
+	giveUpClose = "close"
+	giveUpStop  = "stop"
+)
+
+func parseReconnect(v any) *Reconnect {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	r := &Reconnect{
+		Delay:    time.Second,
+		MaxDelay: time.Minute,
+		GiveUp:   giveUpClose,
+	}
+
+	if d := parseDuration(m["delay"]); d > 0 {
+		r.Delay = d
+	}
+	if d := parseDuration(m["max_delay"]); d > 0 {
+		r.MaxDelay = d
+	}
+
+	switch v := m["jitter"].(type) {
+	case float64:
+		r.Jitter = v
+	case int:
+		r.Jitter = float64(v)
+	}
+
+	if i, ok := m["max_attempts"].(int); ok {
+		r.MaxAttempts = i
+	}
+
+	if s, ok := m["give_up"].(string); ok && s == giveUpStop {
+		r.GiveUp = giveUpStop
+	}
+
+	return r
+}
+
+// retryDelay - without policy uses default ladder: 1s, 5s, 10s, 1m
+func (p *Producer) retryDelay(retry int) time.Duration {
+	r := p.policy
+	if r == nil {
+		switch {
+		case retry < 5:
+			return time.Second
+		case retry < 10:
+			return time.Second * 5
+		case retry < 20:
+			return time.Second * 10
+		}
+		return time.Minute
+	}
+
+	d := r.Delay
+	for i := 0; i < retry && d < r.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+
+	if r.Jitter > 0 {
+		d += time.Duration(rand.Float64() * r.Jitter * float64(d))
+	}
+
+	return d
+}
+
+// giveUp - stop producer after max attempts, should be called without lock
+func (p *Producer) giveUp() {
+	stream := p.owner
+
+	p.stop()
+
+	if p.policy.GiveUp != giveUpClose || stream == nil {
+		return
+	}
+
+	stream.mu.Lock()
+	_, failover := stream.failovers[p]
+	stream.mu.Unlock()
+
+	// consumers already moved to another producer
+	if failover {
+		return
+	}
+
+	stream.mu.Lock()
+	consumers := append([]core.Consumer(nil), stream.consumers...)
+	stream.mu.Unlock()
+
+	for _, cons := range consumers {
+		stream.RemoveConsumer(cons)
+	}
+}
+
+// watch - force reconnect if producer tracks don't receive packets
+func (p *Producer) watch(workerID int, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var packets int
+	last := time.Now()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+			n := 0
+			for _, receiver := range p.receivers {
+				n += receiver.Packets
+			}
+			empty := len(p.receivers) == 0 // backchannel only
+			p.mu.Unlock()
+
+			if n != packets || empty {
+				packets = n
+				last = now
+				continue
+			}
+
+			if now.Sub(last) < p.watchdog {
+				continue
+			}
+
+			err := fmt.Errorf("streams: no data for %s", p.watchdog)
+			log.Warn().Err(err).Str("url", p.url).Send()
+			p.setError(err)
+
+			p.mu.Lock()
+			if p.workerID != workerID {
+				p.mu.Unlock()
+				return // producer stopped
+			}
+			// new reconnect chain, so the worker of the stalled connection
+			// will not start another one when its Start returns an error
+			p.workerID++
+			workerID = p.workerID
+			p.mu.Unlock()
+
+			// don't stop the connection here, because it will close the tracks,
+			// old connection will be stopped after moving tracks to the new one
+			p.reconnect(workerID, 0)
+			return
+		}
+	}
+}
+
+const (
+	healthIdle         = "idle"
+	healthOnline       = "online"
+	healthReconnecting = "reconnecting"
+	healthFailed       = "failed"
+)
+
+func (p *Producer) setHealth(health string, retry int) {
+	p.infoMu.Lock()
+	p.health = health
+	p.retry = retry
+	p.infoMu.Unlock()
+}
+
+func (p *Producer) setError(err error) {
+	p.infoMu.Lock()
+	p.lastError = err.Error()
+	p.infoMu.Unlock()
+}
+
+func (p *Producer) getHealth() string {
+	p.infoMu.Lock()
+	defer p.infoMu.Unlock()
+	return p.health
+}
@@ -0,0 +1,45 @@
+package streams
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDelay(t *testing.T) {
+	prod := &Producer{}
+	require.Equal(t, time.Second, prod.retryDelay(0))
+	require.Equal(t, 5*time.Second, prod.retryDelay(5))
+	require.Equal(t, time.Minute, prod.retryDelay(20))
+
+	prod.policy = parseReconnect(map[string]any{"delay": "2s", "max_delay": 10, "max_attempts": 5})
+	require.Equal(t, 2*time.Second, prod.retryDelay(0))
+	require.Equal(t, 8*time.Second, prod.retryDelay(2))
+	require.Equal(t, 10*time.Second, prod.retryDelay(3))
+	require.Equal(t, 5, prod.policy.MaxAttempts)
+	require.Equal(t, giveUpClose, prod.policy.GiveUp)
+
+	prod.policy.Jitter = 0.5
+	d := prod.retryDelay(0)
+	require.True(t, d >= 2*time.Second && d <= 3*time.Second)
+}
+
+func TestProducerHealth(t *testing.T) {
+	stream := NewStream(map[string]any{
+		"url":       "rtsp://example.com",
+		"reconnect": map[string]any{"give_up": "stop"},
+		"watchdog":  "5s",
+	})
+	prod := stream.producers[0]
+	require.Equal(t, giveUpStop, prod.policy.GiveUp)
+	require.Equal(t, 5*time.Second, prod.watchdog)
+
+	prod.setHealth(healthReconnecting, 2)
+	prod.lastError = "dial tcp: i/o timeout"
+
+	b, err := json.Marshal(prod)
+	require.Nil(t, err)
+	require.Equal(t, `{"url":"rtsp://example.com","health":"reconnecting","last_error":"dial tcp: i/o timeout","retry":2}`, string(b))
+}
@@ -0,0 +1,310 @@
+package streams
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+const (
+	sharedQueueSize = 1024     // fragments for the slow viewer
+	sharedGOPSize   = 16 << 20 // max cached bytes from the last keyframe
+)
+
+// splitter - muxer consumer (mp4, mpegts), which can split own output to
+// the init segment and the media fragments
+type splitter interface {
+	Split(b []byte, yield func(b []byte, init, keyframe bool))
+}
+
+// SharedConsumer - viewer of the shared muxer. One muxer (consumer) of the stream
+// for all viewers with the same key (format and codecs), viewers only write bytes.
+// New viewers start from the cached init segment and the last keyframe.
+type SharedConsumer struct {
+	core.Connection
+	key     string
+	newCons func() core.Consumer
+
+	muxer *sharedMuxer
+	queue chan []byte
+	skip  bool // skip fragments until the next keyframe
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewSharedConsumer - newCons should return consumer with io.WriterTo and Split
+// methods, it's called only for the first viewer with the key
+func NewSharedConsumer(key string, newCons func() core.Consumer) *SharedConsumer {
+	return &SharedConsumer{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: "shared",
+		},
+		key:     key,
+		newCons: newCons,
+		queue:   make(chan []byte, sharedQueueSize),
+		done:    make(chan struct{}),
+	}
+}
+
+// SharedKey - key for the shared muxer from the format and the consumer medias
+func SharedKey(format string, medias []*core.Media) string {
+	sb := strings.Builder{}
+	sb.WriteString(format)
+	for _, media := range medias {
+		sb.WriteByte('|')
+		sb.WriteString(media.String())
+	}
+	return sb.String()
+}
+
+func (c *SharedConsumer) AddTrack(*core.Media, *core.Codec, *core.Receiver) error {
+	return errors.New("streams: shared consumer can't have tracks")
+}
+
+// Codecs - codecs of the shared muxer
+func (c *SharedConsumer) Codecs() []*core.Codec {
+	if c.muxer == nil {
+		return nil
+	}
+	return c.muxer.cons.(interface{ Codecs() []*core.Codec }).Codecs()
+}
+
+func (c *SharedConsumer) WriteTo(wr io.Writer) (n int64, err error) {
+	for {
+		select {
+		case b := <-c.queue:
+			var i int
+			if i, err = wr.Write(b); err != nil {
+				return
+			}
+			n += int64(i)
+			c.Send += i
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *SharedConsumer) Stop() error {
+	c.close()
+	if c.muxer != nil {
+		c.muxer.remove(c)
+	}
+	return nil
+}
+
+func (c *SharedConsumer) close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// push - called under muxer lock, drops fragments until the next keyframe for the slow viewer
+func (c *SharedConsumer) push(b []byte, keyframe bool) {
+	if c.skip {
+		if !keyframe {
+			return
+		}
+		c.skip = false
+	}
+
+	select {
+	case c.queue <- b:
+	default:
+		c.skip = true
+	}
+}
+
+type sharedMuxer struct {
+	stream *Stream
+	key    string
+	cons   core.Consumer
+	split  func(b []byte, yield func(b []byte, init, keyframe bool))
+	video  bool
+
+	init    []byte
+	gop     [][]byte // fragments from the last keyframe
+	gopSize int
+
+	viewers []*SharedConsumer
+	closed  bool
+	mu      sync.Mutex
+}
+
+func (m *sharedMuxer) Write(b []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	m.split(b, func(b []byte, init, keyframe bool) {
+		b = bytes.Clone(b) // muxer may reuse the buffer
+
+		// for streams without video any fragment is a start point
+		start := init || keyframe || !m.video
+
+		switch {
+		case init:
+			m.init = b
+			m.gop = nil
+		case start:
+			m.gop = [][]byte{b}
+			m.gopSize = len(b)
+		case m.gop != nil:
+			if m.gopSize += len(b); m.gopSize <= sharedGOPSize {
+				m.gop = append(m.gop, b)
+			} else {
+				m.gop = nil
+			}
+		}
+
+		for _, viewer := range m.viewers {
+			viewer.push(b, start)
+		}
+	})
+
+	return len(b), nil
+}
+
+// add - new viewer starts from the init and the last keyframe, false if muxer closed
+func (m *sharedMuxer) add(viewer *SharedConsumer) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false
+	}
+
+	viewer.muxer = m
+
+	if m.init != nil {
+		viewer.push(m.init, true)
+		if m.gop != nil {
+			for _, b := range m.gop {
+				viewer.push(b, false)
+			}
+		} else {
+			viewer.skip = true
+		}
+	}
+
+	m.viewers = append(m.viewers, viewer)
+
+	return true
+}
+
+func (m *sharedMuxer) remove(viewer *SharedConsumer) {
+	m.mu.Lock()
+	for i, v := range m.viewers {
+		if v == viewer {
+			m.viewers = append(m.viewers[:i], m.viewers[i+1:]...)
+			break
+		}
+	}
+	last := len(m.viewers) == 0 && !m.closed
+	m.mu.Unlock()
+
+	if last {
+		m.close()
+	}
+}
+
+func (m *sharedMuxer) close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	viewers := m.viewers
+	m.viewers = nil
+	m.mu.Unlock()
+
+	m.stream.mu.Lock()
+	if m.stream.shared[m.key] == m {
+		delete(m.stream.shared, m.key)
+	}
+	m.stream.mu.Unlock()
+
+	for _, viewer := range viewers {
+		viewer.close()
+	}
+
+	m.stream.RemoveConsumer(m.cons)
+}
+
+func (s *Stream) addSharedConsumer(viewer *SharedConsumer) error {
+	for {
+		s.mu.Lock()
+		m := s.shared[viewer.key]
+		s.mu.Unlock()
+
+		if m == nil {
+			var err error
+			if m, err = s.newSharedMuxer(viewer); err != nil {
+				return err
+			}
+		}
+
+		// muxer may be closed by the last viewer just now
+		if m.add(viewer) {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.consumers = append(s.consumers, viewer)
+	s.mu.Unlock()
+
+	s.publish("consumer_add", viewer)
+
+	return nil
+}
+
+func (s *Stream) newSharedMuxer(viewer *SharedConsumer) (*sharedMuxer, error) {
+	cons := viewer.newCons()
+
+	split, ok := cons.(splitter)
+	if !ok {
+		return nil, errors.New("streams: unsupported shared consumer")
+	}
+
+	// stream may dial the source for some time, so without lock
+	if err := s.addConsumer(cons); err != nil {
+		return nil, err
+	}
+
+	m := &sharedMuxer{stream: s, key: viewer.key, cons: cons, split: split.Split}
+
+	for _, codec := range cons.(interface{ Codecs() []*core.Codec }).Codecs() {
+		if codec.IsVideo() {
+			m.video = true
+		}
+	}
+
+	s.mu.Lock()
+	if existing := s.shared[viewer.key]; existing != nil {
+		s.mu.Unlock()
+		s.RemoveConsumer(cons) // another viewer was faster
+		return existing, nil
+	}
+	if s.shared == nil {
+		s.shared = map[string]*sharedMuxer{}
+	}
+	s.shared[viewer.key] = m
+	s.mu.Unlock()
+
+	go func() {
+		_, _ = cons.(io.WriterTo).WriteTo(m)
+		m.close()
+	}()
+
+	return m, nil
+}
@@ -0,0 +1,55 @@
+package streams
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedMuxer(t *testing.T) {
+	// fragment type in the first byte: i - init, k - keyframe, p - other
+	m := &sharedMuxer{
+		video: true,
+		split: func(b []byte, yield func(b []byte, init, keyframe bool)) {
+			yield(b, b[0] == 'i', b[0] == 'k')
+		},
+	}
+
+	write := func(s ...string) {
+		for _, b := range s {
+			_, err := m.Write([]byte(b))
+			require.Nil(t, err)
+		}
+	}
+
+	read := func(c *SharedConsumer) (s []string) {
+		for len(c.queue) > 0 {
+			s = append(s, string(<-c.queue))
+		}
+		return
+	}
+
+	viewer1 := NewSharedConsumer("test", nil)
+	require.True(t, m.add(viewer1))
+
+	write("i", "k1", "p1", "p2")
+	require.Equal(t, []string{"i", "k1", "p1", "p2"}, read(viewer1))
+
+	// late joiner starts from the init and the last keyframe
+	write("k2", "p3")
+	viewer2 := NewSharedConsumer("test", nil)
+	require.True(t, m.add(viewer2))
+	write("p4")
+
+	require.Equal(t, []string{"k2", "p3", "p4"}, read(viewer1))
+	require.Equal(t, []string{"i", "k2", "p3", "p4"}, read(viewer2))
+
+	// slow viewer skips fragments until the next keyframe
+	for i := 0; i <= sharedQueueSize; i++ {
+		write("p")
+	}
+	read(viewer1)
+	write("p5", "k3", "p6")
+
+	require.Equal(t, []string{"k3", "p6"}, read(viewer1))
+}
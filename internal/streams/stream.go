@@ -2,8 +2,11 @@ package streams
 
 import (
 	"encoding/json"
+	"errors"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/AlexxIT/go2rtc/pkg/core"
 )
@@ -13,18 +16,48 @@ type Stream struct {
 	consumers []core.Consumer
 	mu        sync.Mutex
 	pending   atomic.Int32
+
+	buffer         *Buffer
+	bufferDuration time.Duration
+	bufferStop     chan struct{}
+
+	publishes map[string]core.Consumer
+
+	// shared muxers by key (format and codecs)
+	shared map[string]*sharedMuxer
+
+	// failed producer => working producer
+	failovers  map[*Producer]*Producer
+	failoverOn bool
+
+	// layer name => source index
+	layers map[string]int
+
+	limits   *Limits
+	reserved []*reservation
+
+	// consumers for sessions (ex. HLS packager), not counted by limits
+	sessionConsumers []core.Consumer
+
+	// sent stats of the removed consumers for metrics counters
+	consumerTotals map[consumerKey]*[3]int
+
+	// input bitrate for limits
+	bitrate     int
+	sampleBytes int
+	sampleTime  time.Time
 }
 
 func NewStream(source any) *Stream {
 	switch source := source.(type) {
 	case string:
-		return &Stream{
-			producers: []*Producer{NewProducer(source)},
-		}
+		s := new(Stream)
+		s.addProducer(NewProducer(source))
+		return s
 	case []string:
 		s := new(Stream)
 		for _, str := range source {
-			s.producers = append(s.producers, NewProducer(str))
+			s.addProducer(NewProducer(str))
 		}
 		return s
 	case []any:
@@ -35,11 +68,27 @@ func NewStream(source any) *Stream {
 				log.Error().Msgf("[stream] NewStream: Expected string, got %v", src)
 				continue
 			}
-			s.producers = append(s.producers, NewProducer(str))
+			s.addProducer(NewProducer(str))
 		}
 		return s
 	case map[string]any:
-		return NewStream(source["url"])
+		s := NewStream(source["url"])
+		policy := parseReconnect(source["reconnect"])
+		watchdog := ParseDuration(source["watchdog"])
+		s.failoverOn, _ = source["failover"].(bool)
+		s.limits = parseLimits(source["limits"])
+		s.layers = parseLayers(source["layers"], s.Sources())
+		for _, prod := range s.producers {
+			prod.policy = policy
+			prod.watchdog = watchdog
+			if s.failoverOn {
+				prod.stream = s
+			}
+		}
+
+		s.StartBuffer(ParseDuration(source["buffer"]))
+
+		return s
 	case nil:
 		return new(Stream)
 	default:
@@ -55,6 +104,12 @@ func (s *Stream) Sources() []string {
 	return sources
 }
 
+func (s *Stream) ConsumersCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.consumers)
+}
+
 func (s *Stream) SetSource(source string) {
 	for _, prod := range s.producers {
 		prod.SetSource(source)
@@ -64,25 +119,150 @@ func (s *Stream) SetSource(source string) {
 func (s *Stream) RemoveConsumer(cons core.Consumer) {
 	_ = cons.Stop()
 
+	// final stats before removing, so metrics don't see a gap
+	c, _ := marshalConn(cons)
+
 	s.mu.Lock()
 	for i, consumer := range s.consumers {
 		if consumer == cons {
 			s.consumers = append(s.consumers[:i], s.consumers[i+1:]...)
+			if c != nil {
+				if s.consumerTotals == nil {
+					s.consumerTotals = map[consumerKey]*[3]int{}
+				}
+				addConsumerStats(s.consumerTotals, c)
+			}
 			break
 		}
 	}
+	if i := slices.Index(s.sessionConsumers, cons); i >= 0 {
+		s.sessionConsumers = slices.Delete(s.sessionConsumers, i, i+1)
+	}
+	buffer := s.buffer
 	s.mu.Unlock()
 
+	if buffer != nil {
+		buffer.Detach()
+	}
+
+	s.publish("consumer_remove", cons)
+
 	s.stopProducers()
 }
 
+// AddBufferedConsumer - add consumer to the stream buffer. Consumer will get
+// packets from the past (starting from keyframe) and then live packets.
+func (s *Stream) AddBufferedConsumer(cons core.Consumer, before time.Duration) error {
+	release, err := s.checkLimits(cons)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	s.mu.Lock()
+	buffer := s.buffer
+	s.mu.Unlock()
+
+	if buffer == nil {
+		return errors.New("streams: buffer not enabled")
+	}
+
+	if err := buffer.Attach(cons, before); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.consumers = append(s.consumers, cons)
+	s.mu.Unlock()
+
+	s.publish("consumer_add", cons)
+
+	return nil
+}
+
+// StartBuffer - keep stream producers running and store last packets in memory.
+// Buffer starts after all source handlers are registered.
+func (s *Stream) StartBuffer(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if duration <= 0 || s.bufferDuration > 0 {
+		return
+	}
+
+	s.bufferDuration = duration
+	s.bufferStop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		select {
+		case <-ready:
+			s.startBuffer(duration, stop)
+		case <-stop:
+		}
+	}(s.bufferStop)
+}
+
+func (s *Stream) startBuffer(duration time.Duration, stop chan struct{}) {
+	buffer := NewBuffer(duration)
+
+	for {
+		if err := s.addConsumer(buffer); err != nil {
+			log.Warn().Err(err).Caller().Send()
+			select {
+			case <-time.After(10 * time.Second):
+				continue
+			case <-stop:
+				return
+			}
+		}
+		break
+	}
+
+	s.mu.Lock()
+	select {
+	case <-stop:
+		s.mu.Unlock()
+		s.RemoveConsumer(buffer) // stopped while the producers were starting
+		return
+	default:
+		s.buffer = buffer
+	}
+	s.mu.Unlock()
+}
+
+// StopBuffer - stop pending buffer start, remove the buffer consumer and stop
+// the producers without other consumers. Used when the stream is deleted or replaced.
+func (s *Stream) StopBuffer() {
+	s.mu.Lock()
+	if s.bufferStop == nil {
+		s.mu.Unlock()
+		return
+	}
+	close(s.bufferStop)
+	buffer := s.buffer
+	s.buffer = nil
+	s.bufferStop = nil
+	s.bufferDuration = 0
+	s.mu.Unlock()
+
+	if buffer != nil {
+		s.RemoveConsumer(buffer)
+	}
+}
+
 func (s *Stream) AddProducer(prod core.Producer) {
 	producer := &Producer{conn: prod, state: stateExternal, url: "external"}
 	s.mu.Lock()
-	s.producers = append(s.producers, producer)
+	s.addProducer(producer)
 	s.mu.Unlock()
 }
 
+// addProducer - should be called under the lock or before the stream is shared
+func (s *Stream) addProducer(prod *Producer) {
+	prod.owner = s
+	s.producers = append(s.producers, prod)
+}
+
 func (s *Stream) RemoveProducer(prod core.Producer) {
 	s.mu.Lock()
 	for i, producer := range s.producers {
@@ -101,18 +281,15 @@ func (s *Stream) stopProducers() {
 	}
 
 	s.mu.Lock()
-producers:
 	for _, producer := range s.producers {
-		for _, track := range producer.receivers {
-			if len(track.Senders()) > 0 {
-				continue producers
-			}
+		if producer.hasSenders() {
+			continue
 		}
-		for _, track := range producer.senders {
-			if len(track.Senders()) > 0 {
-				continue producers
-			}
+		// keep failed producer reconnecting while its replacement is in use
+		if to := s.failovers[producer]; to != nil && to.hasSenders() {
+			continue
 		}
+		delete(s.failovers, producer)
 		producer.stop()
 	}
 	s.mu.Unlock()
@@ -122,9 +299,18 @@ func (s *Stream) MarshalJSON() ([]byte, error) {
 	var info = struct {
 		Producers []*Producer     `json:"producers"`
 		Consumers []core.Consumer `json:"consumers"`
+		Active    string          `json:"active,omitempty"`
 	}{
 		Producers: s.producers,
 		Consumers: s.consumers,
 	}
+	if s.failoverOn {
+		info.Active = s.activeSource()
+	}
 	return json.Marshal(info)
 }
+
+// parseDuration - duration from the stream config
+func parseDuration(v any) time.Duration {
+	return ParseDuration(v)
+}
@@ -16,6 +16,7 @@ func Init() {
 	var cfg struct {
 		Streams map[string]any `yaml:"streams"`
 		Publish map[string]any `yaml:"publish"`
+		Limits  map[string]any `yaml:"limits"`
 	}
 
 	app.LoadConfig(&cfg)
@@ -26,8 +27,20 @@ func Init() {
 		streams[name] = NewStream(item)
 	}
 
+	limits = parseLimits(cfg.Limits)
+
+	if hasBitrateLimits() {
+		go sampleBitrates(5 * time.Second)
+	}
+
 	api.HandleFunc("api/streams", apiStreams)
 	api.HandleFunc("api/streams.dot", apiStreamsDOT)
+	api.HandleFunc("api/metrics", apiMetrics)
+
+	// all source handlers are registered after Init of other modules
+	time.AfterFunc(time.Second, func() {
+		close(ready)
+	})
 
 	if cfg.Publish == nil {
 		return
@@ -62,9 +75,15 @@ func New(name string, sources ...string) *Stream {
 	stream := NewStream(sources)
 
 	streamsMu.Lock()
+	old := streams[name]
 	streams[name] = stream
+	old = unused(old)
 	streamsMu.Unlock()
 
+	if old != nil {
+		old.StopBuffer()
+	}
+
 	return stream
 }
 
@@ -138,6 +157,9 @@ func GetOrPatch(query url.Values) *Stream {
 
 var log zerolog.Logger
 
+// ready - closed when streams can start the sources
+var ready = make(chan struct{})
+
 // streams map
 
 var streams = map[string]*Stream{}
@@ -151,8 +173,24 @@ func Get(name string) *Stream {
 
 func Delete(name string) {
 	streamsMu.Lock()
-	defer streamsMu.Unlock()
+	stream := streams[name]
 	delete(streams, name)
+	stream = unused(stream)
+	streamsMu.Unlock()
+
+	if stream != nil {
+		stream.StopBuffer()
+	}
+}
+
+// unused - stream if it doesn't have other names (aliases), should be called under the lock
+func unused(stream *Stream) *Stream {
+	for _, s := range streams {
+		if s == stream {
+			return nil
+		}
+	}
+	return stream
 }
 
 func GetAllNames() []string {
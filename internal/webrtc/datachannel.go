@@ -0,0 +1,165 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api/ws"
+	"github.com/AlexxIT/go2rtc/internal/events"
+	"github.com/AlexxIT/go2rtc/internal/onvif"
+	pion "github.com/pion/webrtc/v4"
+)
+
+// DataChannelLabel - viewer data channel for JSON events and control messages,
+// messages have the same format as WebSocket API: {"type":"...","value":...}
+const DataChannelLabel = "go2rtc"
+
+const timestampInterval = time.Second
+
+func (v *viewer) bindChannel(dc *pion.DataChannel) {
+	if dc.Label() != DataChannelLabel {
+		return
+	}
+
+	ch := make(chan *ws.Message, 100)
+
+	v.mu.Lock()
+	v.channel = ch
+	v.mu.Unlock()
+
+	dc.OnOpen(func() {
+		unsubscribe := events.Subscribe(func(event *events.Event) {
+			if v.ownEvent(event) {
+				v.send(&ws.Message{Type: "event", Value: event})
+			}
+		})
+
+		// RTP timestamp to the server time mapping for the frame-accurate overlays
+		var last atomic.Int64
+		v.conn.OnVideoFrame(func(ts uint32) {
+			now := time.Now()
+			if now.UnixNano()-last.Load() < int64(timestampInterval) {
+				return
+			}
+			last.Store(now.UnixNano())
+			v.send(&ws.Message{Type: "timestamp", Value: map[string]any{
+				"rtp": ts, "time": now.UnixMilli(),
+			}})
+		})
+
+		v.sendState()
+
+		go func() {
+			defer unsubscribe()
+			defer v.conn.OnVideoFrame(nil)
+
+			for {
+				select {
+				case msg := <-ch:
+					b, _ := json.Marshal(msg)
+					if err := dc.SendText(string(b)); err != nil {
+						return
+					}
+				case <-v.done:
+					return
+				}
+			}
+		}()
+	})
+
+	dc.OnMessage(func(msg pion.DataChannelMessage) {
+		if err := v.handleMessage(msg.Data); err != nil {
+			log.Debug().Err(err).Str("session", v.conn.SessionID).Msg("[webrtc] data channel")
+			v.send(&ws.Message{Type: "error", Value: err.Error()})
+		}
+	})
+}
+
+func (v *viewer) handleMessage(b []byte) error {
+	msg := new(ws.Message)
+	if err := json.Unmarshal(b, msg); err != nil {
+		return err
+	}
+
+	switch msg.Type {
+	case "state":
+	case "pause":
+		pauseConn(v.conn)
+	case "resume":
+		resumeConn(v.conn)
+	case "layer":
+		// state is sent after the switch
+		return SetLayer(v.conn, msg.String())
+	case "talkback":
+		if !v.control {
+			return errors.New("webrtc: talkback not allowed")
+		}
+		var enabled bool
+		if err := msg.Unmarshal(&enabled); err != nil {
+			return err
+		}
+		v.conn.SetTalkback(enabled)
+	case "ptz":
+		if !v.control {
+			return errors.New("webrtc: PTZ not allowed")
+		}
+		var cmd onvif.PTZ
+		if err := msg.Unmarshal(&cmd); err != nil {
+			return err
+		}
+		if err := onvif.SendPTZ(v.stream.Sources(), &cmd); err != nil {
+			return err
+		}
+	default:
+		return errors.New("webrtc: unknown message: " + msg.Type)
+	}
+
+	v.sendState()
+	return nil
+}
+
+// ownEvent - events of the viewer stream, except the events of other sessions
+func (v *viewer) ownEvent(event *events.Event) bool {
+	if event.Stream != v.conn.StreamSource {
+		return false
+	}
+	if session, ok := event.Data["session"]; ok && session != v.conn.SessionID {
+		return false
+	}
+	return true
+}
+
+func (v *viewer) sendState() {
+	v.mu.Lock()
+	auto := v.auto
+	v.mu.Unlock()
+
+	v.send(&ws.Message{Type: "state", Value: map[string]any{
+		"stream":    v.conn.StreamSource,
+		"session":   v.conn.SessionID,
+		"paused":    v.conn.IsPaused(),
+		"layer":     v.conn.GetRingType(),
+		"layers":    v.stream.Layers(),
+		"auto":      auto,
+		"talkback":  v.conn.Talkback(),
+		"bandwidth": v.conn.Bandwidth(),
+	}})
+}
+
+// send - skip messages for the slow client or without data channel
+func (v *viewer) send(msg *ws.Message) {
+	v.mu.Lock()
+	ch := v.channel
+	v.mu.Unlock()
+
+	if ch == nil {
+		return
+	}
+
+	select {
+	case ch <- msg:
+	default:
+	}
+}
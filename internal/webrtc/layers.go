@@ -0,0 +1,268 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api/ws"
+	"github.com/AlexxIT/go2rtc/internal/events"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/webrtc"
+)
+
+// LayerAuto - select layer by the bandwidth estimate (TWCC or REMB) to the viewer
+const LayerAuto = "auto"
+
+const (
+	autoInterval  = time.Second
+	autoDownTicks = 3                // seconds with low bandwidth before switch down
+	autoHoldMin   = 20 * time.Second // time on the lower layer before trying the higher one
+	autoHoldMax   = 5 * time.Minute
+)
+
+// viewer - WebRTC consumer with layer selection
+type viewer struct {
+	conn    *webrtc.Conn
+	stream  *streams.Stream
+	control bool // talkback and PTZ are allowed
+	done    chan struct{}
+
+	mu      sync.Mutex
+	auto    bool
+	channel chan *ws.Message // data channel messages to the viewer
+
+	// auto mode state
+	bytes   int
+	low     int
+	hold    time.Duration
+	upAfter time.Time
+	upTime  time.Time
+}
+
+var viewers = map[*webrtc.Conn]*viewer{}
+var viewersMu sync.Mutex
+
+var autoLayer bool
+
+func addViewer(conn *webrtc.Conn, stream *streams.Stream, control bool) {
+	v := &viewer{conn: conn, stream: stream, control: control, done: make(chan struct{}), auto: autoLayer}
+
+	viewersMu.Lock()
+	viewers[conn] = v
+	viewersMu.Unlock()
+
+	go v.worker()
+}
+
+func removeViewer(conn *webrtc.Conn) {
+	viewersMu.Lock()
+	if v, ok := viewers[conn]; ok {
+		close(v.done)
+		delete(viewers, conn)
+	}
+	viewersMu.Unlock()
+}
+
+func getViewer(conn *webrtc.Conn) *viewer {
+	viewersMu.Lock()
+	defer viewersMu.Unlock()
+	return viewers[conn]
+}
+
+// SetLayer - switch viewer to the stream layer ("main", "sub", simulcast RID) or to the auto mode
+func SetLayer(conn *webrtc.Conn, layer string) error {
+	v := getViewer(conn)
+	if v == nil {
+		return errors.New("webrtc: connection is not a viewer")
+	}
+
+	if layer == LayerAuto {
+		v.mu.Lock()
+		v.auto = true
+		v.mu.Unlock()
+		v.sendState()
+		return nil
+	}
+
+	v.mu.Lock()
+	v.auto = false
+	v.mu.Unlock()
+
+	return v.switchLayer(layer)
+}
+
+func (v *viewer) switchLayer(layer string) error {
+	if layer == v.conn.GetRingType() {
+		return nil
+	}
+
+	if err := v.stream.SwitchLayer(v.conn.Senders, layer); err != nil {
+		return err
+	}
+
+	v.conn.SetRingType(layer)
+
+	log.Debug().Str("session", v.conn.SessionID).Str("layer", layer).Msg("[webrtc] switch layer")
+
+	events.Publish("webrtc_layer", v.conn.StreamSource, map[string]any{
+		"id":      v.conn.ID,
+		"session": v.conn.SessionID,
+		"viewer":  v.conn.ViewerID,
+		"layer":   layer,
+	})
+
+	v.sendState()
+
+	return nil
+}
+
+func (v *viewer) worker() {
+	ticker := time.NewTicker(autoInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.done:
+			return
+		case now := <-ticker.C:
+			v.mu.Lock()
+			auto := v.auto
+			v.mu.Unlock()
+
+			if auto {
+				v.autoLayer(now)
+			}
+		}
+	}
+}
+
+// autoLayer - go down when the estimate is lower than the current bitrate for a few seconds,
+// try to go up after the hold time, the hold time grows if the higher layer fails again
+func (v *viewer) autoLayer(now time.Time) {
+	var bytes int
+	for _, sender := range v.conn.Senders {
+		bytes += sender.Bytes
+	}
+	bitrate := (bytes - v.bytes) * 8 * int(time.Second/autoInterval)
+	v.bytes = bytes
+
+	estimate := v.conn.Bandwidth()
+	if estimate == 0 || bitrate <= 0 {
+		return
+	}
+
+	layers := v.stream.Layers()
+	i := core.Index(layers, v.conn.GetRingType())
+	if i < 0 {
+		return
+	}
+
+	if estimate < bitrate*9/10 {
+		if v.low++; v.low < autoDownTicks || i == len(layers)-1 {
+			return
+		}
+
+		// the higher layer didn't work, wait longer before the next try
+		if now.Sub(v.upTime) < 2*v.hold {
+			v.hold = min(2*v.hold, autoHoldMax)
+		} else {
+			v.hold = autoHoldMin
+		}
+		v.upAfter = now.Add(v.hold)
+		v.low = 0
+
+		if err := v.switchLayer(layers[i+1]); err != nil {
+			log.Debug().Err(err).Msg("[webrtc] auto layer")
+		}
+		return
+	}
+
+	v.low = 0
+
+	if i > 0 && now.After(v.upAfter) {
+		v.upTime = now
+		if err := v.switchLayer(layers[i-1]); err != nil {
+			log.Debug().Err(err).Msg("[webrtc] auto layer")
+		}
+	}
+}
+
+// layerHandler - ws message with the layer name for the current WebRTC connection
+func layerHandler(tr *ws.Transport, msg *ws.Message) error {
+	var conn *webrtc.Conn
+	tr.WithContext(func(ctx map[any]any) {
+		conn, _ = ctx["webrtc_conn"].(*webrtc.Conn)
+	})
+	if conn == nil {
+		return errors.New("webrtc: no connection")
+	}
+	return SetLayer(conn, msg.String())
+}
+
+func sessionLayerHTTPHandler(w http.ResponseWriter, r *http.Request) {
+	// Handle preflight OPTIONS request
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var reqBody struct {
+		SessionID string `json:"session_id"`
+		Layer     string `json:"layer"`
+	}
+
+	if r.Method == "GET" {
+		reqBody.SessionID = r.URL.Query().Get("session_id")
+	} else if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.SessionID == "" {
+		http.Error(w, "session_id is required", http.StatusBadRequest)
+		return
+	}
+
+	conn := findSession(r, reqBody.SessionID)
+	if conn == nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	v := getViewer(conn)
+	if v == nil {
+		http.Error(w, "Session is not a viewer", http.StatusBadRequest)
+		return
+	}
+
+	if reqBody.Layer != "" {
+		if err := SetLayer(conn, reqBody.Layer); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info().Str("session", reqBody.SessionID).Str("layer", reqBody.Layer).Msg("[webrtc] Session layer")
+	}
+
+	v.mu.Lock()
+	auto := v.auto
+	v.mu.Unlock()
+
+	response := map[string]interface{}{
+		"success":    true,
+		"session_id": reqBody.SessionID,
+		"layer":      conn.GetRingType(),
+		"layers":     v.stream.Layers(),
+		"auto":       auto,
+		"bandwidth":  conn.Bandwidth(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
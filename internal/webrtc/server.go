@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/auth"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/webrtc"
@@ -124,19 +125,33 @@ func outputWebRTC(w http.ResponseWriter, r *http.Request) {
 		desc = "webrtc/post"
 	}
 
-	answer, err := ExchangeSDP(stream, offer, desc, r.UserAgent())
+	id := auth.FromRequest(r)
+	allowed := func(action string) bool {
+		return id.Allowed(u, action)
+	}
+
+	answer, sessionID, err := exchangeSDP(stream, offer, desc, r.UserAgent(), r, allowed)
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.StatusCode(err))
 		return
 	}
 
+	// session ID for pause, resume and layer switching
+	if sessionID != "" {
+		w.Header().Set("X-Session-Id", sessionID)
+	}
+
 	switch mediaType {
 	case "application/json":
 		w.Header().Set("Content-Type", mediaType)
 
-		v := pion.SessionDescription{
-			Type: pion.SDPTypeAnswer, SDP: answer,
+		v := struct {
+			pion.SessionDescription
+			SessionID string `json:"session_id,omitempty"`
+		}{
+			SessionDescription: pion.SessionDescription{Type: pion.SDPTypeAnswer, SDP: answer},
+			SessionID:          sessionID,
 		}
 		err = json.NewEncoder(w).Encode(v)
 
@@ -147,6 +162,7 @@ func outputWebRTC(w http.ResponseWriter, r *http.Request) {
 
 	case MimeSDP:
 		w.Header().Set("Content-Type", mediaType)
+		setICELinks(w.Header())
 		w.WriteHeader(http.StatusCreated)
 
 		_, err = w.Write([]byte(answer))
@@ -226,6 +242,7 @@ func inputWebRTC(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", MimeSDP)
 	w.Header().Set("Location", "webrtc?id="+id)
+	setICELinks(w.Header())
 	w.WriteHeader(http.StatusCreated)
 
 	if _, err = w.Write([]byte(answer)); err != nil {
@@ -0,0 +1,254 @@
+package webrtc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api/ws"
+	"github.com/AlexxIT/go2rtc/pkg/webrtc"
+	"github.com/pion/turn/v4"
+	pion "github.com/pion/webrtc/v4"
+)
+
+// TURN - embedded TURN/STUN server for the clients behind NAT
+type TURN struct {
+	Listen     string        `yaml:"listen"`      // UDP and TCP address, ex. ":3478"
+	PublicIP   string        `yaml:"public_ip"`   // relay address, default: detected with STUN
+	Host       string        `yaml:"host"`        // host for the ICE servers URLs, default: public IP
+	Realm      string        `yaml:"realm"`       // default: go2rtc
+	Username   string        `yaml:"username"`    // long-term credentials
+	Password   string        `yaml:"password"`    // long-term credentials
+	Secret     string        `yaml:"secret"`      // shared secret for time-limited (REST API) credentials
+	TTL        time.Duration `yaml:"ttl"`         // time-limited credentials lifetime, default: 24h
+	RelayPorts []uint16      `yaml:"relay_ports"` // relay ports range, ex. [50000, 50100]
+	AllowPeers []string      `yaml:"allow_peers"` // local peers for relay, ex. ["192.168.1.0/24"]
+}
+
+var turnConf *TURN
+var turnHost string
+var turnMu sync.Mutex
+
+func initTURN(conf *TURN) {
+	if conf.Listen == "" {
+		return
+	}
+
+	if conf.Realm == "" {
+		conf.Realm = "go2rtc"
+	}
+	if conf.TTL == 0 {
+		conf.TTL = 24 * time.Hour
+	}
+
+	if conf.Username == "" && conf.Secret == "" {
+		log.Error().Msg("[webrtc] turn: username/password or secret required")
+		return
+	}
+
+	allow, err := parseNets(conf.AllowPeers)
+	if err != nil {
+		log.Error().Err(err).Msg("[webrtc] turn: wrong allow_peers")
+		return
+	}
+
+	// public IP detection may take some time
+	go func() {
+		if err := startTURN(conf, allow); err != nil {
+			log.Error().Err(err).Caller().Send()
+		}
+	}()
+}
+
+func startTURN(conf *TURN, allow []*net.IPNet) error {
+	var relayIP net.IP
+	if conf.PublicIP != "" {
+		relayIP = net.ParseIP(conf.PublicIP)
+	} else {
+		relayIP, _ = webrtc.GetCachedPublicIP()
+	}
+	if relayIP == nil {
+		return errors.New("turn: can't get public IP")
+	}
+
+	udpConn, err := net.ListenPacket("udp", conf.Listen)
+	if err != nil {
+		return err
+	}
+
+	tcpListener, err := net.Listen("tcp", conf.Listen)
+	if err != nil {
+		_ = udpConn.Close()
+		return err
+	}
+
+	var relay turn.RelayAddressGenerator
+	if len(conf.RelayPorts) == 2 {
+		relay = &turn.RelayAddressGeneratorPortRange{
+			RelayAddress: relayIP,
+			Address:      "0.0.0.0",
+			MinPort:      conf.RelayPorts[0],
+			MaxPort:      conf.RelayPorts[1],
+		}
+	} else {
+		relay = &turn.RelayAddressGeneratorStatic{
+			RelayAddress: relayIP,
+			Address:      "0.0.0.0",
+		}
+	}
+
+	permission := turnPermissionHandler(allow)
+
+	_, err = turn.NewServer(turn.ServerConfig{
+		Realm:       conf.Realm,
+		AuthHandler: turnAuthHandler(conf),
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{PacketConn: udpConn, RelayAddressGenerator: relay, PermissionHandler: permission},
+		},
+		ListenerConfigs: []turn.ListenerConfig{
+			{Listener: tcpListener, RelayAddressGenerator: relay, PermissionHandler: permission},
+		},
+	})
+	if err != nil {
+		_ = udpConn.Close()
+		_ = tcpListener.Close()
+		return err
+	}
+
+	host := conf.Host
+	if host == "" {
+		host = relayIP.String()
+	}
+	_, port, _ := net.SplitHostPort(conf.Listen)
+
+	turnMu.Lock()
+	turnConf = conf
+	turnHost = net.JoinHostPort(host, port)
+	turnMu.Unlock()
+
+	log.Info().Str("addr", conf.Listen).Str("relay", relayIP.String()).Msg("[webrtc] turn listen")
+
+	return nil
+}
+
+func turnAuthHandler(conf *TURN) turn.AuthHandler {
+	var restAuth turn.AuthHandler
+	if conf.Secret != "" {
+		restAuth = turn.LongTermTURNRESTAuthHandler(conf.Secret, nil)
+	}
+
+	return func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+		if conf.Username != "" && subtle.ConstantTimeCompare([]byte(username), []byte(conf.Username)) == 1 {
+			return turn.GenerateAuthKey(username, realm, conf.Password), true
+		}
+		if restAuth != nil && strings.IndexByte(username, ':') > 0 {
+			return restAuth(username, realm, srcAddr)
+		}
+		log.Debug().Str("user", username).Str("addr", srcAddr.String()).Msg("[webrtc] turn: wrong credentials")
+		return nil, false
+	}
+}
+
+// turnPermissionHandler - the relay doesn't send packets to the local networks of
+// the server (loopback, private, link-local), only to the allowed peers
+func turnPermissionHandler(allow []*net.IPNet) turn.PermissionHandler {
+	return func(clientAddr net.Addr, peerIP net.IP) bool {
+		for _, ipnet := range allow {
+			if ipnet.Contains(peerIP) {
+				return true
+			}
+		}
+
+		if peerIP.IsLoopback() || peerIP.IsPrivate() || peerIP.IsUnspecified() ||
+			peerIP.IsLinkLocalUnicast() || peerIP.IsLinkLocalMulticast() {
+			log.Debug().Str("peer", peerIP.String()).Str("addr", clientAddr.String()).Msg("[webrtc] turn: peer not allowed")
+			return false
+		}
+
+		return true
+	}
+}
+
+// parseNets - CIDR list, single IP is also supported
+func parseNets(items []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range items {
+		if ip := net.ParseIP(item); ip != nil {
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipnet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
+
+// turnServers - ICE servers of the embedded TURN server, with new time-limited
+// credentials for each client if the secret is set
+func turnServers() []pion.ICEServer {
+	turnMu.Lock()
+	conf, host := turnConf, turnHost
+	turnMu.Unlock()
+
+	if conf == nil {
+		return nil
+	}
+
+	username, password := conf.Username, conf.Password
+	if conf.Secret != "" {
+		var err error
+		username, password, err = turn.GenerateLongTermTURNRESTCredentials(conf.Secret, "go2rtc", conf.TTL)
+		if err != nil {
+			return nil
+		}
+	}
+
+	return []pion.ICEServer{
+		{URLs: []string{"stun:" + host}},
+		{
+			URLs:       []string{"turn:" + host + "?transport=udp", "turn:" + host + "?transport=tcp"},
+			Username:   username,
+			Credential: password,
+		},
+	}
+}
+
+// ICEServers - ICE servers for the clients: from the config and the embedded TURN server
+func ICEServers() []pion.ICEServer {
+	return append(append([]pion.ICEServer(nil), iceServers...), turnServers()...)
+}
+
+// iceServersHandler - ws request from the web player before creating RTCPeerConnection
+func iceServersHandler(tr *ws.Transport, _ *ws.Message) error {
+	tr.Write(&ws.Message{Type: "webrtc/ice_servers", Value: ICEServers()})
+	return nil
+}
+
+// setICELinks - ICE servers for the WHIP/WHEP clients in the Link headers (RFC 9725)
+func setICELinks(header http.Header) {
+	for _, server := range ICEServers() {
+		for _, u := range server.URLs {
+			link := "<" + u + `>; rel="ice-server"`
+			if server.Username != "" {
+				link += "; username=" + strconv.Quote(server.Username)
+			}
+			if s, ok := server.Credential.(string); ok && s != "" {
+				link += "; credential=" + strconv.Quote(s) + `; credential-type="password"`
+			}
+			header.Add("Link", link)
+		}
+	}
+}
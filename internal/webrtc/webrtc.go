@@ -14,6 +14,8 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/api/ws"
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
+	"github.com/AlexxIT/go2rtc/internal/events"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/webrtc"
@@ -28,6 +30,8 @@ func Init() {
 			Candidates []string         `yaml:"candidates"`
 			IceServers []pion.ICEServer `yaml:"ice_servers"`
 			Filters    webrtc.Filters   `yaml:"filters"`
+			AutoLayer  bool             `yaml:"auto_layer"`
+			TURN       TURN             `yaml:"turn"`
 		} `yaml:"webrtc"`
 	}
 
@@ -41,6 +45,8 @@ func Init() {
 	log = app.GetLogger("webrtc")
 
 	filters = cfg.Mod.Filters
+	autoLayer = cfg.Mod.AutoLayer
+	iceServers = cfg.Mod.IceServers
 
 	address, network, _ := strings.Cut(cfg.Mod.Listen, "/")
 	for _, candidate := range cfg.Mod.Candidates {
@@ -64,6 +70,8 @@ func Init() {
 		clientAPI, _ = webrtc.NewAPI()
 	}
 
+	initTURN(&cfg.Mod.TURN)
+
 	pionConf := pion.Configuration{
 		ICEServers:   cfg.Mod.IceServers,
 		SDPSemantics: pion.SDPSemanticsUnifiedPlanWithFallback,
@@ -74,7 +82,7 @@ func Init() {
 		if active {
 			return clientAPI.NewPeerConnection(pionConf)
 		} else {
-			return serverAPI.NewPeerConnection(pionConf)
+			return webrtc.NewPeerConnection(serverAPI, pionConf)
 		}
 	}
 
@@ -82,13 +90,20 @@ func Init() {
 	ws.HandleFunc("webrtc", asyncHandler)
 	ws.HandleFunc("webrtc/offer", asyncHandler)
 	ws.HandleFunc("webrtc/candidate", candidateHandler)
+	ws.HandleFunc("webrtc/ice_servers", iceServersHandler)
 	
 	// pause/resume controls
 	ws.HandleFunc("webrtc/pause", pauseHandler)
 	ws.HandleFunc("webrtc/resume", resumeHandler)
 
+	// layer selection: main/sub sources, simulcast RIDs or auto
+	ws.HandleFunc("webrtc/layer", layerHandler)
+
 	// sync WebRTC server (two API versions)
 	api.HandleFunc("api/webrtc", syncHandler)
+
+	// WHIP ingest with trickle ICE
+	api.HandleFunc("api/whip", whipHandler)
 	
 	// HTTP API for pause/resume controls
 	api.HandleFunc("api/webrtc/pause", pauseHTTPHandler)
@@ -98,6 +113,7 @@ func Init() {
 	api.HandleFunc("api/webrtc/session/pause", sessionPauseHTTPHandler)
 	api.HandleFunc("api/webrtc/session/resume", sessionResumeHTTPHandler)
 	api.HandleFunc("api/webrtc/sessions", listSessionsHTTPHandler)
+	api.HandleFunc("api/webrtc/session/layer", sessionLayerHTTPHandler)
 
 	// WebRTC client
 	streams.HandleFunc("webrtc", streamsHandler)
@@ -105,6 +121,8 @@ func Init() {
 
 var serverAPI, clientAPI *pion.API
 
+var iceServers []pion.ICEServer
+
 var log zerolog.Logger
 
 var PeerConnection func(active bool) (*pion.PeerConnection, error)
@@ -117,6 +135,55 @@ var connectionsMutex sync.RWMutex
 var sessionConnections = make(map[string]*webrtc.Conn)
 var sessionMutex sync.RWMutex
 
+// trackConn - register viewer for pause/resume, layer switching and session API
+func trackConn(conn *webrtc.Conn, stream *streams.Stream, control bool) {
+	connectionsMutex.Lock()
+	activeConnections[conn.ID] = conn
+	connectionsMutex.Unlock()
+
+	sessionMutex.Lock()
+	sessionConnections[conn.SessionID] = conn
+	sessionMutex.Unlock()
+
+	addViewer(conn, stream, control)
+}
+
+func untrackConn(conn *webrtc.Conn) {
+	removeViewer(conn)
+
+	connectionsMutex.Lock()
+	delete(activeConnections, conn.ID)
+	connectionsMutex.Unlock()
+
+	sessionMutex.Lock()
+	delete(sessionConnections, conn.SessionID)
+	sessionMutex.Unlock()
+}
+
+// findSession - session of the stream the client is authorized for (src param),
+// admin can use any session
+func findSession(r *http.Request, sessionID string) *webrtc.Conn {
+	sessionMutex.RLock()
+	conn := sessionConnections[sessionID]
+	sessionMutex.RUnlock()
+
+	if conn == nil || !sessionAllowed(r, conn) {
+		return nil
+	}
+
+	return conn
+}
+
+func sessionAllowed(r *http.Request, conn *webrtc.Conn) bool {
+	id := auth.FromRequest(r)
+	if id.Admin {
+		return true
+	}
+
+	src := r.URL.Query().Get("src")
+	return src != "" && src == conn.StreamSource && id.Allowed(src, auth.View)
+}
+
 // generateSessionID creates a unique session identifier
 func generateSessionID() string {
 	bytes := make([]byte, 16)
@@ -207,7 +274,7 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 	if offer.ICEServers == nil {
 		pc, err = PeerConnection(false)
 	} else {
-		pc, err = serverAPI.NewPeerConnection(pion.Configuration{ICEServers: offer.ICEServers})
+		pc, err = webrtc.NewPeerConnection(serverAPI, pion.Configuration{ICEServers: offer.ICEServers})
 	}
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
@@ -242,7 +309,7 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 	
 	// Set initial pause state if provided
 	if pausedParam := query.Get("paused"); pausedParam == "true" {
-		conn.Pause()
+		pauseConn(conn)
 		log.Info().Str("session", conn.SessionID).Str("viewer", conn.ViewerID).Msg("[webrtc] 🔇 CONNECTION STARTED IN PAUSED STATE")
 	}
 	
@@ -279,11 +346,17 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 			
 			switch mode {
 			case core.ModePassiveConsumer:
+				removeViewer(conn)
 				stream.RemoveConsumer(conn)
 			case core.ModePassiveProducer:
 				stream.RemoveProducer(conn)
 			}
 
+		case *pion.DataChannel:
+			if v := getViewer(conn); v != nil {
+				v.bindChannel(msg)
+			}
+
 		case *pion.ICECandidate:
 			if !FilterCandidate(msg) {
 				return
@@ -306,12 +379,20 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 
 	switch mode {
 	case core.ModePassiveConsumer:
+		// backchannel access also allows camera control (talkback, PTZ)
+		control := auth.FromRequest(tr.Request).Allowed(query.Get("src"), auth.Backchannel)
+		if !control {
+			removeBackchannel(conn)
+		}
+
 		// 2. AddConsumer, so we get new tracks
 		if err = stream.AddConsumer(conn); err != nil {
 			log.Debug().Err(err).Msg("[webrtc] add consumer")
 			_ = conn.Close()
 			return err
 		}
+
+		addViewer(conn, stream, control)
 	case core.ModePassiveProducer:
 		stream.AddProducer(conn)
 	}
@@ -351,6 +432,16 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 }
 
 func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer string, err error) {
+	answer, _, err = exchangeSDP(stream, offer, desc, userAgent, nil, nil)
+	return
+}
+
+// exchangeSDP - allowed checks client access to the stream, nil - any access.
+// The request is optional, it's used for the client IP and the stream name.
+// Viewers get session ID for pause, resume and layer switching.
+func exchangeSDP(
+	stream *streams.Stream, offer, desc, userAgent string, r *http.Request, allowed func(action string) bool,
+) (answer, sessionID string, err error) {
 	pc, err := PeerConnection(false)
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
@@ -362,6 +453,11 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 	conn.FormatName = desc
 	conn.UserAgent = userAgent
 	conn.Protocol = "http"
+	conn.SessionID = generateSessionID()
+	if r != nil {
+		conn.ClientIP = getClientIP(r)
+		conn.StreamSource = r.URL.Query().Get("src")
+	}
 	conn.Listen(func(msg any) {
 		switch msg := msg.(type) {
 		case pion.PeerConnectionState:
@@ -369,10 +465,16 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 				return
 			}
 			if conn.Mode == core.ModePassiveConsumer {
+				untrackConn(conn)
 				stream.RemoveConsumer(conn)
 			} else {
 				stream.RemoveProducer(conn)
 			}
+
+		case *pion.DataChannel:
+			if v := getViewer(conn); v != nil {
+				v.bindChannel(msg)
+			}
 		}
 	})
 
@@ -387,13 +489,27 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 	if IsConsumer(conn) {
 		conn.Mode = core.ModePassiveConsumer
 
+		// backchannel access also allows camera control (talkback, PTZ)
+		control := allowed == nil || allowed(auth.Backchannel)
+		if !control {
+			removeBackchannel(conn)
+		}
+
 		// 2. AddConsumer, so we get new tracks
 		if err = stream.AddConsumer(conn); err != nil {
 			log.Warn().Err(err).Caller().Send()
 			_ = conn.Close()
 			return
 		}
+
+		trackConn(conn, stream, control)
+		sessionID = conn.SessionID
 	} else {
+		if allowed != nil && !allowed(auth.Publish) {
+			_ = conn.Close()
+			return "", "", errors.New("webrtc: publish not allowed")
+		}
+
 		conn.Mode = core.ModePassiveProducer
 
 		stream.AddProducer(conn)
@@ -409,6 +525,17 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 	return
 }
 
+// removeBackchannel - skip medias from the client, if it doesn't have backchannel access
+func removeBackchannel(conn *webrtc.Conn) {
+	medias := conn.Medias[:0]
+	for _, media := range conn.Medias {
+		if media.Direction != core.DirectionRecvonly {
+			medias = append(medias, media)
+		}
+	}
+	conn.Medias = medias
+}
+
 func IsConsumer(conn *webrtc.Conn) bool {
 	// if wants get video - consumer
 	for _, media := range conn.GetMedias() {
@@ -431,161 +558,170 @@ func IsConsumer(conn *webrtc.Conn) bool {
 	return false
 }
 
-// Pause/Resume Handler Functions
+// pauseConn - pause connection and publish event
+func pauseConn(conn *webrtc.Conn) {
+	conn.Pause()
+	publishConn("webrtc_pause", conn)
+}
 
-func pauseHandler(tr *ws.Transport, msg *ws.Message) error {
-	log.Info().Msg("[webrtc] 🔥 PAUSE MESSAGE RECEIVED!")
-	
-	// Use the global connection tracking instead of context
-	connectionsMutex.RLock()
-	defer connectionsMutex.RUnlock()
-	
-	log.Info().Int("total_connections", len(activeConnections)).Msg("[webrtc] checking active connections")
-	
-	pausedCount := 0
-	for connID, conn := range activeConnections {
-		log.Info().Uint32("conn_id", connID).Str("mode", conn.Mode.String()).Msg("[webrtc] found connection")
-		if conn.Mode == core.ModePassiveConsumer { // Only pause consumers (video viewers)
-			conn.Pause()
-			log.Info().Uint32("conn", connID).Bool("is_paused", conn.IsPaused()).Msg("[webrtc] ✅ CONNECTION PAUSED")
-			pausedCount++
-		}
+// resumeConn - resume connection and publish event
+func resumeConn(conn *webrtc.Conn) {
+	conn.Resume()
+	publishConn("webrtc_resume", conn)
+}
+
+// PauseSession - pause WebRTC session by ID, returns false if session not found
+func PauseSession(sessionID string) bool {
+	sessionMutex.RLock()
+	conn, ok := sessionConnections[sessionID]
+	sessionMutex.RUnlock()
+
+	if ok {
+		pauseConn(conn)
 	}
-	
-	if pausedCount > 0 {
-		log.Info().Int("paused", pausedCount).Msg("[webrtc] pause: completed")
-	} else {
-		log.Warn().Msg("[webrtc] pause: no active consumer connections found")
+	return ok
+}
+
+// ResumeSession - resume WebRTC session by ID, returns false if session not found
+func ResumeSession(sessionID string) bool {
+	sessionMutex.RLock()
+	conn, ok := sessionConnections[sessionID]
+	sessionMutex.RUnlock()
+
+	if ok {
+		resumeConn(conn)
 	}
-	
-	return nil
+	return ok
 }
 
-func resumeHandler(tr *ws.Transport, msg *ws.Message) error {
-	// Use the global connection tracking instead of context
+func publishConn(typ string, conn *webrtc.Conn) {
+	events.Publish(typ, conn.StreamSource, map[string]any{
+		"id":        conn.ID,
+		"session":   conn.SessionID,
+		"viewer":    conn.ViewerID,
+		"client_ip": conn.ClientIP,
+	})
+}
+
+// Pause/Resume Handler Functions
+
+var errPauseForbidden = errors.New("webrtc: pause all connections allowed only for admin")
+
+// pauseConns - pause or resume consumers of the stream the client is authorized for,
+// optionally only one viewer. Consumers of all streams can be changed only by admin.
+func pauseConns(id *auth.Identity, src, viewerID string, pause bool) (int, error) {
+	if src == "" {
+		if !id.Admin {
+			return 0, errPauseForbidden
+		}
+	} else if !id.Allowed(src, auth.View) {
+		return 0, errPauseForbidden
+	}
+
 	connectionsMutex.RLock()
 	defer connectionsMutex.RUnlock()
-	
-	resumedCount := 0
-	for connID, conn := range activeConnections {
-		if conn.Mode == core.ModePassiveConsumer { // Only resume consumers (video viewers)
-			conn.Resume()
-			log.Info().Uint32("conn", connID).Msg("[webrtc] ✅ CONNECTION RESUMED")
-			resumedCount++
+
+	var n int
+	for _, conn := range activeConnections {
+		if conn.Mode != core.ModePassiveConsumer {
+			continue // only consumers (video viewers)
 		}
+		if src != "" && conn.StreamSource != src {
+			continue
+		}
+		if viewerID != "" && conn.ViewerID != viewerID {
+			continue
+		}
+
+		if pause {
+			pauseConn(conn)
+		} else {
+			resumeConn(conn)
+		}
+		n++
 	}
-	
-	if resumedCount > 0 {
-		log.Info().Int("resumed", resumedCount).Msg("[webrtc] resume: completed")
-	} else {
-		log.Warn().Msg("[webrtc] resume: no active consumer connections found")
+
+	return n, nil
+}
+
+// pauseMessage - optional viewer ID in the WS message value
+func pauseMessage(tr *ws.Transport, msg *ws.Message, pause bool) error {
+	var value struct {
+		ViewerID string `json:"viewer_id"`
 	}
-	
+	_ = msg.Unmarshal(&value)
+
+	src := tr.Request.URL.Query().Get("src")
+
+	n, err := pauseConns(auth.FromRequest(tr.Request), src, value.ViewerID, pause)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Str("src", src).Str("viewer", value.ViewerID).Int("count", n).Bool("pause", pause).Msg("[webrtc] pause")
+
 	return nil
 }
 
+func pauseHandler(tr *ws.Transport, msg *ws.Message) error {
+	return pauseMessage(tr, msg, true)
+}
 
+func resumeHandler(tr *ws.Transport, msg *ws.Message) error {
+	return pauseMessage(tr, msg, false)
+}
 
 // HTTP API Handlers for pause/resume
 
 func pauseHTTPHandler(w http.ResponseWriter, r *http.Request) {
-	// Parse request body to get viewer_id
-	var reqBody struct {
-		Action   string `json:"action"`
-		ViewerID string `json:"viewer_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		log.Warn().Err(err).Msg("[webrtc] HTTP pause: invalid request body")
-		reqBody.ViewerID = "" // Fall back to global pause
-	}
-	
-	connectionsMutex.RLock()
-	defer connectionsMutex.RUnlock()
-	
-	log.Info().Int("total_connections", len(activeConnections)).Str("target_viewer", reqBody.ViewerID).Msg("[webrtc] HTTP pause: checking connections")
-	
-	pausedCount := 0
-	for connID, conn := range activeConnections {
-		log.Info().Uint32("conn_id", connID).Str("mode", conn.Mode.String()).Str("viewer", conn.ViewerID).Msg("[webrtc] HTTP pause: found connection")
-		
-		// Check if this connection matches the target viewer (or pause all if no viewer_id specified)
-		if conn.Mode == core.ModePassiveConsumer && (reqBody.ViewerID == "" || conn.ViewerID == reqBody.ViewerID) {
-			conn.Pause()
-			log.Info().Uint32("conn", connID).Str("viewer", conn.ViewerID).Msg("[webrtc] ✅ HTTP CONNECTION PAUSED")
-			pausedCount++
-		}
-	}
-	
-	response := map[string]interface{}{
-		"action": "pause",
-		"paused_connections": pausedCount,
-		"success": true,
-		"viewer_id": reqBody.ViewerID,
-	}
-	
-	if pausedCount == 0 {
-		response["success"] = false
-		if reqBody.ViewerID != "" {
-			response["message"] = "No active consumer connections found for viewer: " + reqBody.ViewerID
-		} else {
-			response["message"] = "No active consumer connections found"
-		}
-	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-	
-	log.Info().Int("paused", pausedCount).Str("viewer", reqBody.ViewerID).Msg("[webrtc] HTTP pause: completed")
+	pauseRequest(w, r, true)
 }
 
 func resumeHTTPHandler(w http.ResponseWriter, r *http.Request) {
-	// Parse request body to get viewer_id
+	pauseRequest(w, r, false)
+}
+
+// pauseRequest - viewer ID in the JSON body, stream from the src param
+func pauseRequest(w http.ResponseWriter, r *http.Request, pause bool) {
 	var reqBody struct {
 		Action   string `json:"action"`
 		ViewerID string `json:"viewer_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		log.Warn().Err(err).Msg("[webrtc] HTTP resume: invalid request body")
-		reqBody.ViewerID = "" // Fall back to global resume
+		log.Warn().Err(err).Msg("[webrtc] HTTP pause: invalid request body")
 	}
-	
-	connectionsMutex.RLock()
-	defer connectionsMutex.RUnlock()
-	
-	log.Info().Int("total_connections", len(activeConnections)).Str("target_viewer", reqBody.ViewerID).Msg("[webrtc] HTTP resume: checking connections")
-	
-	resumedCount := 0
-	for connID, conn := range activeConnections {
-		log.Info().Uint32("conn_id", connID).Str("mode", conn.Mode.String()).Str("viewer", conn.ViewerID).Msg("[webrtc] HTTP resume: found connection")
-		
-		// Check if this connection matches the target viewer (or resume all if no viewer_id specified)
-		if conn.Mode == core.ModePassiveConsumer && (reqBody.ViewerID == "" || conn.ViewerID == reqBody.ViewerID) {
-			conn.Resume()
-			log.Info().Uint32("conn", connID).Str("viewer", conn.ViewerID).Msg("[webrtc] ✅ HTTP CONNECTION RESUMED")
-			resumedCount++
-		}
+
+	src := r.URL.Query().Get("src")
+
+	n, err := pauseConns(auth.FromRequest(r), src, reqBody.ViewerID, pause)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
 	}
-	
-	response := map[string]interface{}{
-		"action": "resume",
-		"resumed_connections": resumedCount,
-		"success": true,
+
+	action, key := "pause", "paused_connections"
+	if !pause {
+		action, key = "resume", "resumed_connections"
+	}
+
+	response := map[string]any{
+		"action":    action,
+		key:         n,
+		"success":   n > 0,
 		"viewer_id": reqBody.ViewerID,
 	}
-	
-	if resumedCount == 0 {
-		response["success"] = false
+
+	if n == 0 {
 		if reqBody.ViewerID != "" {
 			response["message"] = "No active consumer connections found for viewer: " + reqBody.ViewerID
 		} else {
 			response["message"] = "No active consumer connections found"
 		}
 	}
-	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-	
-	log.Info().Int("resumed", resumedCount).Str("viewer", reqBody.ViewerID).Msg("[webrtc] HTTP resume: completed")
+
+	api.ResponseJSON(w, response)
+
+	log.Debug().Str("src", src).Str("viewer", reqBody.ViewerID).Int("count", n).Bool("pause", pause).Msg("[webrtc] HTTP pause")
 }
 
 // GetActiveConnections returns a copy of active connections for motion detection
@@ -625,16 +761,13 @@ func sessionPauseHTTPHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	sessionMutex.RLock()
-	conn, exists := sessionConnections[reqBody.SessionID]
-	sessionMutex.RUnlock()
-	
-	if !exists {
+	conn := findSession(r, reqBody.SessionID)
+	if conn == nil {
 		http.Error(w, "Session not found", http.StatusNotFound)
 		return
 	}
 	
-	conn.Pause()
+	pauseConn(conn)
 	log.Info().Str("session", reqBody.SessionID).Msg("[webrtc] Session paused")
 	
 	response := map[string]interface{}{
@@ -670,16 +803,13 @@ func sessionResumeHTTPHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	sessionMutex.RLock()
-	conn, exists := sessionConnections[reqBody.SessionID]
-	sessionMutex.RUnlock()
-	
-	if !exists {
+	conn := findSession(r, reqBody.SessionID)
+	if conn == nil {
 		http.Error(w, "Session not found", http.StatusNotFound)
 		return
 	}
 	
-	conn.Resume()
+	resumeConn(conn)
 	log.Info().Str("session", reqBody.SessionID).Msg("[webrtc] Session resumed")
 	
 	response := map[string]interface{}{
@@ -698,6 +828,9 @@ func listSessionsHTTPHandler(w http.ResponseWriter, r *http.Request) {
 	
 	sessions := make([]map[string]interface{}, 0, len(sessionConnections))
 	for sessionID, conn := range sessionConnections {
+		if !sessionAllowed(r, conn) {
+			continue
+		}
 		sessions = append(sessions, map[string]interface{}{
 			"session_id":    sessionID,
 			"connection_id": conn.ID,
@@ -706,6 +839,8 @@ func listSessionsHTTPHandler(w http.ResponseWriter, r *http.Request) {
 			"client_ip":     conn.ClientIP,
 			"mode":          conn.Mode.String(),
 			"paused":        conn.IsPaused(),
+			"layer":         conn.GetRingType(),
+			"bandwidth":     conn.Bandwidth(),
 		})
 	}
 	
@@ -2,10 +2,18 @@ package webrtc
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/api/ws"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/webrtc"
+	"github.com/pion/turn/v4"
 	pion "github.com/pion/webrtc/v4"
 	"github.com/stretchr/testify/require"
 )
@@ -71,3 +79,186 @@ a=candidate:3 1 TCP 1010827519 172.22.233.10 60677 typ host tcptype passive
 	require.Nil(t, err)
 	require.False(t, strings.Contains(sdp, "x-google-max-bitrate"))
 }
+
+func TestWHIP(t *testing.T) {
+	api, err := webrtc.NewAPI()
+	require.Nil(t, err)
+
+	PeerConnection = func(active bool) (*pion.PeerConnection, error) {
+		return api.NewPeerConnection(pion.Configuration{})
+	}
+
+	streams.New("whip1")
+	defer streams.Delete("whip1")
+
+	client, err := api.NewPeerConnection(pion.Configuration{})
+	require.Nil(t, err)
+	defer client.Close()
+
+	_, err = client.AddTransceiverFromKind(pion.RTPCodecTypeVideo, pion.RTPTransceiverInit{
+		Direction: pion.RTPTransceiverDirectionSendonly,
+	})
+	require.Nil(t, err)
+
+	offer, err := client.CreateOffer(nil)
+	require.Nil(t, err)
+	require.Nil(t, client.SetLocalDescription(offer))
+
+	whip := func(method, target, contentType, body string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		for i := 0; i < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		whipHandler(w, r)
+		return w
+	}
+
+	w := whip("POST", "/api/whip?dst=whip2", MimeSDP, offer.SDP)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = whip("POST", "/api/whip?dst=whip1", MimeSDP, offer.SDP)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	location := w.Header().Get("Location")
+	require.True(t, strings.HasPrefix(location, "whip?dst=whip1&id="))
+
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	answer := pion.SessionDescription{Type: pion.SDPTypeAnswer, SDP: w.Body.String()}
+	require.Nil(t, client.SetRemoteDescription(answer))
+
+	const frag = "a=ice-ufrag:%s\r\na=mid:0\r\na=candidate:1 1 udp 2130706431 127.0.0.1 50000 typ host\r\n"
+	ufrag := iceUfrag(offer.SDP)
+
+	w = whip("PATCH", "/api/"+location, MimeTrickleICE, fmt.Sprintf(frag, ufrag), "If-Match", `"wrong"`)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = whip("PATCH", "/api/"+location, MimeTrickleICE, fmt.Sprintf(frag, "restart"))
+	require.Equal(t, http.StatusNotImplemented, w.Code)
+
+	w = whip("PATCH", "/api/"+location, MimeTrickleICE, fmt.Sprintf(frag, ufrag), "If-Match", etag)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	// the session is bound to the stream from the dst param
+	w = whip("DELETE", "/api/"+strings.Replace(location, "whip1", "whip2", 1), "", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = whip("DELETE", "/api/"+location, "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestTURNAuth(t *testing.T) {
+	conf := &TURN{Username: "user", Password: "pass", Secret: "secret", TTL: time.Hour}
+	auth := turnAuthHandler(conf)
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000}
+
+	key, ok := auth("user", "go2rtc", addr)
+	require.True(t, ok)
+	require.Equal(t, turn.GenerateAuthKey("user", "go2rtc", "pass"), key)
+
+	_, ok = auth("admin", "go2rtc", addr)
+	require.False(t, ok)
+
+	username, password, err := turn.GenerateLongTermTURNRESTCredentials("secret", "go2rtc", time.Hour)
+	require.Nil(t, err)
+
+	key, ok = auth(username, "go2rtc", addr)
+	require.True(t, ok)
+	require.Equal(t, turn.GenerateAuthKey(username, "go2rtc", password), key)
+
+	username, _, _ = turn.GenerateLongTermTURNRESTCredentials("secret", "go2rtc", -time.Hour)
+	_, ok = auth(username, "go2rtc", addr)
+	require.False(t, ok)
+}
+
+func TestTURNPermission(t *testing.T) {
+	client := &net.UDPAddr{IP: net.IPv4(8, 8, 4, 4), Port: 50000}
+
+	permission := turnPermissionHandler(nil)
+	for _, peer := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.10", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::", "::ffff:192.168.1.10"} {
+		require.False(t, permission(client, net.ParseIP(peer)), peer)
+	}
+	for _, peer := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		require.True(t, permission(client, net.ParseIP(peer)), peer)
+	}
+
+	allow, err := parseNets([]string{"192.168.1.0/24", "10.0.0.5"})
+	require.Nil(t, err)
+
+	permission = turnPermissionHandler(allow)
+	require.True(t, permission(client, net.ParseIP("192.168.1.10")))
+	require.True(t, permission(client, net.ParseIP("::ffff:192.168.1.10")))
+	require.True(t, permission(client, net.ParseIP("10.0.0.5")))
+	require.False(t, permission(client, net.ParseIP("10.0.0.6")))
+	require.False(t, permission(client, net.ParseIP("127.0.0.1")))
+
+	_, err = parseNets([]string{"192.168.1.0/33"})
+	require.NotNil(t, err)
+}
+
+func TestICELinks(t *testing.T) {
+	iceServers = []pion.ICEServer{{URLs: []string{"stun:stun.l.google.com:19302"}}}
+
+	turnMu.Lock()
+	turnConf = &TURN{Username: "user", Password: "pass"}
+	turnHost = "example.com:3478"
+	turnMu.Unlock()
+
+	defer func() {
+		iceServers = nil
+		turnMu.Lock()
+		turnConf = nil
+		turnMu.Unlock()
+	}()
+
+	header := http.Header{}
+	setICELinks(header)
+
+	require.Equal(t, []string{
+		`<stun:stun.l.google.com:19302>; rel="ice-server"`,
+		`<stun:example.com:3478>; rel="ice-server"`,
+		`<turn:example.com:3478?transport=udp>; rel="ice-server"; username="user"; credential="pass"; credential-type="password"`,
+		`<turn:example.com:3478?transport=tcp>; rel="ice-server"; username="user"; credential="pass"; credential-type="password"`,
+	}, header.Values("Link"))
+}
+
+func TestDataChannelMessages(t *testing.T) {
+	api, err := webrtc.NewAPI()
+	require.Nil(t, err)
+
+	pc, err := api.NewPeerConnection(pion.Configuration{})
+	require.Nil(t, err)
+
+	conn := webrtc.NewConn(pc)
+	defer conn.Close()
+
+	stream := streams.New("dc1", "rtsp://localhost/stream")
+	defer streams.Delete("dc1")
+
+	v := &viewer{conn: conn, stream: stream, done: make(chan struct{}), channel: make(chan *ws.Message, 10)}
+
+	require.Nil(t, v.handleMessage([]byte(`{"type":"pause"}`)))
+	require.True(t, conn.IsPaused())
+
+	msg := <-v.channel
+	require.Equal(t, "state", msg.Type)
+	require.Equal(t, true, msg.Value.(map[string]any)["paused"])
+
+	require.Nil(t, v.handleMessage([]byte(`{"type":"resume"}`)))
+	require.False(t, conn.IsPaused())
+
+	require.NotNil(t, v.handleMessage([]byte(`{"type":"talkback","value":false}`)))
+	require.True(t, conn.Talkback())
+
+	v.control = true
+	require.Nil(t, v.handleMessage([]byte(`{"type":"talkback","value":false}`)))
+	require.False(t, conn.Talkback())
+
+	err = v.handleMessage([]byte(`{"type":"ptz","value":{"action":"stop"}}`))
+	require.EqualError(t, err, "onvif: stream without ONVIF source")
+
+	require.NotNil(t, v.handleMessage([]byte(`{"type":"unknown"}`)))
+}
@@ -0,0 +1,237 @@
+package webrtc
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/webrtc"
+	pion "github.com/pion/webrtc/v4"
+)
+
+// WebRTC-HTTP Ingestion Protocol (WHIP)
+// https://www.rfc-editor.org/rfc/rfc9725.html
+
+const MimeTrickleICE = "application/trickle-ice-sdpfrag"
+
+type whipSession struct {
+	conn  *webrtc.Conn
+	dst   string
+	etag  string // ICE session entity-tag
+	ufrag string // remote ICE username fragment
+}
+
+var whipSessions = map[string]*whipSession{}
+var whipMu sync.Mutex
+
+// whipHandler - POST api/whip?dst={stream} creates the session, the session resource
+// api/whip?dst={stream}&id={id} supports PATCH for trickle ICE and DELETE for stop
+func whipHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dst := query.Get("dst")
+	if dst == "" {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		if query.Has("id") {
+			http.Error(w, "", http.StatusMethodNotAllowed)
+			return
+		}
+		whipPost(w, r, dst)
+
+	case "PATCH":
+		if session := getWHIPSession(query.Get("id"), dst); session != nil {
+			whipPatch(w, r, session)
+		} else {
+			http.Error(w, "", http.StatusNotFound)
+		}
+
+	case "DELETE":
+		if session := getWHIPSession(query.Get("id"), dst); session != nil {
+			_ = session.conn.Close()
+		} else {
+			http.Error(w, "", http.StatusNotFound)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Accept-Post", MimeSDP)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+func whipPost(w http.ResponseWriter, r *http.Request, dst string) {
+	if mediaType(r) != MimeSDP {
+		http.Error(w, "", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	stream := streams.Get(dst)
+	if stream == nil {
+		http.Error(w, api.StreamNotFound, http.StatusNotFound)
+		return
+	}
+
+	offer, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Trace().Msgf("[webrtc] WHIP offer\n%s", offer)
+
+	pc, err := PeerConnection(false)
+	if err != nil {
+		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	prod := webrtc.NewConn(pc)
+	prod.FormatName = "webrtc/whip"
+	prod.Mode = core.ModePassiveProducer
+	prod.Protocol = "http"
+	prod.UserAgent = r.UserAgent()
+
+	// listener before the offer, so the state changes don't race with it
+	id := generateSessionID()
+	prod.Listen(func(msg any) {
+		switch msg := msg.(type) {
+		case pion.PeerConnectionState:
+			if msg == pion.PeerConnectionStateClosed {
+				stream.RemoveProducer(prod)
+
+				whipMu.Lock()
+				delete(whipSessions, id)
+				whipMu.Unlock()
+			}
+		}
+	})
+
+	if err = prod.SetOffer(string(offer)); err != nil {
+		log.Warn().Err(err).Caller().Send()
+		_ = prod.Close()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if IsConsumer(prod) {
+		_ = prod.Close()
+		http.Error(w, "webrtc: WHIP offer without media to publish", http.StatusBadRequest)
+		return
+	}
+
+	answer, err := prod.GetCompleteAnswer(GetCandidates(), FilterCandidate)
+	if err != nil {
+		log.Warn().Err(err).Caller().Send()
+		_ = prod.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Trace().Msgf("[webrtc] WHIP answer\n%s", answer)
+
+	session := &whipSession{
+		conn:  prod,
+		dst:   dst,
+		etag:  `"` + generateSessionID() + `"`,
+		ufrag: iceUfrag(string(offer)),
+	}
+
+	whipMu.Lock()
+	whipSessions[id] = session
+	whipMu.Unlock()
+
+	stream.AddProducer(prod)
+
+	header := w.Header()
+	header.Set("Content-Type", MimeSDP)
+	header.Set("Location", "whip?dst="+url.QueryEscape(dst)+"&id="+id)
+	header.Set("ETag", session.etag)
+	header.Set("Accept-Patch", MimeTrickleICE)
+	setICELinks(header)
+	w.WriteHeader(http.StatusCreated)
+
+	if _, err = w.Write([]byte(answer)); err != nil {
+		log.Warn().Err(err).Caller().Send()
+	}
+}
+
+// whipPatch - trickle ICE candidates from the client, ICE restarts are not supported
+func whipPatch(w http.ResponseWriter, r *http.Request, session *whipSession) {
+	if mediaType(r) != MimeTrickleICE {
+		http.Error(w, "", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if etag := r.Header.Get("If-Match"); etag != "" && etag != "*" && etag != session.etag {
+		http.Error(w, "", http.StatusPreconditionFailed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	frag := string(body)
+
+	log.Trace().Msgf("[webrtc] WHIP candidates\n%s", frag)
+
+	if ufrag := iceUfrag(frag); ufrag != "" && ufrag != session.ufrag {
+		http.Error(w, "webrtc: ICE restart not supported", http.StatusNotImplemented)
+		return
+	}
+
+	for _, line := range strings.Split(frag, "\n") {
+		candidate, ok := strings.CutPrefix(strings.TrimSpace(line), "a=candidate:")
+		if !ok {
+			continue
+		}
+		if err = session.conn.AddCandidate("candidate:" + candidate); err != nil {
+			log.Warn().Err(err).Caller().Send()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWHIPSession - the session can be accessed only with the same dst,
+// because the api module checks publish permission by the dst param
+func getWHIPSession(id, dst string) *whipSession {
+	whipMu.Lock()
+	defer whipMu.Unlock()
+
+	if session, ok := whipSessions[id]; ok && session.dst == dst {
+		return session
+	}
+	return nil
+}
+
+func mediaType(r *http.Request) string {
+	s, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func iceUfrag(sdp string) string {
+	for _, line := range strings.Split(sdp, "\n") {
+		if s, ok := strings.CutPrefix(strings.TrimSpace(line), "a=ice-ufrag:"); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/api/ws"
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/auth"
 
 	"github.com/AlexxIT/go2rtc/internal/bubble"
 	"github.com/AlexxIT/go2rtc/internal/debug"
@@ -12,6 +13,7 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/dvrip"
 	"github.com/AlexxIT/go2rtc/internal/echo"
 	"github.com/AlexxIT/go2rtc/internal/eseecloud"
+	"github.com/AlexxIT/go2rtc/internal/events"
 	"github.com/AlexxIT/go2rtc/internal/exec"
 	"github.com/AlexxIT/go2rtc/internal/expr"
 	"github.com/AlexxIT/go2rtc/internal/ffmpeg"
@@ -26,13 +28,17 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/mjpeg"
 	"github.com/AlexxIT/go2rtc/internal/mp4"
 	"github.com/AlexxIT/go2rtc/internal/mpegts"
+	"github.com/AlexxIT/go2rtc/internal/mqtt"
 	"github.com/AlexxIT/go2rtc/internal/nest"
 	"github.com/AlexxIT/go2rtc/internal/ngrok"
 	"github.com/AlexxIT/go2rtc/internal/onvif"
+	"github.com/AlexxIT/go2rtc/internal/record"
 	"github.com/AlexxIT/go2rtc/internal/ring"
 	"github.com/AlexxIT/go2rtc/internal/roborock"
 	"github.com/AlexxIT/go2rtc/internal/rtmp"
 	"github.com/AlexxIT/go2rtc/internal/rtsp"
+	"github.com/AlexxIT/go2rtc/internal/sei"
+	"github.com/AlexxIT/go2rtc/internal/srt"
 	"github.com/AlexxIT/go2rtc/internal/srtp"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/internal/tapo"
@@ -51,6 +57,8 @@ func main() {
 
 	app.Init() // init config and logs
 
+	auth.Init() // init users and ACL before all servers
+
 	api.Init() // init API before all others
 	ws.Init()  // init WS API endpoint
 
@@ -66,6 +74,7 @@ func main() {
 	mp4.Init()   // MP4 API
 	hls.Init()   // HLS API
 	mjpeg.Init() // MJPEG API
+	sei.Init()   // SEI metadata API
 
 	// 4. Other sources and servers
 
@@ -77,6 +86,7 @@ func main() {
 	// 5. Other sources
 
 	rtmp.Init()     // rtmp source
+	srt.Init()      // srt source, SRT server
 	exec.Init()     // exec source
 	ffmpeg.Init()   // ffmpeg source
 	echo.Init()     // echo source
@@ -102,9 +112,12 @@ func main() {
 
 	// 6. Helper modules
 
-	ngrok.Init() // ngrok module
-	srtp.Init()  // SRTP server
-	debug.Init() // debug API
+	ngrok.Init()  // ngrok module
+	srtp.Init()   // SRTP server
+	debug.Init()  // debug API
+	record.Init() // recording module
+	events.Init() // events and webhooks
+	mqtt.Init()   // mqtt publisher
 
 	// 7. Go
 
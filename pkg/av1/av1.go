@@ -0,0 +1,367 @@
+// Package av1 - low overhead bitstream format (OBUs with size field) related functions
+package av1
+
+import (
+	"fmt"
+
+	"github.com/AlexxIT/go2rtc/pkg/bits"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// https://aomediacodec.github.io/av1-spec/
+
+const (
+	OBUTypeSequenceHeader       = 1
+	OBUTypeTemporalDelimiter    = 2
+	OBUTypeFrameHeader          = 3
+	OBUTypeTileGroup            = 4
+	OBUTypeMetadata             = 5
+	OBUTypeFrame                = 6
+	OBUTypeRedundantFrameHeader = 7
+	OBUTypeTileList             = 8
+	OBUTypePadding              = 15
+)
+
+func OBUType(b []byte) byte {
+	return (b[0] >> 3) & 0b1111
+}
+
+// ReadLEB128 - value and its size in bytes, zero size for wrong data
+func ReadLEB128(b []byte) (v uint64, n int) {
+	for n < 8 && n < len(b) {
+		v |= uint64(b[n]&0x7F) << (7 * n)
+		if b[n]&0x80 == 0 {
+			return v, n + 1
+		}
+		n++
+	}
+	return 0, 0
+}
+
+func AppendLEB128(b []byte, v uint64) []byte {
+	for {
+		if v < 0x80 {
+			return append(b, byte(v))
+		}
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+}
+
+// SplitOBUs - split temporal unit to the OBUs (with headers), each OBU should have size field
+func SplitOBUs(data []byte) (obus [][]byte) {
+	for len(data) > 0 {
+		i := 1
+		if data[0]&0b100 != 0 {
+			i++ // extension header
+		}
+
+		if data[0]&0b10 == 0 || i > len(data) {
+			return append(obus, data) // OBU without size up to the end
+		}
+
+		size, n := ReadLEB128(data[i:])
+		if n == 0 || i+n+int(size) > len(data) {
+			return
+		}
+
+		i += n + int(size)
+		obus = append(obus, data[:i])
+		data = data[i:]
+	}
+	return
+}
+
+// OBUPayload - OBU without header and size
+func OBUPayload(obu []byte) []byte {
+	i := 1
+	if obu[0]&0b100 != 0 {
+		i++
+	}
+	if obu[0]&0b10 != 0 {
+		_, n := ReadLEB128(obu[i:])
+		i += n
+	}
+	return obu[i:]
+}
+
+// GetSequenceHeader - sequence header OBU from the temporal unit
+func GetSequenceHeader(data []byte) []byte {
+	for _, obu := range SplitOBUs(data) {
+		if OBUType(obu) == OBUTypeSequenceHeader {
+			return obu
+		}
+	}
+	return nil
+}
+
+// IsKeyframe - encoders send sequence header with each key frame
+func IsKeyframe(data []byte) bool {
+	return GetSequenceHeader(data) != nil
+}
+
+type SequenceHeader struct {
+	Profile              byte
+	LevelIdx             byte // of the first operating point
+	Tier                 byte
+	HighBitdepth         bool
+	TwelveBit            bool
+	Monochrome           bool
+	SubsamplingX         byte
+	SubsamplingY         byte
+	ChromaSamplePosition byte
+
+	MaxFrameWidth  uint32
+	MaxFrameHeight uint32
+}
+
+// DecodeSequenceHeader - parse sequence header OBU (with OBU header)
+func DecodeSequenceHeader(obu []byte) *SequenceHeader {
+	if len(obu) < 2 {
+		return nil
+	}
+
+	r := bits.NewReader(OBUPayload(obu))
+
+	s := &SequenceHeader{}
+	s.Profile = r.ReadBits8(3)
+	_ = r.ReadBit() // still_picture
+
+	if reduced := r.ReadBit(); reduced != 0 {
+		s.LevelIdx = r.ReadBits8(5)
+		s.readFrameSize(r)
+		_ = r.ReadBits8(3) // use_128x128_superblock, enable_filter_intra, enable_intra_edge_filter
+	} else {
+		var decoderModel bool
+		var bufferDelayLength byte
+
+		if timingInfo := r.ReadBit(); timingInfo != 0 {
+			_ = r.ReadUint32() // num_units_in_display_tick
+			_ = r.ReadUint32() // time_scale
+			if equalPictureInterval := r.ReadBit(); equalPictureInterval != 0 {
+				readUVLC(r) // num_ticks_per_picture_minus_1
+			}
+
+			if decoderModel = r.ReadBit() != 0; decoderModel {
+				bufferDelayLength = r.ReadBits8(5) + 1
+				_ = r.ReadUint32()  // num_units_in_decoding_tick
+				_ = r.ReadBits8(10) // buffer_removal_time_length_minus_1, frame_presentation_time_length_minus_1
+			}
+		}
+
+		initialDisplayDelay := r.ReadBit() != 0
+
+		operatingPoints := r.ReadBits8(5) + 1
+		for i := byte(0); i < operatingPoints; i++ {
+			_ = r.ReadBits16(12) // operating_point_idc
+			levelIdx := r.ReadBits8(5)
+			var tier byte
+			if levelIdx > 7 {
+				tier = r.ReadBit()
+			}
+			if i == 0 {
+				s.LevelIdx, s.Tier = levelIdx, tier
+			}
+			if decoderModel {
+				if present := r.ReadBit(); present != 0 {
+					_ = r.ReadBits64(bufferDelayLength) // decoder_buffer_delay
+					_ = r.ReadBits64(bufferDelayLength) // encoder_buffer_delay
+					_ = r.ReadBit()                     // low_delay_mode_flag
+				}
+			}
+			if initialDisplayDelay {
+				if present := r.ReadBit(); present != 0 {
+					_ = r.ReadBits8(4) // initial_display_delay_minus_1
+				}
+			}
+		}
+
+		s.readFrameSize(r)
+
+		if frameIDNumbers := r.ReadBit(); frameIDNumbers != 0 {
+			_ = r.ReadBits8(7) // delta_frame_id_length_minus_2, additional_frame_id_length_minus_1
+		}
+
+		_ = r.ReadBits8(3) // use_128x128_superblock, enable_filter_intra, enable_intra_edge_filter
+		_ = r.ReadBits8(4) // enable_interintra_compound, enable_masked_compound, enable_warped_motion, enable_dual_filter
+
+		orderHint := r.ReadBit() != 0
+		if orderHint {
+			_ = r.ReadBits8(2) // enable_jnt_comp, enable_ref_frame_mvs
+		}
+
+		screenContentTools := byte(2) // SELECT_SCREEN_CONTENT_TOOLS
+		if choose := r.ReadBit(); choose == 0 {
+			screenContentTools = r.ReadBit()
+		}
+		if screenContentTools > 0 {
+			if choose := r.ReadBit(); choose == 0 {
+				_ = r.ReadBit() // seq_force_integer_mv
+			}
+		}
+
+		if orderHint {
+			_ = r.ReadBits8(3) // order_hint_bits_minus_1
+		}
+	}
+
+	_ = r.ReadBits8(3) // enable_superres, enable_cdef, enable_restoration
+
+	s.readColorConfig(r)
+
+	if r.EOF {
+		return nil
+	}
+
+	return s
+}
+
+func (s *SequenceHeader) readFrameSize(r *bits.Reader) {
+	widthBits := r.ReadBits8(4) + 1
+	heightBits := r.ReadBits8(4) + 1
+	s.MaxFrameWidth = r.ReadBits(widthBits) + 1
+	s.MaxFrameHeight = r.ReadBits(heightBits) + 1
+}
+
+func (s *SequenceHeader) readColorConfig(r *bits.Reader) {
+	s.HighBitdepth = r.ReadBit() != 0
+	if s.Profile == 2 && s.HighBitdepth {
+		s.TwelveBit = r.ReadBit() != 0
+	}
+	if s.Profile != 1 {
+		s.Monochrome = r.ReadBit() != 0
+	}
+
+	primaries, transfer, matrix := byte(2), byte(2), byte(2) // unspecified
+	if colorDescription := r.ReadBit(); colorDescription != 0 {
+		primaries, transfer, matrix = r.ReadByte(), r.ReadByte(), r.ReadByte()
+	}
+
+	switch {
+	case s.Monochrome:
+		_ = r.ReadBit() // color_range
+		s.SubsamplingX, s.SubsamplingY = 1, 1
+		return
+	case primaries == 1 && transfer == 13 && matrix == 0: // BT709, sRGB, identity
+		return // 4:4:4
+	}
+
+	_ = r.ReadBit() // color_range
+
+	switch s.Profile {
+	case 0:
+		s.SubsamplingX, s.SubsamplingY = 1, 1
+	case 1:
+		// 4:4:4
+	default:
+		if s.TwelveBit {
+			if s.SubsamplingX = r.ReadBit(); s.SubsamplingX != 0 {
+				s.SubsamplingY = r.ReadBit()
+			}
+		} else {
+			s.SubsamplingX = 1
+		}
+	}
+
+	if s.SubsamplingX != 0 && s.SubsamplingY != 0 {
+		s.ChromaSamplePosition = r.ReadBits8(2)
+	}
+}
+
+func readUVLC(r *bits.Reader) {
+	var leadingZeros byte
+	for leadingZeros < 32 && r.ReadBit() == 0 && !r.EOF {
+		leadingZeros++
+	}
+	_ = r.ReadBits(leadingZeros)
+}
+
+// EncodeConfig - AV1CodecConfigurationRecord (av1C) with sequence header OBU
+func EncodeConfig(obu []byte) []byte {
+	s := DecodeSequenceHeader(obu)
+	if s == nil {
+		return nil
+	}
+
+	b := []byte{
+		0x81, // marker + version
+		s.Profile<<5 | s.LevelIdx,
+		s.Tier<<7 | btoi(s.HighBitdepth)<<6 | btoi(s.TwelveBit)<<5 | btoi(s.Monochrome)<<4 |
+			s.SubsamplingX<<3 | s.SubsamplingY<<2 | s.ChromaSamplePosition,
+		0, // no initial_presentation_delay
+	}
+	return append(b, obu...)
+}
+
+// DecodeConfig - sequence header OBU from av1C
+func DecodeConfig(conf []byte) []byte {
+	if len(conf) < 4 || conf[0] != 0x81 {
+		return nil
+	}
+	return GetSequenceHeader(conf[4:])
+}
+
+func ConfigToCodec(conf []byte) *core.Codec {
+	codec := &core.Codec{
+		Name:        core.CodecAV1,
+		ClockRate:   90000,
+		PayloadType: core.PayloadTypeRAW,
+	}
+	if len(conf) >= 4 {
+		codec.FmtpLine = fmt.Sprintf("profile=%d;level-idx=%d;tier=%d", conf[1]>>5, conf[1]&0x1F, conf[2]>>7)
+	}
+	return codec
+}
+
+// FmtpToConfig - av1C from SDP fmtp without sequence header OBU,
+// decoders will get it in-band with the keyframes
+func FmtpToConfig(fmtp string) []byte {
+	profile, level, tier := parseFmtp(fmtp)
+
+	var subsampling byte
+	switch profile {
+	case 0:
+		subsampling = 0b1100 // 4:2:0
+	case 2:
+		subsampling = 0b1000 // 4:2:2
+	}
+
+	return []byte{0x81, profile<<5 | level, tier<<7 | subsampling, 0}
+}
+
+// GetProfileLevelID - codec string for MIME type (P.LLT.DD) from SDP fmtp,
+// 8 bit depth because it's unknown from fmtp
+func GetProfileLevelID(fmtp string) string {
+	profile, level, tier := parseFmtp(fmtp)
+
+	s := fmt.Sprintf("%d.%02d", profile, level)
+	if tier != 0 {
+		s += "H.08"
+	} else {
+		s += "M.08"
+	}
+	return s
+}
+
+// parseFmtp - default values from RTP spec: main profile, level 3.1, main tier
+func parseFmtp(fmtp string) (profile, level, tier byte) {
+	profile, level = 0, 5
+
+	if s := core.Between(fmtp, "profile=", ";"); s != "" {
+		profile = byte(core.Atoi(s)) & 0b111
+	}
+	if s := core.Between(fmtp, "level-idx=", ";"); s != "" {
+		level = byte(core.Atoi(s)) & 0b11111
+	}
+	if s := core.Between(fmtp, "tier=", ";"); s != "" {
+		tier = byte(core.Atoi(s)) & 1
+	}
+	return
+}
+
+func btoi(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package av1
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeConfig(t *testing.T) {
+	// temporal delimiter + sequence header 1280x720 from libaom
+	tu, _ := hex.DecodeString("12000a0b0000002d4cffb3dfff9804")
+
+	obu := GetSequenceHeader(tu)
+	require.Equal(t, "0a0b0000002d4cffb3dfff9804", hex.EncodeToString(obu))
+	require.True(t, IsKeyframe(tu))
+
+	s := DecodeSequenceHeader(obu)
+	require.NotNil(t, s)
+	require.Equal(t, uint32(1280), s.MaxFrameWidth)
+	require.Equal(t, uint32(720), s.MaxFrameHeight)
+
+	conf := EncodeConfig(obu)
+	require.Equal(t, "81050c00", hex.EncodeToString(conf[:4]))
+	require.Equal(t, obu, DecodeConfig(conf))
+
+	codec := ConfigToCodec(conf)
+	require.Equal(t, "profile=0;level-idx=5;tier=0", codec.FmtpLine)
+}
+
+func TestRTP(t *testing.T) {
+	// temporal delimiter + sequence header + big frame OBU
+	tu, _ := hex.DecodeString("12000a0b0000002d4cffb3dfff9804")
+	frame := make([]byte, 3000)
+	tu = append(tu, 0x32) // frame OBU with size
+	tu = AppendLEB128(tu, uint64(len(frame)))
+	tu = append(tu, frame...)
+
+	var packets []*rtp.Packet
+	pay := RTPPay(1200, func(packet *rtp.Packet) {
+		packets = append(packets, packet)
+	})
+	pay(&rtp.Packet{Payload: tu})
+
+	require.Len(t, packets, 3)
+	require.True(t, IsKeyframeRTP(packets[0].Payload))
+	require.True(t, packets[2].Marker)
+
+	var result []byte
+	depay := RTPDepay(func(packet *rtp.Packet) {
+		result = append([]byte{}, packet.Payload...)
+	})
+	for _, packet := range packets {
+		depay(packet)
+	}
+
+	// without temporal delimiter
+	require.Equal(t, tu[2:], result)
+}
@@ -0,0 +1,200 @@
+package av1
+
+import (
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+// https://aomediacodec.github.io/av1-rtp-spec/
+
+const (
+	flagZ = 0x80 // first OBU element is continuation of the previous packet OBU
+	flagY = 0x40 // last OBU element continues in the next packet
+	flagN = 0x08 // first packet of the coded video sequence
+)
+
+// RTPDepay - RTP payload to the temporal unit in low overhead bitstream format
+// (OBUs with size field), without temporal delimiters
+func RTPDepay(handler core.HandlerFunc) core.HandlerFunc {
+	buf := make([]byte, 0, 512*1024) // 512K
+	var obu []byte                   // OBU fragment from the previous packets
+
+	return func(packet *rtp.Packet) {
+		b := packet.Payload
+		if len(b) < 2 {
+			return
+		}
+
+		header := b[0]
+		b = b[1:]
+
+		count := int(header>>4) & 0b11 // zero - all elements with size
+
+		for i := 1; len(b) > 0; i++ {
+			size := len(b)
+			if count == 0 || i < count {
+				v, n := ReadLEB128(b)
+				if n == 0 || n+int(v) > len(b) {
+					buf, obu = buf[:0], obu[:0] // broken packet
+					return
+				}
+				size = int(v)
+				b = b[n:]
+			}
+
+			element := b[:size]
+			b = b[size:]
+
+			if i == 1 && header&flagZ != 0 {
+				if len(obu) == 0 {
+					continue // lost start of the OBU
+				}
+				obu = append(obu, element...)
+			} else {
+				obu = append(obu[:0], element...)
+			}
+
+			if len(b) == 0 && header&flagY != 0 {
+				break // OBU continues in the next packet
+			}
+
+			buf = appendOBU(buf, obu)
+			obu = obu[:0]
+		}
+
+		if !packet.Marker || len(buf) == 0 {
+			return
+		}
+
+		clone := *packet
+		clone.Payload = buf
+
+		buf = buf[:0]
+
+		handler(&clone)
+	}
+}
+
+// appendOBU - append OBU with size field, skip OBUs that should be removed
+func appendOBU(b, obu []byte) []byte {
+	if len(obu) == 0 {
+		return b
+	}
+
+	switch OBUType(obu) {
+	case OBUTypeTemporalDelimiter, OBUTypeTileList, OBUTypePadding:
+		return b
+	}
+
+	if obu[0]&0b10 != 0 {
+		return append(b, obu...) // already with size field
+	}
+
+	i := 1
+	if obu[0]&0b100 != 0 {
+		i++ // extension header
+	}
+	if i > len(obu) {
+		return b
+	}
+
+	b = append(b, obu[0]|0b10)
+	b = append(b, obu[1:i]...)
+	b = AppendLEB128(b, uint64(len(obu)-i))
+	return append(b, obu[i:]...)
+}
+
+// RTPPay - temporal unit in low overhead bitstream format to RTP packets
+func RTPPay(mtu uint16, handler core.HandlerFunc) core.HandlerFunc {
+	if mtu == 0 {
+		mtu = 1472
+	}
+
+	sequencer := rtp.NewRandomSequencer()
+	mtu -= 12 // rtp.Header size
+
+	return func(packet *rtp.Packet) {
+		payloads := Payload(int(mtu), packet.Payload)
+		last := len(payloads) - 1
+		for i, payload := range payloads {
+			clone := rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					Marker:         i == last,
+					SequenceNumber: sequencer.NextSequenceNumber(),
+					Timestamp:      packet.Timestamp,
+				},
+				Payload: payload,
+			}
+			handler(&clone)
+		}
+	}
+}
+
+// Payload - split temporal unit to RTP payloads, each OBU element with size
+func Payload(mtu int, data []byte) (payloads [][]byte) {
+	b := make([]byte, 1, mtu)
+	if IsKeyframe(data) {
+		b[0] = flagN
+	}
+
+	for _, obu := range SplitOBUs(data) {
+		switch OBUType(obu) {
+		case OBUTypeTemporalDelimiter, OBUTypeTileList, OBUTypePadding:
+			continue
+		}
+
+		obu = withoutSize(obu)
+
+		for len(obu) > 0 {
+			free := mtu - len(b) - 2 // two bytes LEB128 enough for the MTU
+			if free <= 0 {
+				payloads = append(payloads, b)
+				b = make([]byte, 1, mtu)
+				continue
+			}
+
+			n := min(free, len(obu))
+			b = AppendLEB128(b, uint64(n))
+			b = append(b, obu[:n]...)
+			obu = obu[n:]
+
+			if len(obu) > 0 {
+				b[0] |= flagY
+				payloads = append(payloads, b)
+				b = make([]byte, 1, mtu)
+				b[0] = flagZ
+			}
+		}
+	}
+
+	if len(b) > 1 {
+		payloads = append(payloads, b)
+	}
+
+	return
+}
+
+// withoutSize - OBU without size field, as recommended for RTP
+func withoutSize(obu []byte) []byte {
+	if obu[0]&0b10 == 0 {
+		return obu
+	}
+
+	i := 1
+	if obu[0]&0b100 != 0 {
+		i++
+	}
+
+	_, n := ReadLEB128(obu[i:])
+
+	b := make([]byte, 0, len(obu)-n)
+	b = append(b, obu[0]&^0b10)
+	b = append(b, obu[1:i]...)
+	return append(b, obu[i+n:]...)
+}
+
+// IsKeyframeRTP - first packet of the coded video sequence
+func IsKeyframeRTP(payload []byte) bool {
+	return len(payload) > 0 && payload[0]&flagN != 0
+}
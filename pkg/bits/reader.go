@@ -50,6 +50,13 @@ func (r *Reader) ReadUint32() uint32 {
 	return uint32(r.ReadByte())<<24 | uint32(r.ReadByte())<<16 | uint32(r.ReadByte())<<8 | uint32(r.ReadByte())
 }
 
+func (r *Reader) ReadUint64() uint64 {
+	if r.bits != 0 {
+		return r.ReadBits64(64)
+	}
+	return uint64(r.ReadUint32())<<32 | uint64(r.ReadUint32())
+}
+
 func (r *Reader) ReadBit() byte {
 	if r.bits == 0 {
 		r.byte = r.ReadByte()
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"slices"
 	"sync"
 
 	"github.com/pion/rtp"
@@ -31,6 +32,8 @@ type Node struct {
 	childs []*Node
 	parent *Node
 
+	keyframe func()
+
 	mu sync.Mutex
 }
 
@@ -44,7 +47,7 @@ func (n *Node) AppendChild(child *Node) {
 	n.childs = append(n.childs, child)
 	n.mu.Unlock()
 
-	child.parent = n
+	child.setParent(n)
 }
 
 func (n *Node) RemoveChild(child *Node) {
@@ -58,20 +61,84 @@ func (n *Node) RemoveChild(child *Node) {
 	n.mu.Unlock()
 }
 
+// replaceChild - replace child in place, so the parent Input loop doesn't skip other childs.
+// Child is removed from the old parent, it's appended if the old child is already removed.
+func (n *Node) replaceChild(old, child *Node) {
+	n.mu.Lock()
+	i := slices.Index(n.childs, old)
+	if i >= 0 {
+		n.childs[i] = child
+	} else {
+		n.childs = append(n.childs, child)
+	}
+	n.mu.Unlock()
+
+	if parent := child.swapParent(n); parent != nil && parent != n {
+		parent.RemoveChild(child)
+	}
+}
+
+// moveChild - append child and remove it from the old parent
+func (n *Node) moveChild(child *Node) {
+	n.replaceChild(nil, child)
+}
+
+func (n *Node) getParent() *Node {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.parent
+}
+
+func (n *Node) setParent(parent *Node) {
+	n.mu.Lock()
+	n.parent = parent
+	n.mu.Unlock()
+}
+
+// swapParent - set the new parent and return the old one
+func (n *Node) swapParent(parent *Node) (old *Node) {
+	n.mu.Lock()
+	old, n.parent = n.parent, parent
+	n.mu.Unlock()
+	return
+}
+
+func (n *Node) getChilds() []*Node {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Node(nil), n.childs...)
+}
+
+// OnKeyframeRequest - set handler for keyframe requests from the childs (ex. RTCP PLI for the source)
+func (n *Node) OnKeyframeRequest(f func()) {
+	n.keyframe = f
+}
+
+// RequestKeyframe - pass request to the nearest parent with the keyframe handler
+func (n *Node) RequestKeyframe() {
+	for node := n; node != nil; node = node.getParent() {
+		if node.keyframe != nil {
+			node.keyframe()
+			return
+		}
+	}
+}
+
 func (n *Node) Close() {
-	if parent := n.parent; parent != nil {
+	if parent := n.getParent(); parent != nil {
 		parent.RemoveChild(n)
 
-		if len(parent.childs) == 0 {
+		if len(parent.getChilds()) == 0 {
 			parent.Close()
 		}
 	} else {
-		for _, childs := range n.childs {
+		for _, childs := range n.getChilds() {
 			childs.Close()
 		}
 	}
 }
 
+// MoveNode - append all childs of src to dst, dst may already have own childs
 func MoveNode(dst, src *Node) {
 	src.mu.Lock()
 	childs := src.childs
@@ -79,10 +146,10 @@ func MoveNode(dst, src *Node) {
 	src.mu.Unlock()
 
 	dst.mu.Lock()
-	dst.childs = childs
+	dst.childs = append(dst.childs, childs...)
 	dst.mu.Unlock()
 
 	for _, child := range childs {
-		child.parent = dst
+		child.setParent(dst)
 	}
 }
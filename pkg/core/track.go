@@ -41,9 +41,21 @@ func (r *Receiver) WriteRTP(packet *rtp.Packet) {
 	r.Input(packet)
 }
 
+// Replay - pass packet to childs output, bypassing their buffers.
+// Useful for sending many packets from the past at once.
+func (r *Receiver) Replay(packet *Packet) {
+	for _, child := range r.getChilds() {
+		if child.Output != nil {
+			child.Output(packet)
+		} else {
+			child.Input(packet)
+		}
+	}
+}
+
 // Deprecated: should be removed
 func (r *Receiver) Senders() []*Sender {
-	if len(r.childs) > 0 {
+	if len(r.getChilds()) > 0 {
 		return []*Sender{{}}
 	} else {
 		return nil
@@ -73,6 +85,8 @@ type Sender struct {
 
 	buf  chan *Packet
 	done chan struct{}
+
+	gate *Node // waits for keyframe from the new parent
 }
 
 func NewSender(media *Media, codec *Codec) *Sender {
@@ -164,6 +178,66 @@ func (s *Sender) State() string {
 	return "connected"
 }
 
+// ParentCodec - codec of the current sender source
+func (s *Sender) ParentCodec() *Codec {
+	if parent := s.getParent(); parent != nil {
+		return parent.Codec
+	}
+	return nil
+}
+
+// Switch - move sender to another receiver without stopping the consumer.
+// With isKeyframe, sender stays on the old receiver until the first keyframe of the new one.
+func (s *Sender) Switch(to *Receiver, isKeyframe func(packet *Packet) bool) {
+	s.mu.Lock()
+	s.removeGate()
+	s.mu.Unlock()
+
+	if s.getParent() == &to.Node {
+		return
+	}
+
+	if isKeyframe == nil {
+		to.moveChild(&s.Node)
+		return
+	}
+
+	gate := &Node{id: NewID(), Codec: to.Codec}
+	gate.Input = func(packet *Packet) {
+		if !isKeyframe(packet) {
+			return
+		}
+
+		s.mu.Lock()
+		if s.gate != gate {
+			s.mu.Unlock()
+			return
+		}
+		s.gate = nil
+		s.mu.Unlock()
+
+		to.replaceChild(gate, &s.Node)
+
+		s.Input(packet)
+	}
+
+	s.mu.Lock()
+	s.gate = gate
+	s.mu.Unlock()
+
+	to.AppendChild(gate)
+	to.RequestKeyframe()
+}
+
+func (s *Sender) removeGate() {
+	if gate := s.gate; gate != nil {
+		if parent := gate.getParent(); parent != nil {
+			parent.RemoveChild(gate)
+		}
+		s.gate = nil
+	}
+}
+
 func (s *Sender) Close() {
 	// close buffer if exists
 	s.mu.Lock()
@@ -171,6 +245,7 @@ func (s *Sender) Close() {
 		close(s.buf) // exit from for range loop
 		s.buf = nil  // prevent writing to closed chan
 	}
+	s.removeGate()
 	s.mu.Unlock()
 
 	s.Node.Close()
@@ -189,7 +264,7 @@ func (r *Receiver) MarshalJSON() ([]byte, error) {
 		Bytes:   r.Bytes,
 		Packets: r.Packets,
 	}
-	for _, child := range r.childs {
+	for _, child := range r.getChilds() {
 		v.Childs = append(v.Childs, child.id)
 	}
 	return json.Marshal(v)
@@ -210,8 +285,8 @@ func (s *Sender) MarshalJSON() ([]byte, error) {
 		Packets: s.Packets,
 		Drops:   s.Drops,
 	}
-	if s.parent != nil {
-		v.Parent = s.parent.id
+	if parent := s.getParent(); parent != nil {
+		v.Parent = parent.id
 	}
 	return json.Marshal(v)
 }
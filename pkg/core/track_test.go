@@ -3,6 +3,7 @@ package core
 import (
 	"testing"
 
+	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -51,3 +52,55 @@ func TestSenser(t *testing.T) {
 	}
 	require.False(t, ok)
 }
+
+func TestSenderSwitch(t *testing.T) {
+	codec := &Codec{Name: CodecH264}
+	main := NewReceiver(nil, codec)
+	sub := NewReceiver(nil, codec)
+
+	var requests int
+	sub.OnKeyframeRequest(func() {
+		requests++
+	})
+
+	sender := NewSender(nil, codec)
+	sender.WithParent(main)
+
+	isKeyframe := func(packet *Packet) bool {
+		return packet.Marker
+	}
+
+	sender.Switch(sub, isKeyframe)
+	require.Equal(t, 1, requests)
+
+	// stay on the main until the keyframe from the sub
+	main.Input(&Packet{})
+	sub.Input(&Packet{})
+	require.Equal(t, 1, sender.Packets)
+	require.Equal(t, codec, sender.ParentCodec())
+
+	sub.Input(&Packet{Header: rtp.Header{Marker: true}})
+	main.Input(&Packet{})
+	require.Equal(t, 2, sender.Packets)
+	require.Len(t, main.Senders(), 0)
+	require.Len(t, sub.childs, 1)
+
+	sub.Input(&Packet{})
+	require.Equal(t, 3, sender.Packets)
+}
+
+func TestReceiverReplace(t *testing.T) {
+	codec := &Codec{}
+	from := NewReceiver(nil, codec)
+	to := NewReceiver(nil, codec)
+
+	moved := NewSender(nil, codec).WithParent(from)
+	own := NewSender(nil, codec).WithParent(to)
+
+	// childs of the target should stay in place
+	from.Replace(to)
+	require.Len(t, to.childs, 2)
+	require.Len(t, from.childs, 0)
+	require.Equal(t, &to.Node, moved.parent)
+	require.Equal(t, &to.Node, own.parent)
+}
@@ -11,9 +11,10 @@ const (
 	TypeBoolean
 	TypeString
 	TypeObject
-	TypeNull      = 5
-	TypeEcmaArray = 8
-	TypeObjectEnd = 9
+	TypeNull        = 5
+	TypeEcmaArray   = 8
+	TypeObjectEnd   = 9
+	TypeStrictArray = 10
 )
 
 // AMF spec: http://download.macromedia.com/pub/labs/amf/amf0_spec_121207.pdf
@@ -68,6 +69,9 @@ func (a *AMF) ReadItem() (any, error) {
 
 	case TypeObjectEnd:
 		return nil, nil
+
+	case TypeStrictArray:
+		return a.ReadStrictArray()
 	}
 
 	return nil, ErrRead
@@ -144,6 +148,26 @@ func (a *AMF) ReadEcmaArray() (map[string]any, error) {
 	return a.ReadObject()
 }
 
+func (a *AMF) ReadStrictArray() ([]any, error) {
+	if a.pos+4 > len(a.buf) {
+		return nil, ErrRead
+	}
+
+	n := int(binary.BigEndian.Uint32(a.buf[a.pos:]))
+	a.pos += 4
+
+	var items []any
+	for i := 0; i < n; i++ {
+		v, err := a.ReadItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+
+	return items, nil
+}
+
 func NewWriter() *AMF {
 	return &AMF{}
 }
@@ -188,6 +212,12 @@ func (a *AMF) WriteEcmaArray(obj map[string]any) {
 	a.buf = append(a.buf, 0, 0, TypeObjectEnd)
 }
 
+func (a *AMF) WriteStrictArray(items []any) {
+	n := len(items)
+	a.buf = append(a.buf, TypeStrictArray, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	a.buf = append(a.buf, EncodeItems(items...)...)
+}
+
 func (a *AMF) writeKV(obj map[string]any) {
 	for k, v := range obj {
 		n := len(k)
@@ -207,6 +237,8 @@ func (a *AMF) writeKV(obj map[string]any) {
 			a.WriteNumber(v)
 		case bool:
 			a.WriteBool(v)
+		case []any:
+			a.WriteStrictArray(v)
 		default:
 			panic(v)
 		}
@@ -6,6 +6,7 @@ import (
 	"github.com/AlexxIT/go2rtc/pkg/aac"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
 	"github.com/pion/rtp"
 )
 
@@ -22,6 +23,8 @@ func NewConsumer() *Consumer {
 			Direction: core.DirectionSendonly,
 			Codecs: []*core.Codec{
 				{Name: core.CodecH264},
+				{Name: core.CodecH265},
+				{Name: core.CodecAV1},
 			},
 		},
 		{
@@ -29,6 +32,7 @@ func NewConsumer() *Consumer {
 			Direction: core.DirectionSendonly,
 			Codecs: []*core.Codec{
 				{Name: core.CodecAAC},
+				{Name: core.CodecOpus},
 			},
 		},
 	}
@@ -65,6 +69,41 @@ func (c *Consumer) AddTrack(media *core.Media, codec *core.Codec, track *core.Re
 			sender.Handler = h264.RepairAVCC(track.Codec, sender.Handler)
 		}
 
+	case core.CodecH265:
+		payload := c.muxer.GetPayloader(track.Codec)
+
+		sender.Handler = func(pkt *rtp.Packet) {
+			// frames before the first keyframe are skipped
+			if b := payload(pkt); b != nil {
+				if n, err := c.wr.Write(b); err == nil {
+					c.Send += n
+				}
+			}
+		}
+
+		if track.Codec.IsRTP() {
+			sender.Handler = h265.RTPDepay(track.Codec, sender.Handler)
+		} else {
+			sender.Handler = h265.RepairAVCC(track.Codec, sender.Handler)
+		}
+
+	case core.CodecAV1:
+		// only AV1 in low overhead bitstream format (from FLV/MPEG-TS sources)
+		if track.Codec.IsRTP() {
+			return core.ErrCantGetTrack
+		}
+
+		payload := c.muxer.GetPayloader(track.Codec)
+
+		sender.Handler = func(pkt *rtp.Packet) {
+			// frames before the first keyframe are skipped
+			if b := payload(pkt); b != nil {
+				if n, err := c.wr.Write(b); err == nil {
+					c.Send += n
+				}
+			}
+		}
+
 	case core.CodecAAC:
 		payload := c.muxer.GetPayloader(track.Codec)
 
@@ -78,6 +117,16 @@ func (c *Consumer) AddTrack(media *core.Media, codec *core.Codec, track *core.Re
 		if track.Codec.IsRTP() {
 			sender.Handler = aac.RTPDepay(sender.Handler)
 		}
+
+	case core.CodecOpus:
+		payload := c.muxer.GetPayloader(track.Codec)
+
+		sender.Handler = func(pkt *rtp.Packet) {
+			b := payload(pkt)
+			if n, err := c.wr.Write(b); err == nil {
+				c.Send += n
+			}
+		}
 	}
 
 	sender.HandleRTP(track)
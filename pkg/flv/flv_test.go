@@ -0,0 +1,42 @@
+package flv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnhancedRTMP(t *testing.T) {
+	// temporal delimiter + sequence header 1280x720
+	keyframe, _ := hex.DecodeString("12000a0b0000002d4cffb3dfff9804")
+
+	video := &core.Codec{Name: core.CodecAV1, ClockRate: 90000}
+	audio := &core.Codec{Name: core.CodecOpus, ClockRate: 48000, Channels: 2}
+
+	m := &Muxer{}
+	videoPay := m.GetPayloader(video)
+	audioPay := m.GetPayloader(audio)
+
+	// frames before the first keyframe are skipped
+	require.Nil(t, videoPay(&rtp.Packet{Payload: []byte{0x12, 0x00}}))
+
+	buf := bytes.NewBuffer(m.GetInit())
+	buf.Write(audioPay(&rtp.Packet{Header: rtp.Header{Timestamp: 1}, Payload: []byte{0xFC}}))
+	buf.Write(videoPay(&rtp.Packet{Header: rtp.Header{Timestamp: 1}, Payload: keyframe}))
+
+	prod, err := Open(buf)
+	require.Nil(t, err)
+	require.Len(t, prod.Medias, 2)
+
+	codec := prod.Medias[0].Codecs[0]
+	require.Equal(t, core.CodecOpus, codec.Name)
+	require.Equal(t, uint8(2), codec.Channels)
+
+	codec = prod.Medias[1].Codecs[0]
+	require.Equal(t, core.CodecAV1, codec.Name)
+	require.Equal(t, "profile=0;level-idx=5;tier=0", codec.FmtpLine)
+}
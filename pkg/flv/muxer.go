@@ -4,9 +4,11 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 
+	"github.com/AlexxIT/go2rtc/pkg/av1"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/flv/amf"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
 	"github.com/pion/rtp"
 )
 
@@ -36,12 +38,27 @@ func (m *Muxer) GetInit() []byte {
 			b[4] |= FlagsVideo
 			obj["videocodecid"] = CodecAVC
 
+		case core.CodecH265:
+			b[4] |= FlagsVideo
+			obj["videocodecid"] = fourCCNumber(FourCCHEVC)
+
+		case core.CodecAV1:
+			b[4] |= FlagsVideo
+			obj["videocodecid"] = fourCCNumber(FourCCAV1)
+
 		case core.CodecAAC:
 			b[4] |= FlagsAudio
 			obj["audiocodecid"] = CodecAAC
 			obj["audiosamplerate"] = codec.ClockRate
 			obj["audiosamplesize"] = 16
 			obj["stereo"] = codec.Channels == 2
+
+		case core.CodecOpus:
+			b[4] |= FlagsAudio
+			obj["audiocodecid"] = fourCCNumber(FourCCOpus)
+			obj["audiosamplerate"] = codec.ClockRate
+			obj["audiosamplesize"] = 16
+			obj["stereo"] = codec.Channels == 2
 		}
 	}
 
@@ -65,11 +82,22 @@ func (m *Muxer) GetInit() []byte {
 			video := append(encodeAVData(codec, 0), config...)
 			b = append(b, EncodeTag(TagVideo, 0, video)...)
 
+		case core.CodecH265:
+			// otherwise the payloader will send config from the first keyframe
+			if config := h265Config(codec); config != nil {
+				video := append(encodeExVideo(PacketTypeSequenceStart, 1, FourCCHEVC), config...)
+				b = append(b, EncodeTag(TagVideo, 0, video)...)
+			}
+
 		case core.CodecAAC:
 			s := core.Between(codec.FmtpLine, "config=", ";")
 			config, _ := hex.DecodeString(s)
 			audio := append(encodeAVData(codec, 0), config...)
 			b = append(b, EncodeTag(TagAudio, 0, audio)...)
+
+		case core.CodecOpus:
+			audio := append(encodeExAudio(PacketTypeSequenceStart, FourCCOpus), encodeOpusHeader(codec)...)
+			b = append(b, EncodeTag(TagAudio, 0, audio)...)
 		}
 	}
 
@@ -103,6 +131,55 @@ func (m *Muxer) GetPayloader(codec *core.Codec) func(packet *rtp.Packet) []byte
 			return EncodeTag(TagVideo, timeMS, buf)
 		}
 
+	case core.CodecH265:
+		// config from the fmtp line will be sent by GetInit
+		sendConfig := h265Config(codec) == nil
+
+		return func(packet *rtp.Packet) []byte {
+			var b []byte
+
+			if h265.IsKeyframe(packet.Payload) {
+				if sendConfig {
+					config := h265Config(h265.AVCCToCodec(packet.Payload))
+					if config == nil {
+						return nil
+					}
+					sendConfig = false
+					b = append(encodeExVideo(PacketTypeSequenceStart, 1, FourCCHEVC), config...)
+					b = EncodeTag(TagVideo, 0, b)
+				}
+				b = append(b, encodeExTag(packet, &ts0, k, PacketTypeCodedFramesX, 1, FourCCHEVC)...)
+			} else if !sendConfig {
+				b = encodeExTag(packet, &ts0, k, PacketTypeCodedFramesX, 2, FourCCHEVC)
+			}
+
+			return b
+		}
+
+	case core.CodecAV1:
+		sendConfig := true
+
+		return func(packet *rtp.Packet) []byte {
+			var b []byte
+
+			if av1.IsKeyframe(packet.Payload) {
+				if sendConfig {
+					config := av1.EncodeConfig(av1.GetSequenceHeader(packet.Payload))
+					if config == nil {
+						return nil
+					}
+					sendConfig = false
+					b = append(encodeExVideo(PacketTypeSequenceStart, 1, FourCCAV1), config...)
+					b = EncodeTag(TagVideo, 0, b)
+				}
+				b = append(b, encodeExTag(packet, &ts0, k, PacketTypeCodedFrames, 1, FourCCAV1)...)
+			} else if !sendConfig {
+				b = encodeExTag(packet, &ts0, k, PacketTypeCodedFrames, 2, FourCCAV1)
+			}
+
+			return b
+		}
+
 	case core.CodecAAC:
 		buf := encodeAVData(codec, 1)
 
@@ -113,6 +190,20 @@ func (m *Muxer) GetPayloader(codec *core.Codec) func(packet *rtp.Packet) []byte
 				ts0 = packet.Timestamp
 			}
 
+			timeMS := (packet.Timestamp - ts0) / k
+			return EncodeTag(TagAudio, timeMS, buf)
+		}
+
+	case core.CodecOpus:
+		buf := encodeExAudio(PacketTypeCodedFrames, FourCCOpus)
+
+		return func(packet *rtp.Packet) []byte {
+			buf = append(buf[:5], packet.Payload...)
+
+			if ts0 == 0 {
+				ts0 = packet.Timestamp
+			}
+
 			timeMS := (packet.Timestamp - ts0) / k
 			return EncodeTag(TagAudio, timeMS, buf)
 		}
@@ -172,3 +263,50 @@ func encodeAVData(codec *core.Codec, isFrame byte) []byte {
 
 	return nil
 }
+
+// encodeExVideo - Enhanced RTMP video header: frame type 1 - keyframe, 2 - inter frame
+func encodeExVideo(packetType, frameType byte, fourCC string) []byte {
+	return append([]byte{0x80 | frameType<<4 | packetType}, fourCC...)
+}
+
+// encodeExAudio - Enhanced RTMP audio header
+func encodeExAudio(packetType byte, fourCC string) []byte {
+	return append([]byte{CodecExHeader<<4 | packetType}, fourCC...)
+}
+
+func encodeExTag(packet *rtp.Packet, ts0 *uint32, k uint32, packetType, frameType byte, fourCC string) []byte {
+	if *ts0 == 0 {
+		*ts0 = packet.Timestamp
+	}
+
+	timeMS := (packet.Timestamp - *ts0) / k
+	video := append(encodeExVideo(packetType, frameType, fourCC), packet.Payload...)
+	return EncodeTag(TagVideo, timeMS, video)
+}
+
+func fourCCNumber(fourCC string) uint32 {
+	return binary.BigEndian.Uint32([]byte(fourCC))
+}
+
+func h265Config(codec *core.Codec) []byte {
+	vps, sps, pps := h265.GetParameterSet(codec.FmtpLine)
+	if vps == nil || sps == nil || pps == nil {
+		return nil
+	}
+	return h265.EncodeConfig(vps, sps, pps)
+}
+
+// encodeOpusHeader - OpusHead from https://datatracker.ietf.org/doc/html/rfc7845#section-5.1
+func encodeOpusHeader(codec *core.Codec) []byte {
+	channels := codec.Channels
+	if channels == 0 {
+		channels = 2
+	}
+
+	b := []byte("OpusHead")
+	b = append(b, 1, channels)                     // version, channels
+	b = binary.LittleEndian.AppendUint16(b, 0)     // pre-skip
+	b = binary.LittleEndian.AppendUint32(b, 48000) // input sample rate
+	b = binary.LittleEndian.AppendUint16(b, 0)     // output gain
+	return append(b, 0)                            // channel mapping family
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/AlexxIT/go2rtc/pkg/aac"
+	"github.com/AlexxIT/go2rtc/pkg/av1"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
 	"github.com/AlexxIT/go2rtc/pkg/h265"
@@ -19,6 +20,9 @@ type Producer struct {
 	rd *core.ReadBuffer
 
 	video, audio *core.Receiver
+
+	videoFourCC string
+	av1Header   []byte // sequence header OBU from the AV1 config
 }
 
 func Open(rd io.Reader) (*Producer, error) {
@@ -43,8 +47,19 @@ const (
 	TagVideo = 9
 	TagData  = 18
 
-	CodecAAC = 10
-	CodecAVC = 7
+	CodecAAC      = 10
+	CodecAVC      = 7
+	CodecExHeader = 9 // Enhanced RTMP audio with FourCC
+)
+
+// Enhanced RTMP FourCC
+// https://veovera.org/docs/enhanced/enhanced-rtmp-v2
+const (
+	FourCCAVC  = "avc1"
+	FourCCHEVC = "hvc1"
+	FourCCAV1  = "av01"
+	FourCCOpus = "Opus"
+	FourCCAAC  = "mp4a"
 )
 
 const (
@@ -83,12 +98,24 @@ func (c *Producer) Start() error {
 
 		switch pkt.PayloadType {
 		case TagAudio:
-			if c.audio == nil || pkt.Payload[1] == 0 {
+			if c.audio == nil {
 				continue
 			}
 
+			if pkt.Payload[0]>>4 == CodecExHeader {
+				// sound format 4b, packet type 4b, fourCC 32b
+				if pkt.Payload[0]&0b1111 != PacketTypeCodedFrames {
+					continue
+				}
+				pkt.Payload = pkt.Payload[5:]
+			} else {
+				if pkt.Payload[1] == 0 {
+					continue
+				}
+				pkt.Payload = pkt.Payload[2:]
+			}
+
 			pkt.Timestamp = TimeToRTP(pkt.Timestamp, c.audio.Codec.ClockRate)
-			pkt.Payload = pkt.Payload[2:]
 			c.audio.WriteRTP(pkt)
 
 		case TagVideo:
@@ -97,16 +124,28 @@ func (c *Producer) Start() error {
 			}
 
 			if isExHeader(pkt.Payload) {
+				frameType := pkt.Payload[0] >> 4 & 0b111
+
 				switch packetType := pkt.Payload[0] & 0b1111; packetType {
 				case PacketTypeCodedFrames:
-					// frame type 4b, packet type 4b, fourCC 32b, composition time 24b
-					pkt.Payload = pkt.Payload[8:]
+					if c.videoFourCC == FourCCAVC || c.videoFourCC == FourCCHEVC {
+						// frame type 4b, packet type 4b, fourCC 32b, composition time 24b
+						pkt.Payload = pkt.Payload[8:]
+					} else {
+						// frame type 4b, packet type 4b, fourCC 32b
+						pkt.Payload = pkt.Payload[5:]
+					}
 				case PacketTypeCodedFramesX:
 					// frame type 4b, packet type 4b, fourCC 32b
 					pkt.Payload = pkt.Payload[5:]
 				default:
 					continue
 				}
+
+				// AV1 keyframes may come without sequence header (frame type 1 - keyframe)
+				if c.av1Header != nil && frameType == 1 && !av1.IsKeyframe(pkt.Payload) {
+					pkt.Payload = append(append([]byte{}, c.av1Header...), pkt.Payload...)
+				}
 			} else {
 				switch pkt.Payload[1] {
 				case PacketTypeAVCNALU:
@@ -170,15 +209,36 @@ func (c *Producer) probe() error {
 			_ = pkt.Payload[0] & 0b0010    // SoundSize
 			_ = pkt.Payload[0] & 0b0001    // SoundType
 
-			if codecID != CodecAAC {
-				continue
-			}
+			var codec *core.Codec
+
+			switch codecID {
+			case CodecAAC:
+				if pkt.Payload[1] != 0 { // check if header
+					continue
+				}
 
-			if pkt.Payload[1] != 0 { // check if header
+				codec = aac.ConfigToCodec(pkt.Payload[2:])
+
+			case CodecExHeader:
+				_ = pkt.Payload[4] // bounds
+
+				if packetType := pkt.Payload[0] & 0b1111; packetType != PacketTypeSequenceStart {
+					continue
+				}
+
+				switch string(pkt.Payload[1:5]) {
+				case FourCCAAC:
+					codec = aac.ConfigToCodec(pkt.Payload[5:])
+				case FourCCOpus:
+					codec = opusHeaderToCodec(pkt.Payload[5:])
+				default:
+					continue
+				}
+
+			default:
 				continue
 			}
 
-			codec := aac.ConfigToCodec(pkt.Payload[2:])
 			media := &core.Media{
 				Kind:      core.KindAudio,
 				Direction: core.DirectionRecvonly,
@@ -195,15 +255,23 @@ func (c *Producer) probe() error {
 			var codec *core.Codec
 
 			if isExHeader(pkt.Payload) {
-				if string(pkt.Payload[1:5]) != "hvc1" {
+				if packetType := pkt.Payload[0] & 0b1111; packetType != PacketTypeSequenceStart {
 					continue
 				}
 
-				if packetType := pkt.Payload[0] & 0b1111; packetType != PacketTypeSequenceStart {
+				c.videoFourCC = string(pkt.Payload[1:5])
+
+				switch c.videoFourCC {
+				case FourCCAVC:
+					codec = h264.ConfigToCodec(pkt.Payload[5:])
+				case FourCCHEVC:
+					codec = h265.ConfigToCodec(pkt.Payload[5:])
+				case FourCCAV1:
+					codec = av1.ConfigToCodec(pkt.Payload[5:])
+					c.av1Header = av1.DecodeConfig(pkt.Payload[5:])
+				default:
 					continue
 				}
-
-				codec = h265.ConfigToCodec(pkt.Payload[5:])
 			} else {
 				_ = pkt.Payload[0] >> 4 // FrameType
 
@@ -301,3 +369,16 @@ func TimeToRTP(timeMS uint32, clockRate uint32) uint32 {
 func isExHeader(data []byte) bool {
 	return data[0]&0b1000_0000 != 0
 }
+
+// opusHeaderToCodec - OpusHead: magic 64b, version 8b, channels 8b, pre-skip 16b, sample rate 32b...
+func opusHeaderToCodec(header []byte) *core.Codec {
+	codec := &core.Codec{
+		Name:      core.CodecOpus,
+		ClockRate: 48000, // RTP clock rate is always 48000
+		Channels:  2,
+	}
+	if len(header) >= 19 && string(header[:8]) == "OpusHead" {
+		codec.Channels = header[9]
+	}
+	return codec
+}
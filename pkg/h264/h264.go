@@ -43,6 +43,24 @@ func IsKeyframe(b []byte) bool {
 	}
 }
 
+// IsReference - check if the slice in one AU is used for reference by other frames (nal_ref_idc != 0)
+func IsReference(b []byte) bool {
+	for {
+		switch NALUType(b) {
+		case NALUTypePFrame, NALUTypeIFrame:
+			return b[4]&0x60 != 0
+		}
+
+		size := int(binary.BigEndian.Uint32(b)) + 4
+		if size < len(b) {
+			b = b[size:]
+			continue
+		} else {
+			return true
+		}
+	}
+}
+
 func Join(ps, iframe []byte) []byte {
 	b := make([]byte, len(ps)+len(iframe))
 	i := copy(b, ps)
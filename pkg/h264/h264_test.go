@@ -108,3 +108,10 @@ func TestAVCCToCodec(t *testing.T) {
 	codec := AVCCToCodec(b)
 	require.Equal(t, "packetization-mode=1;profile-level-id=64001f;sprop-parameter-sets=Z2QAH6wkhAFAFuwEQAAAAwBAAAAMI8YMkg==,aO4yyLA=", codec.FmtpLine)
 }
+
+func TestRBSP(t *testing.T) {
+	require.Equal(t, []byte{0, 0, 1, 0, 0, 0}, RBSP([]byte{0, 0, 3, 1, 0, 0, 3, 0}))
+
+	b := []byte{0x67, 0x42}
+	require.Equal(t, b, RBSP(b))
+}
@@ -135,3 +135,32 @@ func RTPPay(mtu uint16, handler core.HandlerFunc) core.HandlerFunc {
 		}
 	}
 }
+
+// IsKeyframeRTP - RTP payload with the start of the keyframe (SPS or IDR),
+// so the decoder can start from this packet
+func IsKeyframeRTP(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+
+	switch payload[0] & 0x1F {
+	case NALUTypeSPS, NALUTypeIFrame:
+		return true
+	case 24: // STAP-A
+		for b := payload[1:]; len(b) > 2; {
+			size := int(binary.BigEndian.Uint16(b)) + 2
+			if size > len(b) {
+				break
+			}
+			switch b[2] & 0x1F {
+			case NALUTypeSPS, NALUTypeIFrame:
+				return true
+			}
+			b = b[size:]
+		}
+	case 28: // FU-A
+		return payload[1]&0x80 != 0 && payload[1]&0x1F == NALUTypeIFrame
+	}
+
+	return false
+}
@@ -0,0 +1,169 @@
+package h264
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/AlexxIT/go2rtc/pkg/bits"
+)
+
+const (
+	SEITypePicTiming            = 1
+	SEITypeUserDataRegistered   = 4 // ITU-T T.35, closed captions
+	SEITypeUserDataUnregistered = 5 // UUID and any data
+)
+
+type SEIMessage struct {
+	Type    int
+	Payload []byte
+}
+
+// GetSEI - SEI messages from all SEI NAL units of the AVCC frame
+func GetSEI(avcc []byte) (msgs []*SEIMessage) {
+	for len(avcc) > 4 {
+		size := 4 + int(binary.BigEndian.Uint32(avcc))
+		if size > len(avcc) {
+			break
+		}
+
+		if NALUType(avcc) == NALUTypeSEI {
+			msgs = append(msgs, DecodeSEI(RBSP(avcc[5:size]))...)
+		}
+
+		avcc = avcc[size:]
+	}
+	return
+}
+
+// DecodeSEI - SEI messages from the RBSP of the SEI NAL unit (without NAL header)
+func DecodeSEI(rbsp []byte) (msgs []*SEIMessage) {
+	// last byte is rbsp_trailing_bits
+	for len(rbsp) > 1 {
+		var typ, size int
+		var i int
+
+		for ; i < len(rbsp) && rbsp[i] == 0xFF; i++ {
+			typ += 0xFF
+		}
+		if i >= len(rbsp) {
+			break
+		}
+		typ += int(rbsp[i])
+		i++
+
+		for ; i < len(rbsp) && rbsp[i] == 0xFF; i++ {
+			size += 0xFF
+		}
+		if i >= len(rbsp) {
+			break
+		}
+		size += int(rbsp[i])
+		i++
+
+		if i+size > len(rbsp) {
+			break
+		}
+
+		msgs = append(msgs, &SEIMessage{Type: typ, Payload: rbsp[i : i+size]})
+
+		rbsp = rbsp[i+size:]
+	}
+	return
+}
+
+// RBSP - NAL unit data without emulation prevention bytes (00 00 03)
+func RBSP(b []byte) []byte {
+	var res []byte
+	var zeros int
+
+	for i, c := range b {
+		if zeros >= 2 && c == 3 {
+			if res == nil {
+				res = append(make([]byte, 0, len(b)), b[:i]...)
+			}
+			zeros = 0
+			continue
+		}
+
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+
+		if res != nil {
+			res = append(res, c)
+		}
+	}
+
+	if res == nil {
+		return b // most NAL units don't have emulation prevention
+	}
+	return res
+}
+
+// PicTiming - time code from the pic_timing SEI (HH:MM:SS:FF or HH:MM:SS;FF for
+// drop frame), empty string if the SPS or the SEI doesn't have it
+func (s *SPS) PicTiming(payload []byte) string {
+	if s.pic_struct_present_flag == 0 {
+		return ""
+	}
+
+	r := bits.NewReader(payload)
+
+	if s.nal_hrd_parameters_present_flag != 0 || s.vcl_hrd_parameters_present_flag != 0 {
+		_ = r.ReadBits(s.cpb_removal_delay_length_minus1 + 1) // cpb_removal_delay
+		_ = r.ReadBits(s.dpb_output_delay_length_minus1 + 1)  // dpb_output_delay
+	}
+
+	picStruct := r.ReadBits8(4)
+	if picStruct > 8 {
+		return ""
+	}
+
+	numClockTS := []byte{1, 1, 1, 2, 2, 3, 3, 2, 3}[picStruct]
+
+	for i := byte(0); i < numClockTS; i++ {
+		if r.ReadBit() == 0 {
+			continue // clock_timestamp_flag
+		}
+
+		_ = r.ReadBits8(2 + 1 + 5) // ct_type, nuit_field_based_flag, counting_type
+		full := r.ReadBit()
+		_ = r.ReadBit() // discontinuity_flag
+		dropped := r.ReadBit()
+		frames := r.ReadByte()
+
+		var hh, mm, ss byte
+		if full != 0 {
+			ss = r.ReadBits8(6)
+			mm = r.ReadBits8(6)
+			hh = r.ReadBits8(5)
+		} else if r.ReadBit() != 0 {
+			ss = r.ReadBits8(6)
+			if r.ReadBit() != 0 {
+				mm = r.ReadBits8(6)
+				if r.ReadBit() != 0 {
+					hh = r.ReadBits8(5)
+				}
+			}
+		}
+
+		if r.EOF {
+			return ""
+		}
+
+		return TimeCode(hh, mm, ss, uint16(frames), dropped != 0)
+	}
+
+	return ""
+}
+
+// TimeCode - SMPTE time code string
+func TimeCode(hh, mm, ss byte, frames uint16, dropFrame bool) string {
+	sep := ':'
+	if dropFrame {
+		sep = ';'
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%c%02d", hh, mm, ss, sep, frames)
+}
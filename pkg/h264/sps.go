@@ -70,6 +70,13 @@ type SPS struct {
 	num_units_in_tick        uint32
 	time_scale               uint32
 	fixed_frame_rate_flag    byte
+
+	nal_hrd_parameters_present_flag byte
+	vcl_hrd_parameters_present_flag byte
+	cpb_removal_delay_length_minus1 byte
+	dpb_output_delay_length_minus1  byte
+	time_offset_length              byte
+	pic_struct_present_flag         byte
 }
 
 func (s *SPS) Width() uint16 {
@@ -211,6 +218,10 @@ func DecodeSPS(sps []byte) *SPS {
 			s.time_scale = r.ReadUint32()
 			s.fixed_frame_rate_flag = r.ReadBit()
 		}
+
+		// optional values only for pic_timing SEI, so they can't break the SPS
+		rd := *r
+		s.hrd_and_pic_struct(&rd)
 		//...
 	}
 
@@ -221,6 +232,43 @@ func DecodeSPS(sps []byte) *SPS {
 	return s
 }
 
+//goland:noinspection GoSnakeCaseUsage
+func (s *SPS) hrd_and_pic_struct(r *bits.Reader) {
+	s.nal_hrd_parameters_present_flag = r.ReadBit()
+	if s.nal_hrd_parameters_present_flag != 0 {
+		s.hrd_parameters(r)
+	}
+	s.vcl_hrd_parameters_present_flag = r.ReadBit()
+	if s.vcl_hrd_parameters_present_flag != 0 {
+		s.hrd_parameters(r)
+	}
+	if s.nal_hrd_parameters_present_flag != 0 || s.vcl_hrd_parameters_present_flag != 0 {
+		_ = r.ReadBit() // low_delay_hrd_flag
+	}
+	s.pic_struct_present_flag = r.ReadBit()
+
+	if r.EOF {
+		s.nal_hrd_parameters_present_flag = 0
+		s.vcl_hrd_parameters_present_flag = 0
+		s.pic_struct_present_flag = 0
+	}
+}
+
+//goland:noinspection GoSnakeCaseUsage
+func (s *SPS) hrd_parameters(r *bits.Reader) {
+	cpb_cnt_minus1 := r.ReadUEGolomb()
+	_ = r.ReadBits8(4 + 4) // bit_rate_scale, cpb_size_scale
+	for i := uint32(0); i <= cpb_cnt_minus1 && !r.EOF; i++ {
+		_ = r.ReadUEGolomb() // bit_rate_value_minus1
+		_ = r.ReadUEGolomb() // cpb_size_value_minus1
+		_ = r.ReadBit()      // cbr_flag
+	}
+	_ = r.ReadBits8(5) // initial_cpb_removal_delay_length_minus1
+	s.cpb_removal_delay_length_minus1 = r.ReadBits8(5)
+	s.dpb_output_delay_length_minus1 = r.ReadBits8(5)
+	s.time_offset_length = r.ReadBits8(5)
+}
+
 //goland:noinspection GoSnakeCaseUsage
 func (s *SPS) scaling_list(r *bits.Reader, sizeOfScalingList int) {
 	lastScale := int32(8)
@@ -43,6 +43,24 @@ func IsKeyframe(b []byte) bool {
 	}
 }
 
+// IsReference - check if the slice in one AU is used for reference by other frames,
+// sub-layer non-reference pictures have even NAL types up to 14 (TRAIL_N, TSA_N, etc.)
+func IsReference(b []byte) bool {
+	for {
+		if nuType := NALUType(b); nuType < 32 {
+			return nuType > 14 || nuType%2 == 1
+		}
+
+		size := int(binary.BigEndian.Uint32(b)) + 4
+		if size < len(b) {
+			b = b[size:]
+			continue
+		} else {
+			return true
+		}
+	}
+}
+
 func Types(data []byte) []byte {
 	var types []byte
 	for {
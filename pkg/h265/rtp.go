@@ -193,3 +193,34 @@ func SafariPay(mtu uint16, handler core.HandlerFunc) core.HandlerFunc {
 		}
 	}
 }
+
+// IsKeyframeRTP - RTP payload with the start of the keyframe (VPS, SPS or IRAP)
+func IsKeyframeRTP(payload []byte) bool {
+	if len(payload) < 3 {
+		return false
+	}
+
+	switch nuType := (payload[0] >> 1) & 0x3F; nuType {
+	case NALUTypeVPS, NALUTypeSPS, NALUTypeIFrame, NALUTypeIFrame2, NALUTypeIFrame3:
+		return true
+	case 48: // AP
+		for b := payload[2:]; len(b) > 3; {
+			size := int(binary.BigEndian.Uint16(b)) + 2
+			if size > len(b) {
+				break
+			}
+			switch (b[2] >> 1) & 0x3F {
+			case NALUTypeVPS, NALUTypeSPS, NALUTypeIFrame, NALUTypeIFrame2, NALUTypeIFrame3:
+				return true
+			}
+			b = b[size:]
+		}
+	case NALUTypeFU:
+		switch payload[2] & 0x3F {
+		case NALUTypeIFrame, NALUTypeIFrame2, NALUTypeIFrame3:
+			return payload[2]&0x80 != 0
+		}
+	}
+
+	return false
+}
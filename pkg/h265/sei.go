@@ -0,0 +1,70 @@
+package h265
+
+import (
+	"encoding/binary"
+
+	"github.com/AlexxIT/go2rtc/pkg/bits"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+)
+
+const SEITypeTimeCode = 136
+
+// GetSEI - SEI messages from all prefix and suffix SEI NAL units of the AVCC frame
+func GetSEI(avcc []byte) (msgs []*h264.SEIMessage) {
+	for len(avcc) > 5 {
+		size := 4 + int(binary.BigEndian.Uint32(avcc))
+		if size > len(avcc) {
+			break
+		}
+
+		switch NALUType(avcc) {
+		case NALUTypePrefixSEI, NALUTypeSuffixSEI:
+			msgs = append(msgs, h264.DecodeSEI(h264.RBSP(avcc[6:size]))...)
+		}
+
+		avcc = avcc[size:]
+	}
+	return
+}
+
+// TimeCode - time code from the time code SEI (HH:MM:SS:FF), empty string if not present
+func TimeCode(payload []byte) string {
+	r := bits.NewReader(payload)
+
+	numClockTS := r.ReadBits8(2)
+
+	for i := byte(0); i < numClockTS; i++ {
+		if r.ReadBit() == 0 {
+			continue // clock_timestamp_flag
+		}
+
+		_ = r.ReadBits8(1 + 5) // units_field_based_flag, counting_type
+		full := r.ReadBit()
+		_ = r.ReadBit() // discontinuity_flag
+		dropped := r.ReadBit()
+		frames := r.ReadBits16(9)
+
+		var hh, mm, ss byte
+		if full != 0 {
+			ss = r.ReadBits8(6)
+			mm = r.ReadBits8(6)
+			hh = r.ReadBits8(5)
+		} else if r.ReadBit() != 0 {
+			ss = r.ReadBits8(6)
+			if r.ReadBit() != 0 {
+				mm = r.ReadBits8(6)
+				if r.ReadBit() != 0 {
+					hh = r.ReadBits8(5)
+				}
+			}
+		}
+
+		if r.EOF {
+			return ""
+		}
+
+		return h264.TimeCode(hh, mm, ss, frames, dropped != 0)
+	}
+
+	return ""
+}
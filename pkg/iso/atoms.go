@@ -21,6 +21,9 @@ const (
 	MoovTrakMdiaMinfStblStsc    = "stsc"
 	MoovTrakMdiaMinfStblStsz    = "stsz"
 	MoovTrakMdiaMinfStblStco    = "stco"
+	MoovTrakMdiaMinfStblCo64    = "co64"
+	MoovTrakMdiaMinfStblStss    = "stss"
+	MoovTrakMdiaMinfStblCtts    = "ctts"
 	MoovMvex                    = "mvex"
 	MoovMvexTrex                = "trex"
 	Moof                        = "moof"
@@ -30,6 +33,7 @@ const (
 	MoofTrafTfdt                = "tfdt"
 	MoofTrafTrun                = "trun"
 	Mdat                        = "mdat"
+	Emsg                        = "emsg"
 )
 
 const (
@@ -261,6 +265,8 @@ func (m *Movie) WriteAudioTrack(id uint32, codec string, timescale uint32, chann
 }
 
 const (
+	TfhdBaseDataOffset        = 0x000001
+	TfhdSampleDescription     = 0x000002
 	TfhdDefaultSampleDuration = 0x000008
 	TfhdDefaultSampleSize     = 0x000010
 	TfhdDefaultSampleFlags    = 0x000020
@@ -337,3 +343,21 @@ func (m *Movie) WriteData(b []byte) {
 	m.Write(b)
 	m.EndAtom()
 }
+
+// WriteEventMessage - DASH event message box (version 1), should be placed before
+// the movie fragment, time in the track timescale
+func (m *Movie) WriteEventMessage(scheme, value string, timescale uint32, time uint64, id uint32, data []byte) {
+	m.StartAtom(Emsg)
+	m.WriteBytes(1)          // version
+	m.Skip(3)                // flags
+	m.WriteUint32(timescale) // timescale
+	m.WriteUint64(time)      // presentation time
+	m.WriteUint32(0)         // event duration
+	m.WriteUint32(id)        // id
+	m.WriteString(scheme)
+	m.WriteBytes(0)
+	m.WriteString(value)
+	m.WriteBytes(0)
+	m.Write(data) // message data
+	m.EndAtom()
+}
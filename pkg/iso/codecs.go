@@ -12,6 +12,10 @@ func (m *Movie) WriteVideo(codec string, width, height uint16, conf []byte) {
 		m.StartAtom("avc1")
 	case core.CodecH265:
 		m.StartAtom("hev1")
+	case core.CodecAV1:
+		m.StartAtom("av01")
+	case core.CodecVP9:
+		m.StartAtom("vp09")
 	default:
 		panic("unsupported iso video: " + codec)
 	}
@@ -37,6 +41,10 @@ func (m *Movie) WriteVideo(codec string, width, height uint16, conf []byte) {
 		m.StartAtom("avcC")
 	case core.CodecH265:
 		m.StartAtom("hvcC")
+	case core.CodecAV1:
+		m.StartAtom("av1C")
+	case core.CodecVP9:
+		m.StartAtom("vpcC") // conf with version and flags
 	}
 	m.Write(conf)
 	m.EndAtom() // AVCC
@@ -43,15 +43,25 @@ type AtomMdat struct {
 
 type AtomTfhd struct {
 	TrackID        uint32
+	BaseDataOffset uint64
 	SampleDuration uint32
 	SampleSize     uint32
 	SampleFlags    uint32
 }
+type AtomTrex struct {
+	TrackID        uint32
+	SampleDuration uint32
+	SampleSize     uint32
+	SampleFlags    uint32
+}
+
 type AtomTfdt struct {
 	DecodeTime uint64
 }
 
 type AtomTrun struct {
+	Flags            uint32 // Trun* flags of the present fields
+	SampleCount      uint32
 	DataOffset       uint32
 	FirstSampleFlags uint32
 	SamplesDuration  []uint32
@@ -60,9 +70,48 @@ type AtomTrun struct {
 	SamplesCTS       []uint32
 }
 
+// AtomStts - decoding time to sample
+type AtomStts struct {
+	SampleCounts []uint32
+	SampleDeltas []uint32
+}
+
+// AtomCtts - composition time to sample
+type AtomCtts struct {
+	SampleCounts  []uint32
+	SampleOffsets []int32
+}
+
+// AtomStss - sync samples (keyframes), numbers from one
+type AtomStss struct {
+	Samples []uint32
+}
+
+// AtomStsz - sample sizes, SampleSize for all samples if not zero
+type AtomStsz struct {
+	SampleSize  uint32
+	SampleCount uint32
+	Sizes       []uint32
+}
+
+// AtomStsc - sample to chunk, chunk numbers from one
+type AtomStsc struct {
+	FirstChunks     []uint32
+	SamplesPerChunk []uint32
+}
+
+// AtomStco - chunk offsets (stco and co64)
+type AtomStco struct {
+	Offsets []uint64
+}
+
 func DecodeAtom(b []byte) (any, error) {
+	if len(b) < 8 {
+		return nil, io.EOF
+	}
+
 	size := binary.BigEndian.Uint32(b)
-	if len(b) < int(size) {
+	if size < 8 || len(b) < int(size) {
 		return nil, io.EOF
 	}
 
@@ -71,31 +120,124 @@ func DecodeAtom(b []byte) (any, error) {
 
 	switch name {
 	// useful containers
-	case Moov, MoovTrak, MoovTrakMdia, MoovTrakMdiaMinf, MoovTrakMdiaMinfStbl, Moof, MoofTraf:
+	case Moov, MoovTrak, MoovTrakMdia, MoovTrakMdiaMinf, MoovTrakMdiaMinfStbl, MoovMvex, Moof, MoofTraf:
 		return DecodeAtoms(data)
 
 	case MoovTrakTkhd:
+		if data[0] == 1 {
+			return &AtomTkhd{TrackID: binary.BigEndian.Uint32(data[1+3+8+8:])}, nil
+		}
 		return &AtomTkhd{TrackID: binary.BigEndian.Uint32(data[1+3+4+4:])}, nil
 
 	case MoovTrakMdiaMdhd:
+		if data[0] == 1 {
+			return &AtomMdhd{TimeScale: binary.BigEndian.Uint32(data[1+3+8+8:])}, nil
+		}
 		return &AtomMdhd{TimeScale: binary.BigEndian.Uint32(data[1+3+4+4:])}, nil
 
+	case MoovTrakMdiaMinfStblStts:
+		atom := &AtomStts{}
+		rd := newTableReader(data)
+		for i := rd.ReadUint32(); i > 0 && !rd.EOF; i-- {
+			atom.SampleCounts = append(atom.SampleCounts, rd.ReadUint32())
+			atom.SampleDeltas = append(atom.SampleDeltas, rd.ReadUint32())
+		}
+		return atom, nil
+
+	case MoovTrakMdiaMinfStblCtts:
+		atom := &AtomCtts{}
+		rd := newTableReader(data)
+		for i := rd.ReadUint32(); i > 0 && !rd.EOF; i-- {
+			atom.SampleCounts = append(atom.SampleCounts, rd.ReadUint32())
+			atom.SampleOffsets = append(atom.SampleOffsets, int32(rd.ReadUint32())) // signed in version 1
+		}
+		return atom, nil
+
+	case MoovTrakMdiaMinfStblStss:
+		atom := &AtomStss{}
+		rd := newTableReader(data)
+		for i := rd.ReadUint32(); i > 0 && !rd.EOF; i-- {
+			atom.Samples = append(atom.Samples, rd.ReadUint32())
+		}
+		return atom, nil
+
+	case MoovTrakMdiaMinfStblStsz:
+		rd := newTableReader(data)
+		atom := &AtomStsz{SampleSize: rd.ReadUint32(), SampleCount: rd.ReadUint32()}
+		if atom.SampleSize == 0 {
+			for i := atom.SampleCount; i > 0 && !rd.EOF; i-- {
+				atom.Sizes = append(atom.Sizes, rd.ReadUint32())
+			}
+		}
+		return atom, nil
+
+	case MoovTrakMdiaMinfStblStsc:
+		atom := &AtomStsc{}
+		rd := newTableReader(data)
+		for i := rd.ReadUint32(); i > 0 && !rd.EOF; i-- {
+			atom.FirstChunks = append(atom.FirstChunks, rd.ReadUint32())
+			atom.SamplesPerChunk = append(atom.SamplesPerChunk, rd.ReadUint32())
+			_ = rd.ReadUint32() // sample description index
+		}
+		return atom, nil
+
+	case MoovTrakMdiaMinfStblStco:
+		atom := &AtomStco{}
+		rd := newTableReader(data)
+		for i := rd.ReadUint32(); i > 0 && !rd.EOF; i-- {
+			atom.Offsets = append(atom.Offsets, uint64(rd.ReadUint32()))
+		}
+		return atom, nil
+
+	case MoovTrakMdiaMinfStblCo64:
+		atom := &AtomStco{}
+		rd := newTableReader(data)
+		for i := rd.ReadUint32(); i > 0 && !rd.EOF; i-- {
+			atom.Offsets = append(atom.Offsets, rd.ReadUint64())
+		}
+		return atom, nil
+
 	case MoovTrakMdiaMinfStblStsd:
 		// support only 1 codec entry
 		if n := binary.BigEndian.Uint32(data[1+3:]); n == 1 {
 			return DecodeAtom(data[1+3+4:])
 		}
 
-	case "avc1", "hev1":
+	case "avc1", "hev1", "hvc1", "av01", "vp09":
 		b = data[6+2+2+2+4+4+4+2+2+4+4+4+2+32+2+2:]
-		atom, err := DecodeAtom(b)
-		if err != nil {
-			return nil, err
+		// config atom (avcC, hvcC, av1C, vpcC) may be not the first one (after colr, pasp)
+		for len(b) > 0 {
+			atom, err := DecodeAtom(b)
+			if err != nil {
+				return nil, err
+			}
+			if conf, ok := atom.(*Atom); ok {
+				switch conf.Name {
+				case "avcC", "hvcC", "av1C", "vpcC":
+					return &AtomVideo{Name: name, Config: conf.Data}, nil
+				}
+			}
+			b = b[binary.BigEndian.Uint32(b):]
 		}
-		if conf, ok := atom.(*Atom); ok {
-			return &AtomVideo{Name: name, Config: conf.Data}, nil
+
+	case "Opus":
+		atom := &AtomAudio{Name: name}
+
+		rd := bits.NewReader(data)
+		rd.ReadBytes(6 + 2 + 2 + 2 + 4) // skip
+		atom.Channels = rd.ReadUint16()
+		rd.ReadBytes(2 + 2 + 2) // skip
+		atom.SampleRate = uint32(rd.ReadFloat32())
+
+		// dOps atom without Opus magic, in big endian
+		if atom2, _ := DecodeAtom(rd.Left()); atom2 != nil {
+			if conf, ok := atom2.(*Atom); ok && conf.Name == "dOps" {
+				atom.Config = conf.Data
+			}
 		}
 
+		return atom, nil
+
 	case "mp4a":
 		atom := &AtomAudio{Name: name}
 
@@ -115,6 +257,15 @@ func DecodeAtom(b []byte) (any, error) {
 
 		return atom, nil
 
+	case MoovMvexTrex:
+		rd := newTableReader(data)
+		atom := &AtomTrex{TrackID: rd.ReadUint32()}
+		_ = rd.ReadUint32() // default sample description index
+		atom.SampleDuration = rd.ReadUint32()
+		atom.SampleSize = rd.ReadUint32()
+		atom.SampleFlags = rd.ReadUint32()
+		return atom, nil
+
 	case MoofMfhd:
 		return &AtomMfhd{Sequence: binary.BigEndian.Uint32(data[4:])}, nil
 
@@ -127,9 +278,14 @@ func DecodeAtom(b []byte) (any, error) {
 			TrackID: rd.ReadUint32(),
 		}
 
+		if flags&TfhdBaseDataOffset != 0 {
+			atom.BaseDataOffset = rd.ReadUint64()
+		}
+		if flags&TfhdSampleDescription != 0 {
+			_ = rd.ReadUint32() // sample description index
+		}
 		if flags&TfhdDefaultSampleDuration != 0 {
 			atom.SampleDuration = rd.ReadUint32()
-
 		}
 		if flags&TfhdDefaultSampleSize != 0 {
 			atom.SampleSize = rd.ReadUint32()
@@ -141,6 +297,9 @@ func DecodeAtom(b []byte) (any, error) {
 		return atom, nil
 
 	case MoofTrafTfdt:
+		if data[0] == 0 {
+			return &AtomTfdt{DecodeTime: uint64(binary.BigEndian.Uint32(data[4:]))}, nil
+		}
 		return &AtomTfdt{DecodeTime: binary.BigEndian.Uint64(data[4:])}, nil
 
 	case MoofTrafTrun:
@@ -149,7 +308,7 @@ func DecodeAtom(b []byte) (any, error) {
 		flags := rd.ReadUint24()
 		samples := rd.ReadUint32()
 
-		atom := &AtomTrun{}
+		atom := &AtomTrun{Flags: flags, SampleCount: samples}
 
 		if flags&TrunDataOffset != 0 {
 			atom.DataOffset = rd.ReadUint32()
@@ -158,7 +317,7 @@ func DecodeAtom(b []byte) (any, error) {
 			atom.FirstSampleFlags = rd.ReadUint32()
 		}
 
-		for i := uint32(0); i < samples; i++ {
+		for i := uint32(0); i < samples && !rd.EOF; i++ {
 			if flags&TrunSampleDuration != 0 {
 				atom.SamplesDuration = append(atom.SamplesDuration, rd.ReadUint32())
 			}
@@ -201,3 +360,10 @@ func DecodeAtoms(b []byte) (atoms []any, err error) {
 
 	return atoms, nil
 }
+
+// newTableReader - reader for the full atom data after version and flags
+func newTableReader(data []byte) *bits.Reader {
+	rd := bits.NewReader(data)
+	_ = rd.ReadUint32() // version and flags
+	return rd
+}
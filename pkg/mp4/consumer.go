@@ -1,15 +1,21 @@
 package mp4
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"io"
 	"sync"
 
 	"github.com/AlexxIT/go2rtc/pkg/aac"
+	"github.com/AlexxIT/go2rtc/pkg/av1"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
 	"github.com/AlexxIT/go2rtc/pkg/h265"
+	"github.com/AlexxIT/go2rtc/pkg/iso"
 	"github.com/AlexxIT/go2rtc/pkg/pcm"
+	"github.com/AlexxIT/go2rtc/pkg/sei"
+	"github.com/AlexxIT/go2rtc/pkg/vp9"
 	"github.com/pion/rtp"
 )
 
@@ -20,9 +26,16 @@ type Consumer struct {
 	mu    sync.Mutex
 	start bool
 
+	ready     chan struct{} // AV1 and VP9 init waits for the first keyframe
+	readyOnce sync.Once
+	done      chan struct{}
+	doneOnce  sync.Once
+
 	Rotate int `json:"-"`
 	ScaleX int `json:"-"`
 	ScaleY int `json:"-"`
+
+	Metadata bool `json:"-"` // SEI metadata as emsg boxes
 }
 
 func NewConsumer(medias []*core.Media) *Consumer {
@@ -57,6 +70,8 @@ func NewConsumer(medias []*core.Media) *Consumer {
 		},
 		muxer: &Muxer{},
 		wr:    wr,
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
 	}
 }
 
@@ -68,6 +83,8 @@ func (c *Consumer) AddTrack(media *core.Media, _ *core.Codec, track *core.Receiv
 
 	switch track.Codec.Name {
 	case core.CodecH264:
+		parser := c.seiParser(track.Codec)
+
 		handler.Handler = func(packet *rtp.Packet) {
 			if !c.start {
 				if !h264.IsKeyframe(packet.Payload) {
@@ -78,7 +95,11 @@ func (c *Consumer) AddTrack(media *core.Media, _ *core.Codec, track *core.Receiv
 
 			// important to use Mutex because right fragment order
 			c.mu.Lock()
+			emsg := c.eventMessage(parser, trackID, packet)
 			b := c.muxer.GetPayload(trackID, packet)
+			if emsg != nil {
+				b = append(emsg, b...)
+			}
 			if n, err := c.wr.Write(b); err == nil {
 				c.Send += n
 			}
@@ -92,6 +113,8 @@ func (c *Consumer) AddTrack(media *core.Media, _ *core.Codec, track *core.Receiv
 		}
 
 	case core.CodecH265:
+		parser := c.seiParser(track.Codec)
+
 		handler.Handler = func(packet *rtp.Packet) {
 			if !c.start {
 				if !h265.IsKeyframe(packet.Payload) {
@@ -102,7 +125,11 @@ func (c *Consumer) AddTrack(media *core.Media, _ *core.Codec, track *core.Receiv
 
 			// important to use Mutex because right fragment order
 			c.mu.Lock()
+			emsg := c.eventMessage(parser, trackID, packet)
 			b := c.muxer.GetPayload(trackID, packet)
+			if emsg != nil {
+				b = append(emsg, b...)
+			}
 			if n, err := c.wr.Write(b); err == nil {
 				c.Send += n
 			}
@@ -115,6 +142,52 @@ func (c *Consumer) AddTrack(media *core.Media, _ *core.Codec, track *core.Receiv
 			handler.Handler = h265.RepairAVCC(track.Codec, handler.Handler)
 		}
 
+	case core.CodecAV1:
+		handler.Handler = func(packet *rtp.Packet) {
+			if !c.start {
+				if !av1.IsKeyframe(packet.Payload) {
+					return
+				}
+				c.setHeader(trackID, packet.Payload)
+				c.start = true
+			}
+
+			// important to use Mutex because right fragment order
+			c.mu.Lock()
+			b := c.muxer.GetPayload(trackID, packet)
+			if n, err := c.wr.Write(b); err == nil {
+				c.Send += n
+			}
+			c.mu.Unlock()
+		}
+
+		if track.Codec.IsRTP() {
+			handler.Handler = av1.RTPDepay(handler.Handler)
+		}
+
+	case core.CodecVP9:
+		handler.Handler = func(packet *rtp.Packet) {
+			if !c.start {
+				if !vp9.IsKeyframe(packet.Payload) {
+					return
+				}
+				c.setHeader(trackID, packet.Payload)
+				c.start = true
+			}
+
+			// important to use Mutex because right fragment order
+			c.mu.Lock()
+			b := c.muxer.GetPayload(trackID, packet)
+			if n, err := c.wr.Write(b); err == nil {
+				c.Send += n
+			}
+			c.mu.Unlock()
+		}
+
+		if track.Codec.IsRTP() {
+			handler.Handler = vp9.RTPDepay(handler.Handler)
+		}
+
 	default:
 		handler.Handler = func(packet *rtp.Packet) {
 			if !c.start {
@@ -169,7 +242,22 @@ func (c *Consumer) WriteTo(wr io.Writer) (int64, error) {
 		c.start = true
 	}
 
+	// AV1 and VP9 config and picture size are known only from the keyframe
+	c.mu.Lock()
+	wait := c.muxer.NeedHeader()
+	c.mu.Unlock()
+
+	if wait {
+		select {
+		case <-c.ready:
+		case <-c.done:
+			return 0, io.EOF
+		}
+	}
+
+	c.mu.Lock()
 	init, err := c.muxer.GetInit()
+	c.mu.Unlock()
 	if err != nil {
 		return 0, err
 	}
@@ -187,3 +275,98 @@ func (c *Consumer) WriteTo(wr io.Writer) (int64, error) {
 
 	return c.wr.WriteTo(wr)
 }
+
+func (c *Consumer) setHeader(trackID byte, keyframe []byte) {
+	c.mu.Lock()
+	c.muxer.SetHeader(trackID, keyframe)
+	ready := !c.muxer.NeedHeader()
+	c.mu.Unlock()
+
+	if ready {
+		c.readyOnce.Do(func() {
+			close(c.ready)
+		})
+	}
+}
+
+func (c *Consumer) Stop() error {
+	c.doneOnce.Do(func() {
+		close(c.done)
+	})
+	return c.Connection.Stop()
+}
+
+// seiParser - SEI parser of the video track, nil without the metadata param
+func (c *Consumer) seiParser(codec *core.Codec) *sei.Parser {
+	if !c.Metadata {
+		return nil
+	}
+	return sei.NewParser(codec)
+}
+
+// eventMessage - emsg box with SEI metadata of the frame or nil
+func (c *Consumer) eventMessage(parser *sei.Parser, trackID byte, packet *rtp.Packet) []byte {
+	if parser == nil {
+		return nil
+	}
+
+	md := parser.Parse(packet.Payload)
+	if md == nil {
+		return nil
+	}
+
+	md.Timestamp = packet.Timestamp
+
+	data, err := json.Marshal(md)
+	if err != nil {
+		return nil
+	}
+
+	return c.muxer.GetEventMessage(trackID, sei.Scheme, data)
+}
+
+// Split - splits WriteTo output to the init (ftyp+moov) and the fragments (moof+mdat),
+// keyframe - fragment with the video keyframe (for the shared muxer)
+func (c *Consumer) Split(b []byte, yield func(b []byte, init, keyframe bool)) {
+	var start int
+	var keyframe bool
+
+	for i := 0; i+8 <= len(b); {
+		size := int(binary.BigEndian.Uint32(b[i:]))
+		if size < 8 || i+size > len(b) {
+			return
+		}
+
+		atom := b[i : i+size]
+		i += size
+
+		switch string(atom[4:8]) {
+		case iso.Moov:
+			yield(b[start:i], true, false)
+			start = i
+		case iso.Moof:
+			keyframe = c.isKeyframe(atom)
+		case iso.Mdat:
+			yield(b[start:i], false, keyframe)
+			start = i
+		}
+	}
+}
+
+func (c *Consumer) isKeyframe(moof []byte) bool {
+	atoms, err := iso.DecodeAtoms(moof)
+	if err != nil {
+		return false
+	}
+
+	for _, atom := range atoms {
+		if tfhd, ok := atom.(*iso.AtomTfhd); ok {
+			// audio samples have the same flags
+			i := int(tfhd.TrackID) - 1
+			return i >= 0 && i < len(c.Senders) && c.Senders[i].Codec.IsVideo() &&
+				tfhd.SampleFlags == iso.SampleVideoIFrame
+		}
+	}
+
+	return false
+}
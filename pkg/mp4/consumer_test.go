@@ -0,0 +1,57 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/av1"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/iso"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+type chanWriter chan []byte
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func TestAV1Init(t *testing.T) {
+	codec := &core.Codec{Name: core.CodecAV1, ClockRate: 90000, PayloadType: core.PayloadTypeRAW}
+	media := &core.Media{Kind: core.KindVideo, Direction: core.DirectionSendonly, Codecs: []*core.Codec{codec}}
+	receiver := core.NewReceiver(media, codec)
+
+	cons := NewConsumer([]*core.Media{media})
+	require.Nil(t, cons.AddTrack(media, codec, receiver))
+
+	wr := make(chanWriter, 10)
+	go cons.WriteTo(wr)
+
+	// init waits for the keyframe
+	select {
+	case <-wr:
+		require.FailNow(t, "init before keyframe")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// temporal delimiter + sequence header 1280x720
+	tu, _ := hex.DecodeString("12000a0b0000002d4cffb3dfff9804")
+	obu := av1.GetSequenceHeader(tu)
+
+	receiver.WriteRTP(&rtp.Packet{Payload: tu})
+
+	init := <-wr
+	require.True(t, bytes.Contains(init, av1.EncodeConfig(obu)))
+
+	// track header width and height in 16.16 fixed point
+	i := bytes.Index(init, []byte(iso.MoovTrakTkhd))
+	require.Positive(t, i)
+	tkhd := init[i+4:]
+	require.Equal(t, uint32(1280<<16), binary.BigEndian.Uint32(tkhd[76:]))
+	require.Equal(t, uint32(720<<16), binary.BigEndian.Uint32(tkhd[80:]))
+}
@@ -2,9 +2,12 @@ package mp4
 
 import (
 	"github.com/AlexxIT/go2rtc/pkg/aac"
+	"github.com/AlexxIT/go2rtc/pkg/av1"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
 	"github.com/AlexxIT/go2rtc/pkg/iso"
+	"github.com/AlexxIT/go2rtc/pkg/vp9"
 	"github.com/pion/rtp"
 )
 
@@ -34,11 +37,23 @@ func (d *Demuxer) Probe(init []byte) (medias []*core.Media) {
 			switch atom.Name {
 			case "avc1":
 				codec = h264.ConfigToCodec(atom.Config)
+			case "hev1", "hvc1":
+				codec = h265.ConfigToCodec(atom.Config)
+			case "av01":
+				codec = av1.ConfigToCodec(atom.Config)
+			case "vp09":
+				codec = vp9.ConfigToCodec(atom.Config)
 			}
 		case *iso.AtomAudio:
 			switch atom.Name {
 			case "mp4a":
 				codec = aac.ConfigToCodec(atom.Config)
+			case "Opus":
+				codec = &core.Codec{Name: core.CodecOpus, ClockRate: 48000, Channels: uint8(atom.Channels)}
+				// dOps: version, output channel count...
+				if len(atom.Config) > 1 {
+					codec.Channels = atom.Config[1]
+				}
 			}
 		}
 
@@ -73,6 +88,7 @@ func (d *Demuxer) Demux(data2 []byte) (trackID uint32, packets []*core.Packet) {
 	}
 
 	var ts uint32
+	var tfhd *iso.AtomTfhd
 	var trun *iso.AtomTrun
 	var data []byte
 
@@ -80,6 +96,7 @@ func (d *Demuxer) Demux(data2 []byte) (trackID uint32, packets []*core.Packet) {
 		switch atom := atom.(type) {
 		case *iso.AtomTfhd:
 			trackID = atom.TrackID
+			tfhd = atom
 		case *iso.AtomTfdt:
 			ts = uint32(atom.DecodeTime)
 		case *iso.AtomTrun:
@@ -90,23 +107,32 @@ func (d *Demuxer) Demux(data2 []byte) (trackID uint32, packets []*core.Packet) {
 	}
 
 	timeScale := d.timeScales[trackID]
-	if timeScale == 0 {
+	if timeScale == 0 || trun == nil {
 		return 0, nil
 	}
 
-	n := len(trun.SamplesDuration)
-	packets = make([]*core.Packet, n)
+	n := int(trun.SampleCount)
+	packets = make([]*core.Packet, 0, n)
 
 	for i := 0; i < n; i++ {
-		duration := trun.SamplesDuration[i]
-		size := trun.SamplesSize[i]
+		// durations and sizes may be absent in the trun, use defaults from the tfhd
+		duration, size := tfhd.SampleDuration, tfhd.SampleSize
+		if i < len(trun.SamplesDuration) {
+			duration = trun.SamplesDuration[i]
+		}
+		if i < len(trun.SamplesSize) {
+			size = trun.SamplesSize[i]
+		}
+		if int(size) > len(data) {
+			break
+		}
 
 		// can be SPS, PPS and IFrame in one packet
 		timestamp := uint32(float32(ts) * timeScale)
-		packets[i] = &rtp.Packet{
+		packets = append(packets, &rtp.Packet{
 			Header:  rtp.Header{Timestamp: timestamp},
 			Payload: data[:size],
-		}
+		})
 
 		data = data[size:]
 		ts += duration
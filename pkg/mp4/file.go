@@ -0,0 +1,390 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/iso"
+	"github.com/pion/rtp"
+)
+
+// File - index of all samples of the regular or fragmented MP4 file
+type File struct {
+	Medias   []*core.Media
+	Samples  []*Sample // sorted by decoding time
+	Duration time.Duration
+
+	rd         io.ReaderAt
+	demuxer    Demuxer
+	timeScales map[uint32]uint32 // trackID => mdhd time scale
+	trex       map[uint32]*iso.AtomTrex
+	nextDTS    map[uint32]uint64 // for fragments without tfdt
+}
+
+type Sample struct {
+	TrackID  uint32
+	Offset   int64
+	Size     uint32
+	DTS      uint64 // in the track time scale
+	CTS      int32  // composition time offset in the track time scale
+	Time     time.Duration
+	Keyframe bool
+}
+
+const sampleIsNonSync = 0x10000
+
+// NewFile - read moov and moof atoms from the file, skip mdat atoms
+func NewFile(rd io.ReaderAt, size int64) (*File, error) {
+	f := &File{
+		rd:         rd,
+		timeScales: map[uint32]uint32{},
+		trex:       map[uint32]*iso.AtomTrex{},
+		nextDTS:    map[uint32]uint64{},
+	}
+
+	header := make([]byte, 16)
+
+	for offset := int64(0); offset+8 <= size; {
+		if _, err := rd.ReadAt(header[:8], offset); err != nil {
+			return nil, err
+		}
+
+		atomSize := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+
+		switch atomSize {
+		case 0: // last atom till the end of file
+			atomSize = size - offset
+		case 1: // 64-bit size after the name
+			if _, err := rd.ReadAt(header[8:], offset+8); err != nil {
+				return nil, err
+			}
+			atomSize = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+
+		if atomSize < headerSize || offset+atomSize > size {
+			break // broken or unfinished atom
+		}
+
+		switch name := string(header[4:8]); name {
+		case iso.Moov, iso.Moof:
+			b := make([]byte, atomSize)
+			if _, err := rd.ReadAt(b, offset); err != nil {
+				return nil, err
+			}
+
+			atoms, err := iso.DecodeAtoms(b)
+			if err != nil {
+				return nil, err
+			}
+
+			if name == iso.Moov {
+				f.Medias = f.demuxer.Probe(b)
+				f.readMoov(atoms)
+			} else {
+				f.readMoof(atoms, offset)
+			}
+		}
+
+		offset += atomSize
+	}
+
+	if f.Medias == nil {
+		return nil, errors.New("mp4: can't find moov atom")
+	}
+
+	sort.SliceStable(f.Samples, func(i, j int) bool {
+		return f.Samples[i].Time < f.Samples[j].Time
+	})
+
+	return f, nil
+}
+
+func (f *File) GetTrackID(codec *core.Codec) uint32 {
+	return f.demuxer.GetTrackID(codec)
+}
+
+func (f *File) Codec(trackID uint32) *core.Codec {
+	return f.demuxer.codecs[trackID]
+}
+
+// Seek - index of the last video keyframe before the time or first sample after the time
+func (f *File) Seek(t time.Duration) int {
+	var video bool
+	for _, media := range f.Medias {
+		if media.Kind == core.KindVideo {
+			video = true
+		}
+	}
+
+	i := sort.Search(len(f.Samples), func(i int) bool {
+		return f.Samples[i].Time >= t
+	})
+
+	if !video {
+		return i
+	}
+
+	for j := min(i, len(f.Samples)-1); j >= 0; j-- {
+		sample := f.Samples[j]
+		if sample.Keyframe && sample.Time <= t && f.Codec(sample.TrackID).Kind() == core.KindVideo {
+			return j
+		}
+	}
+
+	return 0
+}
+
+// ReadPacket - sample data in AVCC format, timestamp in the codec clock rate, CTS in the ExtensionProfile
+func (f *File) ReadPacket(sample *Sample) (*rtp.Packet, error) {
+	payload := make([]byte, sample.Size)
+	if _, err := f.rd.ReadAt(payload, sample.Offset); err != nil {
+		return nil, err
+	}
+
+	clockRate := uint64(f.Codec(sample.TrackID).ClockRate)
+	timeScale := uint64(f.timeScales[sample.TrackID])
+
+	packet := &rtp.Packet{
+		Header: rtp.Header{
+			Timestamp: uint32(sample.DTS * clockRate / timeScale),
+		},
+		Payload: payload,
+	}
+
+	if sample.CTS > 0 {
+		// wrong place for CTS, but we don't have another one
+		packet.ExtensionProfile = uint16(uint64(sample.CTS) * clockRate / timeScale)
+	}
+
+	return packet, nil
+}
+
+// sampleTable - stbl atoms of the trak in any order
+type sampleTable struct {
+	trackID uint32
+	stts    *iso.AtomStts
+	ctts    *iso.AtomCtts
+	stss    *iso.AtomStss
+	stsz    *iso.AtomStsz
+	stsc    *iso.AtomStsc
+	stco    *iso.AtomStco
+}
+
+func (f *File) readMoov(atoms []any) {
+	var table *sampleTable
+
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTkhd:
+			f.readTable(table)
+			table = &sampleTable{trackID: atom.TrackID}
+		case *iso.AtomTrex:
+			f.trex[atom.TrackID] = atom
+		}
+
+		if table == nil {
+			continue
+		}
+
+		switch atom := atom.(type) {
+		case *iso.AtomMdhd:
+			f.timeScales[table.trackID] = atom.TimeScale
+		case *iso.AtomStts:
+			table.stts = atom
+		case *iso.AtomCtts:
+			table.ctts = atom
+		case *iso.AtomStss:
+			table.stss = atom
+		case *iso.AtomStsz:
+			table.stsz = atom
+		case *iso.AtomStsc:
+			table.stsc = atom
+		case *iso.AtomStco:
+			table.stco = atom
+		}
+	}
+
+	f.readTable(table)
+}
+
+func (f *File) readTable(table *sampleTable) {
+	if table == nil || table.stts == nil || table.stsz == nil || table.stsc == nil || table.stco == nil {
+		return // fragmented file or broken track
+	}
+
+	trackID := table.trackID
+	stts, ctts, stss, stsz, stsc, stco := table.stts, table.ctts, table.stss, table.stsz, table.stsc, table.stco
+
+	timeScale := f.timeScales[trackID]
+	if f.Codec(trackID) == nil || timeScale == 0 {
+		return // unsupported track
+	}
+
+	count := int(stsz.SampleCount)
+	if stsz.SampleSize == 0 && len(stsz.Sizes) < count {
+		count = len(stsz.Sizes)
+	}
+
+	// run-length tables position
+	var sttsIdx, cttsIdx, stssIdx int
+	var sttsUsed, cttsUsed uint32
+	var dts uint64
+	var n int
+
+	for i, firstChunk := range stsc.FirstChunks {
+		lastChunk := uint32(len(stco.Offsets))
+		if i+1 < len(stsc.FirstChunks) {
+			lastChunk = stsc.FirstChunks[i+1] - 1
+		}
+
+		for chunk := firstChunk; chunk >= 1 && chunk <= lastChunk && int(chunk) <= len(stco.Offsets); chunk++ {
+			offset := int64(stco.Offsets[chunk-1])
+
+			for j := uint32(0); j < stsc.SamplesPerChunk[i] && n < count; j++ {
+				sample := &Sample{TrackID: trackID, Offset: offset, DTS: dts, Keyframe: stss == nil}
+
+				if stsz.SampleSize != 0 {
+					sample.Size = stsz.SampleSize
+				} else {
+					sample.Size = stsz.Sizes[n]
+				}
+
+				if ctts != nil && cttsIdx < len(ctts.SampleCounts) {
+					sample.CTS = ctts.SampleOffsets[cttsIdx]
+					if cttsUsed++; cttsUsed >= ctts.SampleCounts[cttsIdx] {
+						cttsIdx++
+						cttsUsed = 0
+					}
+				}
+
+				// sync samples numbers start from one
+				if stss != nil && stssIdx < len(stss.Samples) && stss.Samples[stssIdx] == uint32(n+1) {
+					sample.Keyframe = true
+					stssIdx++
+				}
+
+				if sttsIdx < len(stts.SampleCounts) {
+					dts += uint64(stts.SampleDeltas[sttsIdx])
+					if sttsUsed++; sttsUsed >= stts.SampleCounts[sttsIdx] {
+						sttsIdx++
+						sttsUsed = 0
+					}
+				}
+
+				f.addSample(sample, dts)
+
+				offset += int64(sample.Size)
+				n++
+			}
+		}
+	}
+}
+
+func (f *File) readMoof(atoms []any, moofOffset int64) {
+	var tfhd *iso.AtomTfhd
+	var dts uint64
+	var offset int64
+
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTfhd:
+			tfhd = atom
+			dts = f.nextDTS[atom.TrackID]
+
+			// default-base-is-moof or explicit base offset
+			if atom.BaseDataOffset != 0 {
+				offset = int64(atom.BaseDataOffset)
+			} else {
+				offset = moofOffset
+			}
+
+		case *iso.AtomTfdt:
+			dts = atom.DecodeTime
+
+		case *iso.AtomTrun:
+			if tfhd == nil {
+				continue
+			}
+
+			trackID := tfhd.TrackID
+			if f.Codec(trackID) == nil || f.timeScales[trackID] == 0 {
+				continue // unsupported track
+			}
+
+			// defaults from the tfhd, then from the trex
+			duration, size, flags := tfhd.SampleDuration, tfhd.SampleSize, tfhd.SampleFlags
+			if trex := f.trex[trackID]; trex != nil {
+				if duration == 0 {
+					duration = trex.SampleDuration
+				}
+				if size == 0 {
+					size = trex.SampleSize
+				}
+				if flags == 0 {
+					flags = trex.SampleFlags
+				}
+			}
+
+			// data offset is signed and relative to the base offset
+			if atom.Flags&iso.TrunDataOffset != 0 {
+				offset = moofOffset + int64(int32(atom.DataOffset))
+				if tfhd.BaseDataOffset != 0 {
+					offset = int64(tfhd.BaseDataOffset) + int64(int32(atom.DataOffset))
+				}
+			}
+
+			for i := 0; i < int(atom.SampleCount); i++ {
+				sample := &Sample{TrackID: trackID, Offset: offset, DTS: dts, Size: size}
+
+				if i < len(atom.SamplesSize) {
+					sample.Size = atom.SamplesSize[i]
+				}
+				if i < len(atom.SamplesCTS) {
+					sample.CTS = int32(atom.SamplesCTS[i]) // signed in version 1
+				}
+
+				sampleFlags := flags
+				if i == 0 && atom.Flags&iso.TrunFirstSampleFlags != 0 {
+					sampleFlags = atom.FirstSampleFlags
+				} else if i < len(atom.SamplesFlags) {
+					sampleFlags = atom.SamplesFlags[i]
+				}
+				sample.Keyframe = sampleFlags&sampleIsNonSync == 0
+
+				if i < len(atom.SamplesDuration) {
+					dts += uint64(atom.SamplesDuration[i])
+				} else {
+					dts += uint64(duration)
+				}
+
+				f.addSample(sample, dts)
+
+				offset += int64(sample.Size)
+			}
+
+			f.nextDTS[trackID] = dts
+		}
+	}
+}
+
+// addSample - with the end time of the sample for the file duration
+func (f *File) addSample(sample *Sample, end uint64) {
+	timeScale := f.timeScales[sample.TrackID]
+	sample.Time = scaleTime(sample.DTS, timeScale)
+	f.Samples = append(f.Samples, sample)
+
+	if d := scaleTime(end, timeScale); d > f.Duration {
+		f.Duration = d
+	}
+}
+
+func scaleTime(ts uint64, timeScale uint32) time.Duration {
+	scale := uint64(timeScale)
+	return time.Duration(ts/scale)*time.Second + time.Duration(ts%scale*uint64(time.Second)/scale)
+}
@@ -0,0 +1,192 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/iso"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testIFrame = []byte{0, 0, 0, 2, 0x65, 0x88}
+	testPFrame = []byte{0, 0, 0, 2, 0x41, 0x9A}
+)
+
+func TestFragmented(t *testing.T) {
+	muxer := &Muxer{}
+	muxer.AddTrack(&core.Codec{Name: core.CodecH264, ClockRate: 90000})
+	muxer.AddTrack(&core.Codec{Name: core.CodecAAC, ClockRate: 44100, Channels: 2, FmtpLine: "config=1210"})
+
+	b, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	for i := 0; i < 4; i++ {
+		payload := testPFrame
+		if i%2 == 0 {
+			payload = testIFrame
+		}
+		packet := &rtp.Packet{Header: rtp.Header{Timestamp: uint32(i * 45000)}, Payload: payload}
+		b = append(b, muxer.GetPayload(0, packet)...)
+
+		packet = &rtp.Packet{Header: rtp.Header{Timestamp: uint32(i * 22050)}, Payload: []byte{1, 2, 3}}
+		b = append(b, muxer.GetPayload(1, packet)...)
+	}
+
+	f, err := NewFile(bytes.NewReader(b), int64(len(b)))
+	require.Nil(t, err)
+	require.Len(t, f.Medias, 2)
+	require.Len(t, f.Samples, 8)
+
+	video := f.Samples[6]
+	require.Equal(t, core.CodecH264, f.Codec(video.TrackID).Name)
+	require.True(t, video.Keyframe)
+
+	packet, err := f.ReadPacket(video)
+	require.Nil(t, err)
+	require.Equal(t, testIFrame, packet.Payload)
+
+	require.Equal(t, 6, f.Seek(1200*time.Millisecond))
+}
+
+func TestRegular(t *testing.T) {
+	testRegular(t, false)
+	testRegular(t, true)
+}
+
+// testRegular - stco is the last table or the first one (any order is valid)
+func testRegular(t *testing.T, stcoFirst bool) {
+	mv := iso.NewMovie(1024)
+	mv.WriteFileType()
+
+	// two chunks with two samples
+	mv.StartAtom(iso.Mdat)
+	offset := uint32(len(mv.Bytes()))
+	mv.Write(testIFrame)
+	mv.Write(testPFrame)
+	mv.Write(testIFrame)
+	mv.Write(testPFrame)
+	mv.EndAtom()
+
+	mv.StartAtom(iso.Moov)
+	mv.WriteMovieHeader()
+	mv.StartAtom(iso.MoovTrak)
+	mv.WriteTrackHeader(1, 1920, 1080)
+	mv.StartAtom(iso.MoovTrakMdia)
+	mv.WriteMediaHeader(1000)
+	mv.WriteMediaHandler("vide", "VideoHandler")
+	mv.StartAtom(iso.MoovTrakMdiaMinf)
+	mv.StartAtom(iso.MoovTrakMdiaMinfStbl)
+
+	mv.StartAtom(iso.MoovTrakMdiaMinfStblStsd)
+	mv.Skip(4)        // version and flags
+	mv.WriteUint32(1) // entry count
+	sps := []byte{0x67, 0x42, 0x00, 0x0a, 0xf8, 0x41, 0xa2}
+	pps := []byte{0x68, 0xce, 0x38, 0x80}
+	mv.WriteVideo(core.CodecH264, 1920, 1080, h264.EncodeConfig(sps, pps))
+	mv.EndAtom()
+
+	if stcoFirst {
+		writeTable(mv, iso.MoovTrakMdiaMinfStblStco, 2, offset, offset+12)
+	}
+	writeTable(mv, iso.MoovTrakMdiaMinfStblStts, 1, 4, 40)   // entries, count, delta
+	writeTable(mv, iso.MoovTrakMdiaMinfStblStss, 2, 1, 3)    // entries, samples
+	writeTable(mv, iso.MoovTrakMdiaMinfStblStsz, 6, 4)       // sample size, count
+	writeTable(mv, iso.MoovTrakMdiaMinfStblStsc, 1, 1, 2, 1) // entries, first chunk, samples, desc
+	if !stcoFirst {
+		writeTable(mv, iso.MoovTrakMdiaMinfStblStco, 2, offset, offset+12)
+	}
+
+	mv.EndAtom() // STBL
+	mv.EndAtom() // MINF
+	mv.EndAtom() // MDIA
+	mv.EndAtom() // TRAK
+	mv.EndAtom() // MOOV
+
+	b := mv.Bytes()
+	f, err := NewFile(bytes.NewReader(b), int64(len(b)))
+	require.Nil(t, err)
+	require.Len(t, f.Samples, 4)
+	require.Equal(t, 160*time.Millisecond, f.Duration)
+
+	sample := f.Samples[2]
+	require.Equal(t, int64(offset+12), sample.Offset)
+	require.Equal(t, 80*time.Millisecond, sample.Time)
+	require.True(t, sample.Keyframe)
+	require.False(t, f.Samples[3].Keyframe)
+
+	packet, err := f.ReadPacket(sample)
+	require.Nil(t, err)
+	require.Equal(t, testIFrame, packet.Payload)
+	require.Equal(t, uint32(80*90), packet.Timestamp)
+
+	require.Equal(t, 2, f.Seek(100*time.Millisecond))
+	require.Equal(t, 0, f.Seek(50*time.Millisecond))
+}
+
+func TestFirstSampleFlags(t *testing.T) {
+	muxer := &Muxer{}
+	muxer.AddTrack(&core.Codec{Name: core.CodecH264, ClockRate: 90000})
+
+	b, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	// two samples, default flags - non-sync, first sample flags - zero (sync)
+	mv := iso.NewMovie(1024)
+	mv.StartAtom(iso.Moof)
+	writeTable(mv, iso.MoofMfhd, 1) // sequence number
+	mv.StartAtom(iso.MoofTraf)
+	mv.StartAtom(iso.MoofTrafTfhd)
+	mv.Skip(1) // version
+	mv.WriteUint24(iso.TfhdDefaultSampleDuration | iso.TfhdDefaultSampleSize | iso.TfhdDefaultSampleFlags | iso.TfhdDefaultBaseIsMoof)
+	mv.WriteUint32(1)    // track ID
+	mv.WriteUint32(9000) // duration
+	mv.WriteUint32(6)    // size
+	mv.WriteUint32(iso.SampleVideoNonIFrame)
+	mv.EndAtom()
+	mv.StartAtom(iso.MoofTrafTrun)
+	mv.Skip(1) // version
+	mv.WriteUint24(iso.TrunDataOffset | iso.TrunFirstSampleFlags)
+	mv.WriteUint32(2) // sample count
+	mv.WriteUint32(0) // data offset, will be patched
+	mv.WriteUint32(0) // first sample flags
+	mv.EndAtom()      // TRUN
+	mv.EndAtom()      // TRAF
+	mv.EndAtom()      // MOOF
+	moof := mv.Bytes()
+
+	// data offset from the moof start to the mdat data
+	i := bytes.Index(moof, []byte(iso.MoofTrafTrun))
+	binary.BigEndian.PutUint32(moof[i+12:], uint32(len(moof)+8))
+
+	mv = iso.NewMovie(1024)
+	mv.WriteData(append(append([]byte{}, testIFrame...), testPFrame...))
+
+	b = append(b, moof...)
+	b = append(b, mv.Bytes()...)
+
+	f, err := NewFile(bytes.NewReader(b), int64(len(b)))
+	require.Nil(t, err)
+	require.Len(t, f.Samples, 2)
+	require.True(t, f.Samples[0].Keyframe)
+	require.False(t, f.Samples[1].Keyframe)
+
+	packet, err := f.ReadPacket(f.Samples[1])
+	require.Nil(t, err)
+	require.Equal(t, testPFrame, packet.Payload)
+}
+
+// writeTable - full atom with values after the version and flags
+func writeTable(mv *iso.Movie, name string, values ...uint32) {
+	mv.StartAtom(name)
+	mv.Skip(4) // version and flags
+	for _, v := range values {
+		mv.WriteUint32(v)
+	}
+	mv.EndAtom()
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/iso"
 )
 
 // ParseQuery - like usual parse, but with mp4 param handler
@@ -33,6 +34,11 @@ func ParseQuery(query map[string][]string) []*core.Media {
 			return medias // legacy
 		}
 
+		medias[0].Codecs = append(medias[0].Codecs,
+			&core.Codec{Name: core.CodecAV1},
+			&core.Codec{Name: core.CodecVP9},
+		)
+
 		medias[1].Codecs = append(medias[1].Codecs,
 			&core.Codec{Name: core.CodecPCMA},
 			&core.Codec{Name: core.CodecPCMU},
@@ -67,6 +73,12 @@ func ParseCodecs(codecs string, parseAudio bool) (medias []*core.Media) {
 		case MimeH265:
 			codec := &core.Codec{Name: core.CodecH265}
 			videos = append(videos, codec)
+		case MimeAV1:
+			codec := &core.Codec{Name: core.CodecAV1}
+			videos = append(videos, codec)
+		case MimeVP9:
+			codec := &core.Codec{Name: core.CodecVP9}
+			videos = append(videos, codec)
 		case MimeAAC:
 			codec := &core.Codec{Name: core.CodecAAC}
 			audios = append(audios, codec)
@@ -164,3 +176,30 @@ func PatchVideoScale(init []byte, scaleX, scaleY int) bool {
 
 	return true
 }
+
+// PatchFragment - update sequence number and base media decode time of fMP4 fragment.
+// Useful for joining fragments from different files into one continuous stream.
+func PatchFragment(moof []byte, seq uint32, dts uint64) bool {
+	// search fragment header atom
+	i := bytes.Index(moof, []byte(iso.MoofMfhd))
+	if i < 0 || i+12 > len(moof) {
+		return false
+	}
+
+	// skip name, version and flags
+	binary.BigEndian.PutUint32(moof[i+8:], seq)
+
+	// search decode time atom
+	i = bytes.Index(moof, []byte(iso.MoofTrafTfdt))
+	if i < 0 || i+16 > len(moof) {
+		return false
+	}
+
+	if moof[i+4] == 1 {
+		binary.BigEndian.PutUint64(moof[i+8:], dts)
+	} else {
+		binary.BigEndian.PutUint32(moof[i+8:], uint32(dts))
+	}
+
+	return true
+}
@@ -1,13 +1,17 @@
 package mp4
 
 import (
+	"github.com/AlexxIT/go2rtc/pkg/av1"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/vp9"
 )
 
 const (
 	MimeH264 = "avc1.640029"
 	MimeH265 = "hvc1.1.6.L153.B0"
+	MimeAV1  = "av01.0.08M.08"
+	MimeVP9  = "vp09.00.41.08"
 	MimeAAC  = "mp4a.40.2"
 	MimeFlac = "flac"
 	MimeOpus = "opus"
@@ -28,6 +32,10 @@ func MimeCodecs(codecs []*core.Codec) string {
 			// H.265 profile=main level=5.1
 			// hvc1 - supported in Safari, hev1 - doesn't, both supported in Chrome
 			s += MimeH265
+		case core.CodecAV1:
+			s += "av01." + av1.GetProfileLevelID(codec.FmtpLine)
+		case core.CodecVP9:
+			s += "vp09." + vp9.GetProfileLevelID(codec.FmtpLine)
 		case core.CodecAAC:
 			s += MimeAAC
 		case core.CodecOpus:
@@ -3,24 +3,54 @@ package mp4
 import (
 	"encoding/hex"
 
+	"github.com/AlexxIT/go2rtc/pkg/av1"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
 	"github.com/AlexxIT/go2rtc/pkg/h265"
 	"github.com/AlexxIT/go2rtc/pkg/iso"
+	"github.com/AlexxIT/go2rtc/pkg/vp9"
 	"github.com/pion/rtp"
 )
 
 type Muxer struct {
-	index  uint32
-	dts    []uint64
-	pts    []uint32
-	codecs []*core.Codec
+	index   uint32
+	dts     []uint64
+	pts     []uint32
+	codecs  []*core.Codec
+	headers [][]byte // in-band AV1 sequence header OBU or VP9 keyframe header
 }
 
 func (m *Muxer) AddTrack(codec *core.Codec) {
 	m.dts = append(m.dts, 0)
 	m.pts = append(m.pts, 0)
 	m.codecs = append(m.codecs, codec)
+	m.headers = append(m.headers, nil)
+}
+
+// vp9HeaderSize - enough for the VP9 uncompressed header up to the frame size
+const vp9HeaderSize = 32
+
+// SetHeader - AV1 and VP9 config and picture size from the keyframe,
+// SDP fmtp doesn't have them
+func (m *Muxer) SetHeader(trackID byte, keyframe []byte) {
+	switch m.codecs[trackID].Name {
+	case core.CodecAV1:
+		if obu := av1.GetSequenceHeader(keyframe); obu != nil {
+			m.headers[trackID] = append([]byte(nil), obu...)
+		}
+	case core.CodecVP9:
+		m.headers[trackID] = append([]byte(nil), keyframe[:min(len(keyframe), vp9HeaderSize)]...)
+	}
+}
+
+// NeedHeader - AV1 or VP9 track without the header from the keyframe
+func (m *Muxer) NeedHeader() bool {
+	for i, codec := range m.codecs {
+		if (codec.Name == core.CodecAV1 || codec.Name == core.CodecVP9) && m.headers[i] == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *Muxer) GetInit() ([]byte, error) {
@@ -81,6 +111,37 @@ func (m *Muxer) GetInit() ([]byte, error) {
 				uint32(i+1), codec.Name, codec.ClockRate, width, height, h265.EncodeConfig(vps, sps, pps),
 			)
 
+		case core.CodecAV1:
+			var width, height uint16
+			var conf []byte
+			if s := av1.DecodeSequenceHeader(m.headers[i]); s != nil {
+				width = uint16(s.MaxFrameWidth)
+				height = uint16(s.MaxFrameHeight)
+				conf = av1.EncodeConfig(m.headers[i])
+			} else {
+				// without sequence header, decoders will get it in-band with the keyframes
+				width = 1920
+				height = 1080
+				conf = av1.FmtpToConfig(codec.FmtpLine)
+			}
+
+			mv.WriteVideoTrack(uint32(i+1), codec.Name, codec.ClockRate, width, height, conf)
+
+		case core.CodecVP9:
+			var width, height uint16
+			var conf []byte
+			if h := vp9.DecodeHeader(m.headers[i]); h != nil {
+				width = h.Width
+				height = h.Height
+				conf = vp9.EncodeConfig(h)
+			} else {
+				width = 1920
+				height = 1080
+				conf = vp9.FmtpToConfig(codec.FmtpLine)
+			}
+
+			mv.WriteVideoTrack(uint32(i+1), codec.Name, codec.ClockRate, width, height, conf)
+
 		case core.CodecAAC:
 			s := core.Between(codec.FmtpLine, "config=", ";")
 			b, err := hex.DecodeString(s)
@@ -142,6 +203,18 @@ func (m *Muxer) GetPayload(trackID byte, packet *rtp.Packet) []byte {
 		} else {
 			flags = iso.SampleVideoNonIFrame
 		}
+	case core.CodecAV1:
+		if av1.IsKeyframe(packet.Payload) {
+			flags = iso.SampleVideoIFrame
+		} else {
+			flags = iso.SampleVideoNonIFrame
+		}
+	case core.CodecVP9:
+		if vp9.IsKeyframe(packet.Payload) {
+			flags = iso.SampleVideoIFrame
+		} else {
+			flags = iso.SampleVideoNonIFrame
+		}
 	case core.CodecAAC:
 		duration = 1024         // important for Apple Finder and QuickTime
 		flags = iso.SampleAudio // not important?
@@ -170,3 +243,11 @@ func (m *Muxer) GetPayload(trackID byte, packet *rtp.Packet) []byte {
 
 	return mv.Bytes()
 }
+
+// GetEventMessage - emsg box for the next sample of the track, should be placed
+// before the sample fragment
+func (m *Muxer) GetEventMessage(trackID byte, scheme string, data []byte) []byte {
+	mv := iso.NewMovie(256 + len(data))
+	mv.WriteEventMessage(scheme, "", m.codecs[trackID].ClockRate, m.dts[trackID], m.index+1, data)
+	return mv.Bytes()
+}
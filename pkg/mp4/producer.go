@@ -0,0 +1,126 @@
+package mp4
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// Producer - MP4 file reader with real-time pacing, seeking and looping
+type Producer struct {
+	core.Connection
+
+	Loop bool
+	Seek time.Duration // start position inside the file
+
+	file *File
+	done chan struct{}
+	once sync.Once
+}
+
+func OpenFile(path string) (*Producer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	file, err := NewFile(f, info.Size())
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if len(file.Samples) == 0 {
+		_ = f.Close()
+		return nil, errors.New("mp4: file without supported tracks")
+	}
+
+	return &Producer{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: "mp4",
+			Protocol:   "file",
+			Medias:     file.Medias,
+			Transport:  f,
+		},
+		file: file,
+		done: make(chan struct{}),
+	}, nil
+}
+
+func (c *Producer) Start() error {
+	receivers := map[uint32]*core.Receiver{}
+	for _, receiver := range c.Receivers {
+		receivers[c.file.GetTrackID(receiver.Codec)] = receiver
+	}
+
+	start := c.file.Seek(c.Seek)
+	if start >= len(c.file.Samples) {
+		return io.EOF
+	}
+
+	// duration of one loop from the start position
+	startTime := c.file.Samples[start].Time
+	loopTime := c.file.Duration - startTime
+
+	// timestamps continue to grow on each loop
+	offsets := map[uint32]uint32{}
+	var loopOffset time.Duration
+
+	t0 := time.Now()
+
+	for {
+		for _, sample := range c.file.Samples[start:] {
+			receiver := receivers[sample.TrackID]
+			if receiver == nil {
+				continue
+			}
+
+			if d := time.Until(t0.Add(loopOffset + sample.Time - startTime)); d > 0 {
+				select {
+				case <-time.After(d):
+				case <-c.done:
+					return nil
+				}
+			}
+
+			packet, err := c.file.ReadPacket(sample)
+			if err != nil {
+				return err
+			}
+
+			packet.Timestamp += offsets[sample.TrackID]
+
+			c.Recv += len(packet.Payload)
+			receiver.WriteRTP(packet)
+		}
+
+		if !c.Loop || loopTime <= 0 {
+			return io.EOF
+		}
+
+		loopOffset += loopTime
+
+		for trackID := range receivers {
+			clockRate := int64(c.file.Codec(trackID).ClockRate)
+			offsets[trackID] += uint32(int64(loopTime) * clockRate / int64(time.Second))
+		}
+	}
+}
+
+func (c *Producer) Stop() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return c.Connection.Stop()
+}
@@ -0,0 +1,135 @@
+package mp4
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+// testFile - 300ms of H264 video, six frames with 50ms interval, keyframes at 0 and 150ms
+func testFile(t *testing.T) string {
+	muxer := &Muxer{}
+	muxer.AddTrack(&core.Codec{Name: core.CodecH264, ClockRate: 90000})
+
+	b, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	for i := 0; i < 6; i++ {
+		payload := testPFrame
+		if i%3 == 0 {
+			payload = testIFrame
+		}
+		// muxer takes the sample duration from the previous timestamp
+		packet := &rtp.Packet{Header: rtp.Header{Timestamp: uint32((i + 1) * 4500)}, Payload: payload}
+		b = append(b, muxer.GetPayload(0, packet)...)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.mp4")
+	require.Nil(t, os.WriteFile(path, b, 0644))
+	return path
+}
+
+type testPacket struct {
+	timestamp uint32
+	keyframe  bool
+	time      time.Duration // since the start
+}
+
+// startProducer - start the producer in the background, packets are sent to the channel
+func startProducer(t *testing.T, prod *Producer) (<-chan testPacket, <-chan error) {
+	media := prod.Medias[0]
+	receiver, err := prod.GetTrack(media, media.Codecs[0])
+	require.Nil(t, err)
+
+	packets := make(chan testPacket, 100)
+	t0 := time.Now()
+
+	sender := core.NewSender(media, media.Codecs[0])
+	sender.Handler = func(packet *rtp.Packet) {
+		packets <- testPacket{
+			timestamp: packet.Timestamp,
+			keyframe:  h264.IsKeyframe(packet.Payload),
+			time:      time.Since(t0),
+		}
+	}
+	sender.HandleRTP(receiver)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- prod.Start()
+	}()
+
+	return packets, errs
+}
+
+func nextPacket(t *testing.T, packets <-chan testPacket) testPacket {
+	select {
+	case packet := <-packets:
+		return packet
+	case <-time.After(time.Second):
+		require.FailNow(t, "packet timeout")
+	}
+	return testPacket{}
+}
+
+func TestProducerPacing(t *testing.T) {
+	prod, err := OpenFile(testFile(t))
+	require.Nil(t, err)
+	defer prod.Stop()
+
+	packets, errs := startProducer(t, prod)
+
+	for i := 0; i < 6; i++ {
+		packet := nextPacket(t, packets)
+		require.Equal(t, uint32(i*4500), packet.timestamp)
+		require.Equal(t, i%3 == 0, packet.keyframe)
+		// real-time: not earlier than the sample time
+		require.GreaterOrEqual(t, packet.time, time.Duration(i)*50*time.Millisecond)
+	}
+
+	require.Equal(t, io.EOF, <-errs)
+}
+
+func TestProducerLoop(t *testing.T) {
+	prod, err := OpenFile(testFile(t))
+	require.Nil(t, err)
+
+	prod.Loop = true
+	packets, errs := startProducer(t, prod)
+
+	// timestamps continue to grow after the end of the file
+	for i := 0; i < 9; i++ {
+		packet := nextPacket(t, packets)
+		require.Equal(t, uint32(i*4500), packet.timestamp)
+	}
+
+	require.Nil(t, prod.Stop())
+	require.Nil(t, <-errs)
+}
+
+func TestProducerSeek(t *testing.T) {
+	prod, err := OpenFile(testFile(t))
+	require.Nil(t, err)
+	defer prod.Stop()
+
+	// start from the keyframe before the seek time without waiting
+	prod.Seek = 200 * time.Millisecond
+	packets, errs := startProducer(t, prod)
+
+	packet := nextPacket(t, packets)
+	require.Equal(t, uint32(3*4500), packet.timestamp)
+	require.True(t, packet.keyframe)
+	require.Less(t, packet.time, 50*time.Millisecond)
+
+	require.Equal(t, uint32(4*4500), nextPacket(t, packets).timestamp)
+	require.Equal(t, uint32(5*4500), nextPacket(t, packets).timestamp)
+
+	require.Equal(t, io.EOF, <-errs)
+}
@@ -116,6 +116,30 @@ func (c *Consumer) WriteTo(wr io.Writer) (int64, error) {
 	return c.wr.WriteTo(wr)
 }
 
+// Split - splits WriteTo output to the header (PAT+PMT) and the PES packets,
+// keyframe - PES with the random access indicator (for the shared muxer)
+func (c *Consumer) Split(b []byte, yield func(b []byte, init, keyframe bool)) {
+	var start int
+	var init, keyframe bool
+
+	for i := 0; i+PacketSize <= len(b); i += PacketSize {
+		pid, pusi, random, _ := ParsePacket(b[i : i+PacketSize])
+		psi := IsPSI(pid)
+
+		if i > start && (psi != init || pusi && !psi) {
+			yield(b[start:i], init, keyframe)
+			start = i
+		}
+		if i == start {
+			init, keyframe = psi, random
+		}
+	}
+
+	if start < len(b) {
+		yield(b[start:], init, keyframe)
+	}
+}
+
 //func TimestampFromRTP(rtp *rtp.Packet, codec *core.Codec) {
 //	if codec.ClockRate == ClockRate {
 //		return
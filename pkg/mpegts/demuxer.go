@@ -344,7 +344,8 @@ type PES struct {
 	Payload    []byte // from PES body
 	Size       int    // from PES header, can be 0
 
-	wr *bits.Writer
+	wr       *bits.Writer
+	keyframe bool // for the random access indicator
 }
 
 func (p *PES) SetBuffer(size uint16, b []byte) {
@@ -4,7 +4,9 @@ import (
 	"encoding/binary"
 
 	"github.com/AlexxIT/go2rtc/pkg/bits"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
 	"github.com/AlexxIT/go2rtc/pkg/h264/annexb"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
 )
 
 type Muxer struct {
@@ -46,7 +48,11 @@ func (m *Muxer) GetPayload(pid uint16, timestamp uint32, payload []byte) []byte
 	pes := m.pes[pid]
 
 	switch pes.StreamType {
-	case StreamTypeH264, StreamTypeH265:
+	case StreamTypeH264:
+		pes.keyframe = h264.IsKeyframe(payload)
+		payload = annexb.DecodeAVCCWithAUD(payload)
+	case StreamTypeH265:
+		pes.keyframe = h265.IsKeyframe(payload)
 		payload = annexb.DecodeAVCCWithAUD(payload)
 	}
 
@@ -97,6 +103,11 @@ const patPID = 0
 const pmtPID = 0x1000
 const pes0PID = 0x100
 
+// IsPSI - PAT or PMT packet from the Muxer header
+func IsPSI(pid uint16) bool {
+	return pid == patPID || pid == pmtPID
+}
+
 func (m *Muxer) writePAT(wr *bits.Writer) {
 	m.writeHeader(wr, patPID)
 	i := wr.Len() + 1 // start for CRC32
@@ -147,6 +158,7 @@ func (m *Muxer) writePES(wr *bits.Writer, pid uint16, pes *PES) {
 	const flagPUSI = 0b01000000_00000000
 	const flagAdaptation = 0b00100000
 	const flagPayload = 0b00010000
+	const flagRandomAccess = 0b01000000
 
 	wr.WriteByte(SyncByte)
 
@@ -158,22 +170,64 @@ func (m *Muxer) writePES(wr *bits.Writer, pid uint16, pes *PES) {
 
 	counter := byte(pes.Sequence) & 0xF
 
-	if size := len(pes.Payload); size < PacketSize-4 {
+	// random access indicator in the first packet of the keyframe (for HLS segmenters)
+	var adFlags byte
+	if pes.Size != 0 && pes.keyframe {
+		adFlags = flagRandomAccess
+	}
+
+	size := len(pes.Payload)
+	if adFlags != 0 && size > PacketSize-4-2 {
+		size = PacketSize - 4 - 2 // adaptation field length and flags
+	}
+
+	if size < PacketSize-4 {
 		wr.WriteByte(flagAdaptation | flagPayload | counter) // adaptation + payload
 
 		// for 183 payload will be zero
 		adSize := PacketSize - 4 - 1 - byte(size)
 		wr.WriteByte(adSize)
-		wr.WriteBytes(make([]byte, adSize)...)
-
-		wr.WriteBytes(pes.Payload...)
-		pes.Payload = nil
+		if adSize > 0 {
+			wr.WriteByte(adFlags)
+			wr.WriteBytes(make([]byte, adSize-1)...)
+		}
 	} else {
 		wr.WriteByte(flagPayload | counter) // only payload
+	}
+
+	wr.WriteBytes(pes.Payload[:size]...)
+	pes.Payload = pes.Payload[size:]
+}
+
+// ParsePacket - PID, PES start and random access (keyframe) flags and PTS of the TS packet
+func ParsePacket(b []byte) (pid uint16, start, keyframe bool, pts uint32) {
+	if len(b) < PacketSize || b[0] != SyncByte {
+		return
+	}
+
+	pid = binary.BigEndian.Uint16(b[1:]) & 0x1FFF
+	start = b[1]&0x40 != 0
+
+	i := 4
+	if b[3]&0x20 != 0 {
+		if size := int(b[4]); size > 0 {
+			keyframe = b[5]&0x40 != 0
+			i += size
+		}
+		i++
+	}
 
-		wr.WriteBytes(pes.Payload[:PacketSize-4]...)
-		pes.Payload = pes.Payload[PacketSize-4:]
+	// PES header: start code, stream ID, length, flags with PTS indicator
+	if start && i+14 <= PacketSize && b[i] == 0 && b[i+1] == 0 && b[i+2] == 1 && b[i+7]&0x80 != 0 {
+		pts = ReadTime(b[i+9:])
 	}
+
+	return
+}
+
+func ReadTime(b []byte) uint32 {
+	_ = b[4] // bounds
+	return uint32(b[0]&0x0E)<<29 | uint32(b[1])<<22 | uint32(b[2]&0xFE)<<14 | uint32(b[3])<<7 | uint32(b[4])>>1
 }
 
 func (m *Muxer) writeHeader(wr *bits.Writer, pid uint16) {
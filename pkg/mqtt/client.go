@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -14,6 +15,7 @@ const Timeout = time.Second * 5
 type Client struct {
 	conn net.Conn
 	mid  uint16
+	mu   sync.Mutex // protects writes from different goroutines
 }
 
 func NewClient(conn net.Conn) *Client {
@@ -21,11 +23,19 @@ func NewClient(conn net.Conn) *Client {
 }
 
 func (c *Client) Connect(clientID, username, password string) (err error) {
+	return c.connect(NewConnect(clientID, username, password))
+}
+
+// ConnectWill - connect with keepalive and retained will message (optional)
+func (c *Client) ConnectWill(clientID, username, password string, keepalive uint16, willTopic string, willPayload []byte) error {
+	return c.connect(NewConnectWill(clientID, username, password, keepalive, willTopic, willPayload))
+}
+
+func (c *Client) connect(msg *Message) (err error) {
 	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
 		return
 	}
 
-	msg := NewConnect(clientID, username, password)
 	if _, err = c.conn.Write(msg.b); err != nil {
 		return
 	}
@@ -43,24 +53,51 @@ func (c *Client) Connect(clientID, username, password string) (err error) {
 }
 
 func (c *Client) Subscribe(topic string) (err error) {
-	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
-		return
-	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	c.mid++
-	msg := NewSubscribe(c.mid, topic, 1)
-	_, err = c.conn.Write(msg.b)
-	return
+	return c.write(NewSubscribe(c.mid, topic, 1).b)
 }
 
 func (c *Client) Publish(topic string, payload []byte) (err error) {
-	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
-		return
-	}
+	return c.PublishQOS1(c.NextID(), topic, payload, false)
+}
 
+// NextID - new packet ID, useful for waiting PUBACK
+func (c *Client) NextID() uint16 {
+	c.mu.Lock()
 	c.mid++
-	msg := NewPublishQOS1(c.mid, topic, payload)
-	_, err = c.conn.Write(msg.b)
+	mid := c.mid
+	c.mu.Unlock()
+	return mid
+}
+
+func (c *Client) PublishQOS1(mid uint16, topic string, payload []byte, retain bool) error {
+	var msg *Message
+	if retain {
+		msg = NewPublishRetain(mid, topic, payload)
+	} else {
+		msg = NewPublishQOS1(mid, topic, payload)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.write(msg.b)
+}
+
+func (c *Client) Ping() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.write([]byte{PINGREQ, 0})
+}
+
+func (c *Client) write(b []byte) (err error) {
+	// write deadline only, so it won't break reading from another goroutine
+	if err = c.conn.SetWriteDeadline(time.Now().Add(Timeout)); err != nil {
+		return
+	}
+	_, err = c.conn.Write(b)
 	return
 }
 
@@ -69,44 +106,93 @@ func (c *Client) Read() (string, []byte, error) {
 		return "", nil, err
 	}
 
+	packet, err := c.ReadPacket()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if packet.Type != PUBLISH {
+		return "", nil, nil
+	}
+
+	return packet.Topic, packet.Payload, nil
+}
+
+// Packet - received control packet. ID is used for PUBLISH with QoS 1 and PUBACK.
+type Packet struct {
+	Type    byte
+	ID      uint16
+	Topic   string
+	Payload []byte
+}
+
+// ReadPacket - read any control packet without changing the deadline.
+// PUBLISH with QoS 1 is acknowledged automatically.
+func (c *Client) ReadPacket() (*Packet, error) {
 	b := make([]byte, 1)
 	if _, err := io.ReadFull(c.conn, b); err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
 	size, err := ReadLen(c.conn)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
 	b0 := b[0]
 	b = make([]byte, size)
 	if _, err = io.ReadFull(c.conn, b); err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
-	if b0&0xF0 != PUBLISH {
-		return "", nil, nil
+	packet := &Packet{Type: b0 & 0xF0}
+
+	if packet.Type == PUBACK {
+		if size < 2 {
+			return nil, errors.New("wrong puback size")
+		}
+		packet.ID = binary.BigEndian.Uint16(b)
+		return packet, nil
 	}
 
-	i := binary.BigEndian.Uint16(b)
-	if uint32(i) > size {
-		return "", nil, errors.New("wrong topic size")
+	if packet.Type != PUBLISH {
+		return packet, nil
 	}
 
-	b = b[2:]
+	if size < 2 {
+		return nil, errors.New("wrong publish size")
+	}
+
+	i := uint32(binary.BigEndian.Uint16(b)) + 2
+	if i > size {
+		return nil, errors.New("wrong topic size")
+	}
+
+	packet.Topic = string(b[2:i])
 
 	if qos := (b0 >> 1) & 0b11; qos == 0 {
-		return string(b[:i]), b[i:], nil
+		packet.Payload = b[i:]
+		return packet, nil
 	}
 
+	if i+2 > size {
+		return nil, errors.New("wrong publish size")
+	}
+
+	packet.ID = binary.BigEndian.Uint16(b[i:])
+	packet.Payload = b[i+2:]
+
 	// response with packet ID
-	_, _ = c.conn.Write([]byte{PUBACK, 2, b[i], b[i+1]})
+	c.mu.Lock()
+	_ = c.write([]byte{PUBACK, 2, b[i], b[i+1]})
+	c.mu.Unlock()
 
-	return string(b[2:i]), b[i+2:], nil
+	return packet, nil
 }
 
 func (c *Client) Close() error {
-	// TODO: Teardown
+	c.mu.Lock()
+	_ = c.write([]byte{DISCONNECT, 0})
+	c.mu.Unlock()
 	return c.conn.Close()
 }
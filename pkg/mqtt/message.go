@@ -10,13 +10,17 @@ type Message struct {
 
 // https://docs.oasis-open.org/mqtt/mqtt/v5.0/mqtt-v5.0.html
 const (
-	CONNECT   = 0x10
-	CONNACK   = 0x20
-	PUBLISH   = 0x30
-	PUBACK    = 0x40
-	SUBSCRIBE = 0x82
-	SUBACK    = 0x90
-	QOS1      = 0x02
+	CONNECT    = 0x10
+	CONNACK    = 0x20
+	PUBLISH    = 0x30
+	PUBACK     = 0x40
+	SUBSCRIBE  = 0x82
+	SUBACK     = 0x90
+	PINGREQ    = 0xC0
+	PINGRESP   = 0xD0
+	DISCONNECT = 0xE0
+	QOS1       = 0x02
+	RETAIN     = 0x01
 )
 
 func (m *Message) WriteByte(b byte) {
@@ -52,6 +56,9 @@ func (m *Message) Bytes() []byte {
 
 const (
 	flagCleanStart = 0x02
+	flagWill       = 0x04
+	flagWillQOS1   = 0x08
+	flagWillRetain = 0x20
 	flagUsername   = 0x80
 	flagPassword   = 0x40
 )
@@ -72,6 +79,49 @@ func NewConnect(clientID, username, password string) *Message {
 	return m
 }
 
+// NewConnectWill - connect with custom keepalive (in seconds) and optional
+// retained will message. Username and password are optional.
+func NewConnectWill(clientID, username, password string, keepalive uint16, willTopic string, willPayload []byte) *Message {
+	flags := byte(flagCleanStart)
+	size := 12 + len(clientID)
+
+	if willTopic != "" {
+		flags |= flagWill | flagWillQOS1 | flagWillRetain
+		size += 4 + len(willTopic) + len(willPayload)
+	}
+	if username != "" {
+		flags |= flagUsername
+		size += 2 + len(username)
+	}
+	if password != "" {
+		flags |= flagPassword
+		size += 2 + len(password)
+	}
+
+	m := &Message{}
+	m.WriteByte(CONNECT)
+	m.WriteLen(size)
+
+	m.WriteString("MQTT")
+	m.WriteByte(4) // MQTT version
+	m.WriteByte(flags)
+	m.WriteUint16(keepalive)
+
+	m.WriteString(clientID)
+	if willTopic != "" {
+		m.WriteString(willTopic)
+		m.WriteUint16(uint16(len(willPayload)))
+		m.WriteBytes(willPayload)
+	}
+	if username != "" {
+		m.WriteString(username)
+	}
+	if password != "" {
+		m.WriteString(password)
+	}
+	return m
+}
+
 func NewSubscribe(mid uint16, topic string, qos byte) *Message {
 	m := &Message{}
 	m.WriteByte(SUBSCRIBE)
@@ -94,8 +144,16 @@ func NewPublish(topic string, payload []byte) *Message {
 }
 
 func NewPublishQOS1(mid uint16, topic string, payload []byte) *Message {
+	return newPublishQOS1(PUBLISH|QOS1, mid, topic, payload)
+}
+
+func NewPublishRetain(mid uint16, topic string, payload []byte) *Message {
+	return newPublishQOS1(PUBLISH|QOS1|RETAIN, mid, topic, payload)
+}
+
+func newPublishQOS1(header byte, mid uint16, topic string, payload []byte) *Message {
 	m := &Message{}
-	m.WriteByte(PUBLISH | QOS1)
+	m.WriteByte(header)
 	m.WriteLen(4 + len(topic) + len(payload))
 
 	m.WriteString(topic)
@@ -0,0 +1,226 @@
+package mqtt
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Session - persistent connection to the broker with auto reconnect,
+// keepalive pings and waiting PUBACK for published messages
+type Session struct {
+	Dial func() (net.Conn, error)
+
+	ClientID string
+	Username string
+	Password string
+
+	KeepAlive time.Duration // default 30 seconds
+
+	// retained will message, sent by broker after connection loss
+	WillTopic   string
+	WillPayload []byte
+
+	OnConnect func()
+	OnMessage func(topic string, payload []byte)
+	OnError   func(err error)
+
+	client *Client
+	topics []string
+	acks   map[uint16]chan struct{}
+	closed bool
+	mu     sync.Mutex
+}
+
+var ErrNotConnected = errors.New("mqtt: not connected")
+
+// Run - connect and reconnect with backoff (1s, 2s, 4s... max 1 min) until Close
+func (s *Session) Run() {
+	if s.KeepAlive == 0 {
+		s.KeepAlive = 30 * time.Second
+	}
+
+	backoff := time.Second
+
+	for {
+		start := time.Now()
+
+		err := s.serve()
+
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+
+		if closed {
+			return
+		}
+
+		if s.OnError != nil {
+			s.OnError(err)
+		}
+
+		// reset backoff if connection was alive for a while
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+
+		time.Sleep(backoff)
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+func (s *Session) serve() error {
+	conn, err := s.Dial()
+	if err != nil {
+		return err
+	}
+
+	client := NewClient(conn)
+
+	keepalive := uint16(s.KeepAlive / time.Second)
+	if err = client.ConnectWill(s.ClientID, s.Username, s.Password, keepalive, s.WillTopic, s.WillPayload); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = client.Close()
+		return nil
+	}
+	s.client = client
+	topics := s.topics
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.client = nil
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	for _, topic := range topics {
+		if err = client.Subscribe(topic); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(s.KeepAlive / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if client.Ping() != nil {
+					_ = conn.Close()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if s.OnConnect != nil {
+		// can publish messages, so should be run outside of read loop
+		go s.OnConnect()
+	}
+
+	for {
+		// broker should answer to ping in keepalive interval
+		if err = conn.SetReadDeadline(time.Now().Add(s.KeepAlive)); err != nil {
+			return err
+		}
+
+		packet, err := client.ReadPacket()
+		if err != nil {
+			return err
+		}
+
+		switch packet.Type {
+		case PUBLISH:
+			if s.OnMessage != nil {
+				go s.OnMessage(packet.Topic, packet.Payload)
+			}
+		case PUBACK:
+			s.mu.Lock()
+			if ch := s.acks[packet.ID]; ch != nil {
+				close(ch)
+				delete(s.acks, packet.ID)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Subscribe - topic will be subscribed again after reconnect
+func (s *Session) Subscribe(topic string) error {
+	s.mu.Lock()
+	s.topics = append(s.topics, topic)
+	client := s.client
+	s.mu.Unlock()
+
+	if client == nil {
+		return nil // will be subscribed on connect
+	}
+
+	return client.Subscribe(topic)
+}
+
+// Publish - publish QoS 1 message and wait PUBACK from broker
+func (s *Session) Publish(topic string, payload []byte, retain bool) error {
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+
+	if client == nil {
+		return ErrNotConnected
+	}
+
+	mid := client.NextID()
+	ack := make(chan struct{})
+
+	s.mu.Lock()
+	if s.acks == nil {
+		s.acks = map[uint16]chan struct{}{}
+	}
+	s.acks[mid] = ack
+	s.mu.Unlock()
+
+	if err := client.PublishQOS1(mid, topic, payload, retain); err != nil {
+		s.removeAck(mid)
+		return err
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-time.After(Timeout):
+		s.removeAck(mid)
+		return errors.New("mqtt: publish timeout")
+	}
+}
+
+func (s *Session) removeAck(mid uint16) {
+	s.mu.Lock()
+	delete(s.acks, mid)
+	s.mu.Unlock()
+}
+
+func (s *Session) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	client := s.client
+	s.mu.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
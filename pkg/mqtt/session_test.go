@@ -0,0 +1,132 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// broker - minimal in-process broker for one client, errors are sent to the test goroutine
+func broker(conn net.Conn, published chan<- []byte, errs chan<- error) {
+	read := func() (byte, []byte, error) {
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return 0, nil, nil // client closed connection
+		}
+		size, err := ReadLen(conn)
+		if err != nil {
+			return 0, nil, err
+		}
+		body := make([]byte, size)
+		if _, err = io.ReadFull(conn, body); err != nil {
+			return 0, nil, err
+		}
+		return b[0], body, nil
+	}
+
+	header, body, err := read()
+	if err != nil {
+		errs <- err
+		return
+	}
+	if header != CONNECT {
+		errs <- fmt.Errorf("wrong connect header: %x", header)
+		return
+	}
+	if flags := byte(flagCleanStart | flagWill | flagWillQOS1 | flagWillRetain); body[7] != flags {
+		errs <- fmt.Errorf("wrong connect flags: %x", body[7])
+		return
+	}
+	_, _ = conn.Write([]byte{CONNACK, 2, 0, 0})
+
+	for {
+		if header, body, err = read(); err != nil {
+			errs <- err
+			return
+		}
+		switch header & 0xF0 {
+		case 0:
+			return
+		case SUBSCRIBE & 0xF0:
+			_, _ = conn.Write([]byte{SUBACK, 3, body[0], body[1], 1})
+			msg := NewPublishQOS1(1, "go2rtc/cmd", []byte("pause"))
+			_, _ = conn.Write(msg.Bytes())
+		case PUBLISH:
+			published <- append([]byte{header}, body...)
+			i := 2 + binary.BigEndian.Uint16(body)
+			_, _ = conn.Write([]byte{PUBACK, 2, body[i], body[i+1]})
+		case PINGREQ:
+			_, _ = conn.Write([]byte{PINGRESP, 0})
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	published := make(chan []byte, 10)
+	messages := make(chan string, 10)
+	errs := make(chan error, 10)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go broker(conn, published, errs)
+		}
+	}()
+
+	s := &Session{
+		Dial: func() (net.Conn, error) {
+			return net.Dial("tcp", ln.Addr().String())
+		},
+		ClientID:    "go2rtc",
+		KeepAlive:   time.Second,
+		WillTopic:   "go2rtc/status",
+		WillPayload: []byte("offline"),
+		OnMessage: func(topic string, payload []byte) {
+			messages <- topic + "=" + string(payload)
+		},
+	}
+	s.OnConnect = func() {
+		if err := s.Publish("go2rtc/status", []byte("online"), true); err != nil {
+			errs <- err
+		}
+	}
+
+	require.Nil(t, s.Subscribe("go2rtc/cmd"))
+
+	go s.Run()
+
+	var b []byte
+	select {
+	case b = <-published:
+	case err = <-errs:
+		require.Nil(t, err)
+	}
+	require.Equal(t, byte(PUBLISH|QOS1|RETAIN), b[0])
+	require.Equal(t, "online", string(b[len(b)-6:]))
+
+	require.Equal(t, "go2rtc/cmd=pause", <-messages)
+
+	// connection should survive several keepalive intervals
+	time.Sleep(1500 * time.Millisecond)
+	require.Nil(t, s.Publish("go2rtc/streams", []byte("1"), false))
+
+	require.Nil(t, s.Close())
+
+	select {
+	case err = <-errs:
+		require.Nil(t, err)
+	default:
+	}
+}
@@ -20,6 +20,7 @@ type Client struct {
 	deviceURL string
 	mediaURL  string
 	imaginURL string
+	ptzURL    string
 }
 
 func NewClient(rawURL string) (*Client, error) {
@@ -44,6 +45,7 @@ func NewClient(rawURL string) (*Client, error) {
 
 	client.mediaURL = FindTagValue(b, "Media.+?XAddr")
 	client.imaginURL = FindTagValue(b, "Imaging.+?XAddr")
+	client.ptzURL = FindTagValue(b, "PTZ.+?XAddr")
 
 	return client, nil
 }
@@ -51,18 +53,9 @@ func NewClient(rawURL string) (*Client, error) {
 func (c *Client) GetURI() (string, error) {
 	query := c.url.Query()
 
-	token := query.Get("subtype")
-
-	// support empty
-	if i := atoi(token); i >= 0 {
-		tokens, err := c.GetProfilesTokens()
-		if err != nil {
-			return "", err
-		}
-		if i >= len(tokens) {
-			return "", errors.New("onvif: wrong subtype")
-		}
-		token = tokens[i]
+	token, err := c.GetProfileToken()
+	if err != nil {
+		return "", err
 	}
 
 	getUri := c.GetStreamUri
@@ -90,6 +83,25 @@ func (c *Client) GetURI() (string, error) {
 	return u.String(), nil
 }
 
+// GetProfileToken - profile token from the subtype param: profile index or token
+func (c *Client) GetProfileToken() (string, error) {
+	token := c.url.Query().Get("subtype")
+
+	// support empty
+	if i := atoi(token); i >= 0 {
+		tokens, err := c.GetProfilesTokens()
+		if err != nil {
+			return "", err
+		}
+		if i >= len(tokens) {
+			return "", errors.New("onvif: wrong subtype")
+		}
+		token = tokens[i]
+	}
+
+	return token, nil
+}
+
 func (c *Client) GetName() (string, error) {
 	b, err := c.DeviceRequest(DeviceGetDeviceInformation)
 	if err != nil {
@@ -158,6 +170,36 @@ func (c *Client) GetServiceCapabilities() ([]byte, error) {
 	)
 }
 
+func (c *Client) HasPTZ() bool {
+	return c.ptzURL != ""
+}
+
+// ContinuousMove - pan, tilt and zoom speed in range -1..1
+func (c *Client) ContinuousMove(token string, x, y, zoom float64) ([]byte, error) {
+	return c.Request(c.ptzURL, `<tptz:ContinuousMove>
+	<tptz:ProfileToken>`+html.EscapeString(token)+`</tptz:ProfileToken>
+	<tptz:Velocity>
+		<tt:PanTilt x="`+formatFloat(x)+`" y="`+formatFloat(y)+`"/>
+		<tt:Zoom x="`+formatFloat(zoom)+`"/>
+	</tptz:Velocity>
+</tptz:ContinuousMove>`)
+}
+
+func (c *Client) Stop(token string) ([]byte, error) {
+	return c.Request(c.ptzURL, `<tptz:Stop>
+	<tptz:ProfileToken>`+html.EscapeString(token)+`</tptz:ProfileToken>
+	<tptz:PanTilt>true</tptz:PanTilt>
+	<tptz:Zoom>true</tptz:Zoom>
+</tptz:Stop>`)
+}
+
+func (c *Client) GotoPreset(token, preset string) ([]byte, error) {
+	return c.Request(c.ptzURL, `<tptz:GotoPreset>
+	<tptz:ProfileToken>`+html.EscapeString(token)+`</tptz:ProfileToken>
+	<tptz:PresetToken>`+html.EscapeString(preset)+`</tptz:PresetToken>
+</tptz:GotoPreset>`)
+}
+
 func (c *Client) DeviceRequest(operation string) ([]byte, error) {
 	switch operation {
 	case DeviceGetServices:
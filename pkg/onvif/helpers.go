@@ -108,6 +108,10 @@ func atoi(s string) int {
 	return i
 }
 
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 func GetPosixTZ(current time.Time) string {
 	// Thanks to https://github.com/Path-Variable/go-posix-time
 	_, offset := current.Zone()
@@ -242,9 +242,10 @@ func (c *Conn) writePacketSize() error {
 
 func (c *Conn) writeConnect() error {
 	b := amf.EncodeItems("connect", 1, map[string]any{
-		"app":      c.App,
-		"flashVer": "FMLE/3.0 (compatible; FMSc/1.0)",
-		"tcUrl":    c.url,
+		"app":        c.App,
+		"flashVer":   "FMLE/3.0 (compatible; FMSc/1.0)",
+		"tcUrl":      c.url,
+		"fourCcList": fourCCList,
 	})
 	if err := c.writeMessage(3, TypeCommand, 0, b); err != nil {
 		return err
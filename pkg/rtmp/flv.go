@@ -4,6 +4,9 @@ import (
 	"github.com/AlexxIT/go2rtc/pkg/flv"
 )
 
+// fourCCList - Enhanced RTMP codecs supported by flv.Producer and flv.Muxer
+var fourCCList = []any{flv.FourCCAV1, flv.FourCCHEVC, flv.FourCCAVC, flv.FourCCOpus, flv.FourCCAAC}
+
 func (c *Conn) Producer() (*flv.Producer, error) {
 	c.rdBuf = []byte{
 		'F', 'L', 'V', // signature
@@ -75,22 +78,21 @@ func (c *Conn) Write(p []byte) (n int, err error) {
 
 	if p[0] == 'F' {
 		p = p[9+4:] // skip first msg with FLV header
+	}
+
+	// decode one or more FLV tags: 11 bytes header + payload + 4 byte size
+	for len(p) > 0 {
+		size := 11 + (int(p[1])<<16 | int(p[2])<<8 | int(p[3])) + 4
+		tagType := p[0]
+		timeMS := uint32(p[4])<<16 | uint32(p[5])<<8 | uint32(p[6]) | uint32(p[7])<<24
+		payload := p[11 : size-4]
 
-		for len(p) > 0 {
-			size := 11 + uint16(p[2])<<8 + uint16(p[3]) + 4
-			if _, err = c.Write(p[:size]); err != nil {
-				return 0, err
-			}
-			p = p[size:]
+		if err = c.writeMessage(4, tagType, timeMS, payload); err != nil {
+			return 0, err
 		}
-		return
-	}
 
-	// decode FLV: 11 bytes header + payload + 4 byte size
-	tagType := p[0]
-	timeMS := uint32(p[4])<<16 | uint32(p[5])<<8 | uint32(p[6]) | uint32(p[7])<<24
-	payload := p[11 : len(p)-4]
+		p = p[size:]
+	}
 
-	err = c.writeMessage(4, tagType, timeMS, payload)
 	return
 }
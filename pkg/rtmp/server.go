@@ -145,15 +145,22 @@ func (c *Conn) acceptCommand(b []byte) error {
 
 	switch cmd {
 	case CommandConnect:
+		properties := map[string]any{"fmsVer": "FMS/3,0,1,123"}
+
 		if len(items) == 3 {
 			if v, ok := items[2].(map[string]any); ok {
 				c.App, _ = v["app"].(string)
+
+				// Enhanced RTMP client, reply with supported codecs
+				if _, ok = v["fourCcList"]; ok {
+					properties["fourCcList"] = fourCCList
+				}
 			}
 		}
 
 		payload := amf.EncodeItems(
 			"_result", tID,
-			map[string]any{"fmsVer": "FMS/3,0,1,123"},
+			properties,
 			map[string]any{"code": "NetConnection.Connect.Success"},
 		)
 		return c.writeMessage(3, TypeCommand, 0, payload)
@@ -177,6 +184,11 @@ func (c *Conn) acceptCommand(b []byte) error {
 		c.Intent = cmd
 		c.streamID = 1
 
+		// stream name or publish key
+		if len(items) >= 4 {
+			c.Stream, _ = items[3].(string)
+		}
+
 	default:
 		println("rtmp: unknown command: " + cmd)
 	}
@@ -196,6 +208,16 @@ func (c *Conn) WriteStart() error {
 	return c.writeMessage(3, TypeCommand, 0, payload)
 }
 
+// WriteError - reject publish or play, code like NetStream.Publish.BadName
+func (c *Conn) WriteError(code, description string) error {
+	payload := amf.EncodeItems("onStatus", 0, nil, map[string]any{
+		"level":       "error",
+		"code":        code,
+		"description": description,
+	})
+	return c.writeMessage(3, TypeCommand, 0, payload)
+}
+
 func nowMS() uint32 {
 	return uint32(time.Now().UnixNano() / int64(time.Millisecond))
 }
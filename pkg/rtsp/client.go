@@ -36,7 +36,7 @@ func (c *Conn) Dial() (err error) {
 
 	var conn net.Conn
 
-	if c.Transport == "" {
+	if c.Transport == "" || c.Transport == TransportUDP {
 		timeout := core.ConnDialTimeout
 		if c.Timeout != 0 {
 			timeout = time.Second * time.Duration(c.Timeout)
@@ -216,21 +216,16 @@ func (c *Conn) Record() (err error) {
 }
 
 func (c *Conn) SetupMedia(media *core.Media) (byte, error) {
-	var transport string
-
 	// try to use media position as channel number
+	ch := -1
 	for i, m := range c.Medias {
 		if m.Equal(media) {
-			transport = fmt.Sprintf(
-				// i   - RTP (data channel)
-				// i+1 - RTCP (control channel)
-				"RTP/AVP/TCP;unicast;interleaved=%d-%d", i*2, i*2+1,
-			)
+			ch = i * 2
 			break
 		}
 	}
 
-	if transport == "" {
+	if ch < 0 {
 		return 0, fmt.Errorf("wrong media: %v", media)
 	}
 
@@ -248,6 +243,23 @@ func (c *Conn) SetupMedia(media *core.Media) (byte, error) {
 		return 0, err
 	}
 
+	var transport string
+	var udp *udpConn
+
+	if c.Transport == TransportUDP {
+		if udp, err = listenUDP(byte(ch)); err != nil {
+			return 0, err
+		}
+		udp.recv = media.Direction == core.DirectionRecvonly
+		transport = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", udp.port(), udp.port()+1)
+	} else {
+		transport = fmt.Sprintf(
+			// i   - RTP (data channel)
+			// i+1 - RTCP (control channel)
+			"RTP/AVP/TCP;unicast;interleaved=%d-%d", ch, ch+1,
+		)
+	}
+
 	req := &tcp.Request{
 		Method: MethodSetup,
 		URL:    trackURL,
@@ -258,6 +270,17 @@ func (c *Conn) SetupMedia(media *core.Media) (byte, error) {
 
 	res, err := c.Do(req)
 	if err != nil {
+		if udp != nil {
+			_ = udp.Close()
+
+			// server without UDP support, ex. 461 Unsupported Transport
+			if res != nil {
+				c.Fire("RTSP UDP not supported, fallback to TCP")
+				c.Transport = ""
+				return c.SetupMedia(media)
+			}
+		}
+
 		// some Dahua/Amcrest cameras fail here because two simultaneous
 		// backchannel connections
 		if c.Backchannel {
@@ -286,12 +309,27 @@ func (c *Conn) SetupMedia(media *core.Media) (byte, error) {
 		}
 	}
 
+	transport = res.Header.Get("Transport")
+
+	if udp != nil {
+		// server can answer with TCP transport on UDP request
+		if !strings.Contains(transport, ";interleaved=") {
+			udp.setServer(c.conn.RemoteAddr(), transport)
+			c.udpMu.Lock()
+			c.udp = append(c.udp, udp)
+			c.udpMu.Unlock()
+			c.Protocol = "rtsp+udp"
+			return udp.channel, nil
+		}
+
+		_ = udp.Close()
+	}
+
 	// we send our `interleaved`, but camera can answer with another
 
 	// Transport: RTP/AVP/TCP;unicast;interleaved=10-11;ssrc=10117CB7
 	// Transport: RTP/AVP/TCP;unicast;destination=192.168.1.111;source=192.168.1.222;interleaved=0
 	// Transport: RTP/AVP/TCP;ssrc=22345682;interleaved=0-1
-	transport = res.Header.Get("Transport")
 	if !strings.HasPrefix(transport, "RTP/AVP/TCP;") {
 		// Escam Q6 has a bug:
 		// Transport: RTP/AVP;unicast;destination=192.168.1.111;source=192.168.1.222;interleaved=0-1
@@ -327,5 +365,9 @@ func (c *Conn) Close() error {
 	if c.OnClose != nil {
 		_ = c.OnClose()
 	}
+	c.closeUDP()
+	if c.conn == nil {
+		return nil // multicast consumer
+	}
 	return c.conn.Close()
 }
@@ -1,11 +1,17 @@
 package rtsp
 
 import (
+	"bufio"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/tcp"
+	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -93,3 +99,139 @@ Session: 1
 	require.Nil(t, err)
 	require.Equal(t, ch, byte(4))
 }
+
+// udpServer - fake camera with RTP over UDP support
+func udpServer(t *testing.T, ln net.Listener, udp bool) {
+	conn, err := ln.Accept()
+	require.Nil(t, err)
+
+	rd := bufio.NewReader(conn)
+
+	srv, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.Nil(t, err)
+	defer srv.Close()
+
+	var client *net.UDPAddr
+
+	for {
+		req, err := tcp.ReadRequest(rd)
+		if err != nil {
+			return
+		}
+
+		res := &tcp.Response{Proto: ProtoRTSP, Status: "200 OK", Request: req, Header: map[string][]string{}}
+		res.Header.Set("CSeq", req.Header.Get("CSeq"))
+
+		switch req.Method {
+		case MethodDescribe:
+			res.Header.Set("Content-Type", "application/sdp")
+			res.Body = []byte(`v=0
+o=- 1 1 IN IP4 0.0.0.0
+s=-
+t=0 0
+m=video 0 RTP/AVP 96
+a=rtpmap:96 H264/90000
+a=control:trackID=0
+`)
+			res.Header.Set("Content-Length", strconv.Itoa(len(res.Body)))
+
+		case MethodSetup:
+			transport := req.Header.Get("Transport")
+			if s := transportParam(transport, "client_port"); s != "" {
+				if !udp {
+					res.Status = "461 Unsupported Transport"
+					break
+				}
+				port, _, _ := strings.Cut(s, "-")
+				client = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: core.Atoi(port)}
+				transport += ";server_port=" + strconv.Itoa(srv.LocalAddr().(*net.UDPAddr).Port) + "-0"
+			}
+			res.Header.Set("Transport", transport)
+			res.Header.Set("Session", "1")
+
+		case MethodPlay:
+			if client != nil {
+				// send packets with wrong order
+				for _, seq := range []uint16{1, 3, 2, 4} {
+					packet := &rtp.Packet{
+						Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: seq, Timestamp: uint32(seq)},
+						Payload: []byte{0x65, byte(seq)},
+					}
+					b, _ := packet.Marshal()
+					_, _ = srv.WriteToUDP(b, client)
+				}
+			} else {
+				packet := &rtp.Packet{
+					Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 1},
+					Payload: []byte{0x65, 1},
+				}
+				b, _ := packet.Marshal()
+				_, _ = conn.Write(append([]byte{'$', 0, 0, byte(len(b))}, b...))
+			}
+		}
+
+		if err = res.Write(conn); err != nil {
+			return // client closed connection
+		}
+	}
+}
+
+func testUDP(t *testing.T, udp bool) (*Conn, []uint16) {
+	Timeout = time.Second
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.Nil(t, err)
+	defer ln.Close()
+
+	stopped := make(chan struct{})
+	go func() {
+		udpServer(t, ln, udp)
+		close(stopped)
+	}()
+
+	client := NewClient("rtsp://" + ln.Addr().String() + "/stream")
+	client.Transport = TransportUDP
+
+	require.Nil(t, client.Dial())
+	require.Nil(t, client.Describe())
+
+	media := client.Medias[0]
+	receiver, err := client.GetTrack(media, media.Codecs[0])
+	require.Nil(t, err)
+
+	var seqs []uint16
+	done := make(chan struct{})
+
+	sender := core.NewSender(media, media.Codecs[0])
+	sender.Handler = func(packet *rtp.Packet) {
+		seqs = append(seqs, packet.SequenceNumber)
+		if len(seqs) == 4 || !udp {
+			close(done)
+		}
+	}
+	sender.HandleRTP(receiver)
+
+	go client.Start()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+	}
+
+	_ = client.Stop()
+	<-stopped
+
+	return client, seqs
+}
+
+func TestUDP(t *testing.T) {
+	client, seqs := testUDP(t, true)
+	require.Equal(t, []uint16{1, 2, 3, 4}, seqs)
+	require.Equal(t, "rtsp+udp", client.Protocol)
+}
+
+func TestUDPFallback(t *testing.T) {
+	client, seqs := testUDP(t, false)
+	require.Equal(t, []uint16{1}, seqs)
+	require.Equal(t, "rtsp+tcp", client.Protocol)
+}
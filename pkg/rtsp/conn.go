@@ -3,12 +3,15 @@ package rtsp
 import (
 	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
+	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AlexxIT/go2rtc/pkg/core"
@@ -24,31 +27,49 @@ type Conn struct {
 	// public
 
 	Backchannel bool
+	// BasicAuth - offer and accept Basic auth in server mode, password is sent in the clear,
+	// so it should be used only with TLS
+	BasicAuth   bool
 	Media       string
 	OnClose     func() error
+	OnMulticast func() (*Conn, error) // shared multicast consumer for SETUP with multicast transport
 	PacketSize  uint16
 	SessionName string
 	Timeout     int
-	Transport   string // custom transport support, ex. RTSP over WebSocket
+	Transport   string // custom transport support, ex. RTSP over WebSocket or "udp"
+
+	// RejectStatus - response for DESCRIBE without tracks, default "404 Not Found"
+	RejectStatus string
 
 	URL *url.URL
 
 	// internal
 
 	auth      *tcp.Auth
+	authFunc  func(req *tcp.Request) bool
 	conn      net.Conn
 	keepalive int
 	mode      core.Mode
-	playOK    bool
+	playOK    atomic.Bool
 	reader    *bufio.Reader
 	sequence  int
 	session   string
 	uri       string
 
+	udp   []*udpConn // medias with RTP over UDP transport
+	udpMu sync.Mutex
+	group *net.UDPAddr // multicast group of NewMulticast consumer
+
 	state   State
 	stateMu sync.Mutex
 }
 
+func (c *Conn) getState() State {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.state
+}
+
 const (
 	ProtoRTSP      = "RTSP/1.0"
 	MethodOptions  = "OPTIONS"
@@ -59,6 +80,8 @@ const (
 	MethodPause    = "PAUSE"
 	MethodAnnounce = "ANNOUNCE"
 	MethodRecord   = "RECORD"
+
+	MethodGetParameter = "GET_PARAMETER"
 )
 
 type State byte
@@ -129,10 +152,35 @@ func (c *Conn) Handle() (err error) {
 		return fmt.Errorf("wrong RTSP conn mode: %d", c.mode)
 	}
 
-	for c.state != StateNone {
+	keepaliveFunc := func(ts time.Time) error {
+		if keepaliveDT != 0 && ts.After(keepaliveTS) {
+			req := &tcp.Request{Method: MethodOptions, URL: c.URL}
+			if err := c.WriteRequest(req); err != nil {
+				return err
+			}
+
+			keepaliveTS = ts.Add(keepaliveDT)
+		}
+		return nil
+	}
+
+	// last data from RTSP connection
+	var readTS time.Time
+
+	if c.hasUDP() {
+		c.startUDP()
+	}
+
+	for c.getState() != StateNone {
 		ts := time.Now()
 
-		if err = c.conn.SetReadDeadline(ts.Add(timeout)); err != nil {
+		// with UDP transport RTSP connection can be silent for a long time
+		if c.hasUDP() {
+			err = c.conn.SetReadDeadline(ts.Add(udpRecvTimeout))
+		} else {
+			err = c.conn.SetReadDeadline(ts.Add(timeout))
+		}
+		if err != nil {
 			return
 		}
 
@@ -143,9 +191,20 @@ func (c *Conn) Handle() (err error) {
 		var buf4 []byte // `$` + 1B channel number + 2B size
 		buf4, err = c.reader.Peek(4)
 		if err != nil {
-			return
+			if !c.hasUDP() || !errors.Is(err, os.ErrDeadlineExceeded) {
+				return
+			}
+			if err = c.checkUDP(timeout, readTS); err != nil || !c.hasUDP() {
+				return // timeout error or fallback to TCP
+			}
+			if err = keepaliveFunc(ts); err != nil {
+				return
+			}
+			continue
 		}
 
+		readTS = ts
+
 		var channelID byte
 		var size uint16
 
@@ -158,7 +217,7 @@ func (c *Conn) Handle() (err error) {
 				}
 				c.Fire(res)
 				// for playing backchannel only after OK response on play
-				c.playOK = true
+				c.playOK.Store(true)
 				continue
 
 			case "OPTI", "TEAR", "DESC", "SETU", "PLAY", "PAUS", "RECO", "ANNO", "GET_", "SET_":
@@ -167,7 +226,7 @@ func (c *Conn) Handle() (err error) {
 					return
 				}
 				c.Fire(req)
-				if req.Method == MethodOptions {
+				if req.Method == MethodOptions || req.Method == MethodGetParameter {
 					res := &tcp.Response{Request: req}
 					if err = c.WriteResponse(res); err != nil {
 						return
@@ -258,13 +317,8 @@ func (c *Conn) Handle() (err error) {
 			c.Fire(msg)
 		}
 
-		if keepaliveDT != 0 && ts.After(keepaliveTS) {
-			req := &tcp.Request{Method: MethodOptions, URL: c.URL}
-			if err = c.WriteRequest(req); err != nil {
-				return
-			}
-
-			keepaliveTS = ts.Add(keepaliveDT)
+		if err = keepaliveFunc(ts); err != nil {
+			return
 		}
 	}
 
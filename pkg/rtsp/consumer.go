@@ -40,6 +40,12 @@ func (c *Conn) AddTrack(media *core.Media, codec *core.Codec, track *core.Receiv
 	case core.ModePassiveConsumer:
 		channel = byte(len(c.Senders)) * 2
 
+		if c.group != nil {
+			if err = c.addMulticast(channel); err != nil {
+				return
+			}
+		}
+
 		// for consumer is better to use original track codec
 		codec = track.Codec.Clone()
 		// generate new payload type, starting from 96
@@ -116,6 +122,16 @@ func (c *Conn) packetWriter(codec *core.Codec, channel, payloadType uint8) core.
 			packet.Marker = true // better to have marker on all audio packets
 		}
 
+		if udp := c.findUDP(channel); udp != nil {
+			if !c.playOK.Load() {
+				return // wait PLAY command
+			}
+			if n, err := udp.writeRTP(&clone); err == nil {
+				c.Send += n
+			}
+			return
+		}
+
 		size := rtpHdr + len(packet.Payload)
 
 		if l := len(buf); n+intHdr+size > l {
@@ -141,10 +157,10 @@ func (c *Conn) packetWriter(codec *core.Codec, channel, payloadType uint8) core.
 
 		n += 4 + size
 
-		if !packet.Marker || !c.playOK {
+		if !packet.Marker || !c.playOK.Load() {
 			// collect continious video packets to buffer
 			// or wait OK for PLAY command for backchannel
-			//log.Printf("[rtsp] collecting buffer ok=%t", c.playOK)
+			//log.Printf("[rtsp] collecting buffer ok=%t", c.playOK.Load())
 			return
 		}
 
@@ -0,0 +1,74 @@
+package rtsp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"golang.org/x/net/ipv4"
+)
+
+const MulticastTTL = 16
+
+// NewMulticast - consumer without RTSP connection, sends RTP packets of all tracks
+// to the multicast group (each track to own ports pair starting from addr port).
+// One multicast consumer is shared by all multicast viewers of the stream.
+func NewMulticast(addr *net.UDPAddr, medias []*core.Media) *Conn {
+	c := &Conn{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: "rtsp",
+			Protocol:   "rtp+multicast",
+			RemoteAddr: addr.String(),
+			Medias:     medias,
+		},
+		group: addr,
+		mode:  core.ModePassiveConsumer,
+		state: StatePlay,
+	}
+	c.playOK.Store(true)
+	return c
+}
+
+// addMulticast - sockets pair for the track with multicast group as remote address
+func (c *Conn) addMulticast(channel byte) error {
+	u, err := listenUDP(channel)
+	if err != nil {
+		return err
+	}
+
+	for _, conn := range []*net.UDPConn{u.rtp, u.rtcp} {
+		if err = ipv4.NewPacketConn(conn).SetMulticastTTL(MulticastTTL); err != nil {
+			_ = u.Close()
+			return err
+		}
+	}
+
+	port := c.group.Port + int(channel)
+	u.setPeer(c.group.IP.String(), strconv.Itoa(port)+"-"+strconv.Itoa(port+1))
+
+	c.udpMu.Lock()
+	c.udp = append(c.udp, u)
+	c.udpMu.Unlock()
+
+	return nil
+}
+
+// MulticastTransport - SETUP response for the track of this multicast consumer,
+// the track should have same codec as in the viewer's SDP
+func (c *Conn) MulticastTransport(trackID int, codec *core.Codec) (string, error) {
+	if trackID < 0 || trackID >= len(c.Senders) {
+		return "", fmt.Errorf("rtsp: wrong multicast track: %d", trackID)
+	}
+
+	if sender := c.Senders[trackID].Codec; sender.Name != codec.Name ||
+		sender.ClockRate != codec.ClockRate || sender.PayloadType != codec.PayloadType {
+		return "", fmt.Errorf("rtsp: multicast codec mismatch: %s", codec)
+	}
+
+	port := c.group.Port + trackID*2
+	return fmt.Sprintf(
+		"RTP/AVP;multicast;destination=%s;port=%d-%d;ttl=%d", c.group.IP, port, port+1, MulticastTTL,
+	), nil
+}
@@ -110,7 +110,18 @@ func (c *Conn) Stop() (err error) {
 }
 
 func (c *Conn) MarshalJSON() ([]byte, error) {
-	return json.Marshal(c.Connection)
+	if !c.hasUDP() {
+		return json.Marshal(c.Connection)
+	}
+
+	info := struct {
+		core.Connection
+		UDP []udpStats `json:"udp"`
+	}{
+		Connection: c.Connection,
+		UDP:        c.udpStats(),
+	}
+	return json.Marshal(info)
 }
 
 func (c *Conn) Reconnect() error {
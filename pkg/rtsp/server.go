@@ -15,6 +15,11 @@ import (
 
 var FailedAuth = errors.New("failed authentication")
 
+const StatusNotEnoughBandwidth = "453 Not Enough Bandwidth"
+
+// Realm - for Basic and Digest authentication of the server
+const Realm = "go2rtc"
+
 func NewServer(conn net.Conn) *Conn {
 	return &Conn{
 		Connection: core.Connection{
@@ -33,6 +38,29 @@ func (c *Conn) Auth(username, password string) {
 	c.auth = tcp.NewAuth(info)
 }
 
+// AuthFunc - custom validation of each request, replaces Auth
+func (c *Conn) AuthFunc(f func(req *tcp.Request) bool) {
+	c.authFunc = f
+}
+
+func (c *Conn) validate(req *tcp.Request) (valid, empty bool) {
+	if c.auth == nil && c.authFunc == nil {
+		return true, true
+	}
+	header := req.Header.Get("Authorization")
+	// client should repeat request with new nonce
+	if tcp.DigestStale(header) {
+		return false, true
+	}
+	if !c.BasicAuth && strings.HasPrefix(header, "Basic ") {
+		return false, false
+	}
+	if c.authFunc != nil {
+		return c.authFunc(req), header == ""
+	}
+	return c.auth.Validate(req, Realm)
+}
+
 func (c *Conn) Accept() error {
 	for {
 		req, err := c.ReadRequest()
@@ -47,10 +75,16 @@ func (c *Conn) Accept() error {
 
 		c.Fire(req)
 
-		if valid, empty := c.auth.Validate(req); !valid {
+		if valid, empty := c.validate(req); !valid {
+			stale := tcp.DigestStale(req.Header.Get("Authorization"))
+			challenge := []string{tcp.DigestChallenge(Realm, stale)}
+			if c.BasicAuth {
+				// clients prefer Digest, so password will not be sent in the clear
+				challenge = append(challenge, `Basic realm="`+Realm+`"`)
+			}
 			res := &tcp.Response{
 				Status:  "401 Unauthorized",
-				Header:  map[string][]string{"Www-Authenticate": {`Basic realm="go2rtc"`}},
+				Header:  map[string][]string{"Www-Authenticate": challenge},
 				Request: req,
 			}
 			if err = c.WriteResponse(res); err != nil {
@@ -114,6 +148,9 @@ func (c *Conn) Accept() error {
 					Status:  "404 Not Found",
 					Request: req,
 				}
+				if c.RejectStatus != "" {
+					res.Status = c.RejectStatus
+				}
 				return c.WriteResponse(res)
 			}
 
@@ -163,9 +200,27 @@ func (c *Conn) Accept() error {
 				Request: req,
 			}
 
-			// Test if client requests TCP transport, otherwise return 461 Transport not supported
+			// Test if client requests TCP, UDP or multicast transport, otherwise return 461 Transport not supported
 			// This allows smart clients who initially requested UDP to fall back on TCP transport
-			if tr := req.Header.Get("Transport"); strings.HasPrefix(tr, "RTP/AVP/TCP") {
+			if tr := req.Header.Get("Transport"); strings.Contains(tr, ";multicast") {
+				if tr, err = c.setupMulticast(req); err == nil {
+					c.session = core.RandString(8, 10)
+					c.state = StateSetup
+					res.Header.Set("Transport", tr)
+				} else {
+					c.Fire(err.Error())
+					res.Status = "461 Unsupported transport"
+				}
+			} else if strings.Contains(tr, "client_port=") {
+				if tr, err = c.setupUDP(req, tr); err == nil {
+					c.session = core.RandString(8, 10)
+					c.state = StateSetup
+					res.Header.Set("Transport", tr)
+				} else {
+					c.Fire(err.Error())
+					res.Status = "461 Unsupported transport"
+				}
+			} else if strings.HasPrefix(tr, "RTP/AVP/TCP") {
 				c.session = core.RandString(8, 10)
 				c.state = StateSetup
 
@@ -204,7 +259,7 @@ func (c *Conn) Accept() error {
 
 			res := &tcp.Response{Request: req}
 			err = c.WriteResponse(res)
-			c.playOK = true
+			c.playOK.Store(true)
 			return err
 
 		case MethodTeardown:
@@ -233,3 +288,66 @@ func reqTrackID(req *tcp.Request) int {
 	}
 	return -1
 }
+
+// setupUDP - allocate server ports pair for the track, RTP packets will be sent
+// to the client IP from RTSP connection (destination param is ignored)
+func (c *Conn) setupUDP(req *tcp.Request, transport string) (string, error) {
+	i := reqTrackID(req)
+
+	if c.mode == core.ModePassiveConsumer {
+		if i < 0 || i >= len(c.Senders)+len(c.Receivers) {
+			return "", errors.New("rtsp: wrong track")
+		}
+		if i < len(c.Senders) {
+			c.Senders[i].Media.ID = MethodSetup
+		} else {
+			c.Receivers[i-len(c.Senders)].Media.ID = MethodSetup
+		}
+	} else if i < 0 || i >= len(c.Receivers) {
+		c.udpMu.Lock()
+		i = len(c.udp)
+		c.udpMu.Unlock()
+	}
+
+	u, err := listenUDP(byte(i * 2))
+	if err != nil {
+		return "", err
+	}
+
+	// receivers: backchannel for consumer or tracks for producer
+	u.recv = c.mode == core.ModePassiveProducer || i >= len(c.Senders)
+
+	ports := transportParam(transport, "client_port")
+	host, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+	u.setPeer(host, ports)
+
+	c.udpMu.Lock()
+	c.udp = append(c.udp, u)
+	c.udpMu.Unlock()
+
+	c.Protocol = "rtsp+udp"
+
+	return fmt.Sprintf(
+		"RTP/AVP;unicast;client_port=%s;server_port=%d-%d", ports, u.port(), u.port()+1,
+	), nil
+}
+
+// setupMulticast - viewer's track will be received from shared multicast consumer
+func (c *Conn) setupMulticast(req *tcp.Request) (string, error) {
+	if c.mode != core.ModePassiveConsumer || c.OnMulticast == nil {
+		return "", errors.New("rtsp: multicast not supported")
+	}
+
+	i := reqTrackID(req)
+	if i < 0 || i >= len(c.Senders) {
+		return "", errors.New("rtsp: wrong multicast track")
+	}
+
+	group, err := c.OnMulticast()
+	if err != nil {
+		return "", err
+	}
+
+	// viewer's sender will be stopped on PLAY, because of Media.ID != SETUP
+	return group.MulticastTransport(i, c.Senders[i].Codec)
+}
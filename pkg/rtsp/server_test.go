@@ -0,0 +1,132 @@
+package rtsp
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/tcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerUDP(t *testing.T) {
+	Timeout = time.Second
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer ln.Close()
+
+	codec := &core.Codec{Name: core.CodecH264, ClockRate: 90000, PayloadType: 96}
+	media := &core.Media{Kind: core.KindVideo, Direction: core.DirectionSendonly, Codecs: []*core.Codec{codec}}
+	source := core.NewReceiver(media, codec)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		conn, err := ln.Accept()
+		require.Nil(t, err)
+
+		server := NewServer(conn)
+		server.Listen(func(msg any) {
+			if msg == MethodDescribe {
+				server.Medias = []*core.Media{media}
+				require.Nil(t, server.AddTrack(media, codec, source))
+			}
+		})
+
+		if err = server.Accept(); err == nil {
+			_ = server.Handle()
+		}
+		_ = server.Close()
+	}()
+
+	client := NewClient("rtsp://" + ln.Addr().String() + "/stream")
+	client.Transport = TransportUDP
+
+	require.Nil(t, client.Dial())
+	require.Nil(t, client.Describe())
+
+	receiver, err := client.GetTrack(client.Medias[0], client.Medias[0].Codecs[0])
+	require.Nil(t, err)
+
+	var mu sync.Mutex
+	var seqs []uint16
+
+	sender := core.NewSender(client.Medias[0], client.Medias[0].Codecs[0])
+	sender.Handler = func(packet *rtp.Packet) {
+		mu.Lock()
+		seqs = append(seqs, packet.SequenceNumber)
+		mu.Unlock()
+	}
+	sender.HandleRTP(receiver)
+
+	go client.Start()
+
+	// server will drop packets before PLAY
+	for seq := uint16(1); seq < 100; seq++ {
+		source.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, SequenceNumber: seq, Timestamp: uint32(seq), Marker: true},
+			Payload: []byte{0x65, byte(seq)},
+		})
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		n := len(seqs)
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+	}
+
+	_ = client.Stop()
+	<-done // server uses the global Timeout
+
+	mu.Lock()
+	require.GreaterOrEqual(t, len(seqs), 3)
+	require.Equal(t, seqs[0]+1, seqs[1])
+	mu.Unlock()
+
+	require.Equal(t, "rtsp+udp", client.Protocol)
+}
+
+func TestServerBasicAuth(t *testing.T) {
+	options := func(basic bool) (*tcp.Response, error) {
+		conn1, conn2 := net.Pipe()
+		defer conn1.Close()
+
+		server := NewServer(conn2)
+		server.Auth("admin", "pass")
+		server.BasicAuth = basic
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- server.Accept()
+		}()
+
+		req := "OPTIONS rtsp://127.0.0.1/stream RTSP/1.0\r\nCSeq: 1\r\nAuthorization: Basic " + tcp.B64("admin", "pass") + "\r\n\r\n"
+		_, err := conn1.Write([]byte(req))
+		require.Nil(t, err)
+
+		res, err := tcp.ReadResponse(bufio.NewReader(conn1))
+		require.Nil(t, err)
+
+		_ = conn1.Close()
+		return res, <-errs
+	}
+
+	// password in the clear is rejected without TLS by default
+	res, err := options(false)
+	require.Equal(t, 401, res.StatusCode)
+	require.Equal(t, FailedAuth, err)
+	require.NotContains(t, res.Header.Values("Www-Authenticate"), `Basic realm="go2rtc"`)
+
+	res, err = options(true)
+	require.Equal(t, 200, res.StatusCode)
+	require.NotEqual(t, FailedAuth, err)
+}
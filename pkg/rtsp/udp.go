@@ -0,0 +1,626 @@
+package rtsp
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// TransportUDP - RTP over UDP unicast for the client, with fallback to TCP interleaved
+const TransportUDP = "udp"
+
+const (
+	reorderSize    = 64                     // max packets in reorder buffer
+	reorderDelay   = 50 * time.Millisecond  // max wait time for missed packet
+	reportInterval = 5 * time.Second        // RTCP receiver reports interval
+	udpBufferSize  = 1024 * 1024            // OS receive buffer for video packets
+	udpPacketSize  = 0xFFFF                 // max UDP payload size
+	udpPunchSize   = rtpHdr                 // header only RTP packet for NAT
+	udpRecvTimeout = 500 * time.Millisecond // check interval in Handle
+)
+
+// udpConn - RTP and RTCP sockets pair of one media
+type udpConn struct {
+	channel byte // virtual interleaved channel: even - RTP, odd - RTCP
+	recv    bool // media for receiving (not backchannel)
+
+	rtp  *net.UDPConn
+	rtcp *net.UDPConn
+
+	// server address and ports from SETUP response
+	ip       net.IP
+	rtpAddr  *net.UDPAddr
+	rtcpAddr *net.UDPAddr
+
+	// protected by Conn.udpMu
+	reorder  reorder
+	stats    rtpStats
+	ssrc     uint32 // our SSRC for receiver reports
+	started  time.Time
+	packetTS time.Time // last received RTP packet
+	reportTS time.Time // last sent receiver report
+	srNTP    uint32    // middle 32 bits of NTP time from last sender report
+	srTS     time.Time // receive time of last sender report
+	rtcpTS   time.Time // last received RTCP packet
+
+	// used only by sender handler
+	sendPackets  uint32
+	sendOctets   uint32
+	sendReportTS time.Time
+}
+
+// listenUDP - open pair of sockets with even RTP port and RTP+1 port for RTCP
+func listenUDP(channel byte) (*udpConn, error) {
+	for i := 0; i < 10; i++ {
+		conn1, err := net.ListenUDP("udp", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		port := conn1.LocalAddr().(*net.UDPAddr).Port
+		if port&1 == 0 {
+			conn2, err := net.ListenUDP("udp", &net.UDPAddr{Port: port + 1})
+			if err == nil {
+				_ = conn1.SetReadBuffer(udpBufferSize)
+				return &udpConn{channel: channel, rtp: conn1, rtcp: conn2, ssrc: rand.Uint32()}, nil
+			}
+		}
+
+		_ = conn1.Close()
+	}
+
+	return nil, errors.New("rtsp: can't listen UDP ports pair")
+}
+
+func (u *udpConn) port() int {
+	return u.rtp.LocalAddr().(*net.UDPAddr).Port
+}
+
+// setServer - parse server address and ports from SETUP response
+// Transport: RTP/AVP;unicast;client_port=5000-5001;server_port=6970-6971;ssrc=1A2B3C4D
+// Transport: RTP/AVP;unicast;client_port=5000-5001;source=192.168.1.123;server_port=8000-8001
+func (u *udpConn) setServer(addr net.Addr, transport string) {
+	host, _, _ := net.SplitHostPort(addr.String())
+	if s := transportParam(transport, "source"); s != "" {
+		host = s
+	}
+
+	u.setPeer(host, transportParam(transport, "server_port"))
+}
+
+// setPeer - remote address and ports pair, ex. "6970-6971",
+// empty ports will be learned from first packets
+func (u *udpConn) setPeer(host, ports string) {
+	if u.ip = net.ParseIP(host); u.ip == nil || ports == "" {
+		return
+	}
+
+	s1, s2, ok := strings.Cut(ports, "-")
+	port := core.Atoi(s1)
+	u.rtpAddr = &net.UDPAddr{IP: u.ip, Port: port}
+	if ok {
+		u.rtcpAddr = &net.UDPAddr{IP: u.ip, Port: core.Atoi(s2)}
+	} else {
+		u.rtcpAddr = &net.UDPAddr{IP: u.ip, Port: port + 1}
+	}
+}
+
+// punch - open NAT and firewall for server packets
+func (u *udpConn) punch() {
+	if u.rtpAddr != nil {
+		b := make([]byte, udpPunchSize)
+		b[0] = 2 << 6 // RTP version
+		_, _ = u.rtp.WriteToUDP(b, u.rtpAddr)
+	}
+	if u.rtcpAddr != nil {
+		b, _ := (&rtcp.ReceiverReport{SSRC: u.ssrc}).Marshal()
+		_, _ = u.rtcp.WriteToUDP(b, u.rtcpAddr)
+	}
+}
+
+// writeRTP - send packet to remote RTP port and RTCP sender report
+// to remote RTCP port, RFC 3550 6.4.1
+func (u *udpConn) writeRTP(packet *rtp.Packet) (int, error) {
+	if u.rtpAddr == nil {
+		return 0, errors.New("rtsp: unknown UDP remote port")
+	}
+
+	b, err := packet.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = u.rtp.WriteToUDP(b, u.rtpAddr); err != nil {
+		return 0, err
+	}
+
+	u.sendPackets++
+	u.sendOctets += uint32(len(packet.Payload))
+
+	if now := time.Now(); u.rtcpAddr != nil && now.Sub(u.sendReportTS) >= reportInterval {
+		sr := &rtcp.SenderReport{
+			SSRC:        packet.SSRC,
+			NTPTime:     ntpTime(now),
+			RTPTime:     packet.Timestamp,
+			PacketCount: u.sendPackets,
+			OctetCount:  u.sendOctets,
+		}
+		if b, err := sr.Marshal(); err == nil {
+			_, _ = u.rtcp.WriteToUDP(b, u.rtcpAddr)
+		}
+		u.sendReportTS = now
+	}
+
+	return len(b), nil
+}
+
+// ntpTime - 64 bit NTP timestamp, seconds since 1900 and fraction
+func ntpTime(t time.Time) uint64 {
+	const ntpEpochOffset = 2208988800
+	sec := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+func (u *udpConn) Close() error {
+	_ = u.rtcp.Close()
+	return u.rtp.Close()
+}
+
+// report - RTCP receiver report with loss and jitter stats, RFC 3550 6.4.2
+func (u *udpConn) report(now time.Time) *rtcp.ReceiverReport {
+	rr := u.stats.report()
+
+	if !u.srTS.IsZero() {
+		rr.LastSenderReport = u.srNTP
+		// delay in units of 1/65536 seconds
+		rr.Delay = uint32(now.Sub(u.srTS) * 65536 / time.Second)
+	}
+
+	return &rtcp.ReceiverReport{SSRC: u.ssrc, Reports: []rtcp.ReceptionReport{rr}}
+}
+
+func (c *Conn) startUDP() {
+	now := time.Now()
+
+	c.udpMu.Lock()
+	for _, u := range c.udp {
+		if !u.started.IsZero() {
+			continue
+		}
+		u.started = now
+		if c.mode == core.ModeActiveProducer {
+			u.punch()
+		}
+		go c.readRTP(u)
+		go c.readRTCP(u)
+	}
+	c.udpMu.Unlock()
+}
+
+func (c *Conn) readRTP(u *udpConn) {
+	b := make([]byte, udpPacketSize)
+
+	var receiver *core.Receiver
+
+	// packets in order from the reorder buffer, written after unlock
+	var ready []*rtp.Packet
+	push := func(packet *rtp.Packet) {
+		ready = append(ready, packet)
+	}
+
+	for {
+		n, addr, err := u.rtp.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+
+		// skip packets from other hosts
+		if u.ip != nil && !u.ip.Equal(addr.IP) {
+			continue
+		}
+
+		if n <= rtpHdr {
+			continue // NAT punch or broken packet
+		}
+
+		// packet payload will reference to this memory
+		buf := make([]byte, n)
+		copy(buf, b)
+
+		packet := &rtp.Packet{}
+		if err = packet.Unmarshal(buf); err != nil {
+			continue
+		}
+
+		if receiver == nil {
+			for _, track := range c.Receivers {
+				if track.ID == u.channel {
+					receiver = track
+					break
+				}
+			}
+			if receiver == nil {
+				continue
+			}
+		}
+
+		now := time.Now()
+
+		c.udpMu.Lock()
+
+		c.Recv += n
+		u.packetTS = now
+
+		if u.rtpAddr == nil {
+			u.rtpAddr = addr
+		}
+
+		arrival := uint32(now.Sub(u.started).Seconds() * float64(receiver.Codec.ClockRate))
+		u.stats.update(packet, arrival)
+
+		ready = ready[:0]
+		u.reorder.push(packet, now, push)
+
+		var rr *rtcp.ReceiverReport
+		rrAddr := u.rtcpAddr
+		if rrAddr != nil && now.Sub(u.reportTS) >= reportInterval {
+			rr = u.report(now)
+			u.reportTS = now
+		}
+
+		c.udpMu.Unlock()
+
+		// consumers may be slow, so don't block RTCP and checkUDP
+		for _, packet := range ready {
+			receiver.WriteRTP(packet)
+		}
+
+		if rr != nil {
+			if b, err := rr.Marshal(); err == nil {
+				_, _ = u.rtcp.WriteToUDP(b, rrAddr)
+			}
+		}
+	}
+}
+
+func (c *Conn) readRTCP(u *udpConn) {
+	b := make([]byte, udpPacketSize)
+
+	for {
+		n, addr, err := u.rtcp.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+
+		if u.ip != nil && !u.ip.Equal(addr.IP) {
+			continue
+		}
+
+		msg := &RTCP{Channel: u.channel + 1}
+
+		if err = msg.Header.Unmarshal(b[:n]); err != nil {
+			continue
+		}
+
+		if msg.Packets, err = rtcp.Unmarshal(b[:n]); err != nil {
+			continue
+		}
+
+		c.udpMu.Lock()
+		c.Recv += n
+		u.rtcpTS = time.Now()
+		if u.rtcpAddr == nil {
+			u.rtcpAddr = addr
+		}
+		for _, packet := range msg.Packets {
+			if sr, ok := packet.(*rtcp.SenderReport); ok {
+				u.srNTP = uint32(sr.NTPTime >> 16)
+				u.srTS = time.Now()
+			}
+		}
+		c.udpMu.Unlock()
+
+		c.Fire(msg)
+	}
+}
+
+// checkUDP - called on TCP read timeout, returns error if there is no RTP packets
+// for receivers (or RTSP/RTCP packets for consumers) longer than timeout.
+// Client fallback to TCP if UDP packets never came.
+func (c *Conn) checkUDP(timeout time.Duration, readTS time.Time) error {
+	var recv bool
+	var started, packetTS, rtcpTS time.Time
+
+	c.udpMu.Lock()
+	for _, u := range c.udp {
+		started = u.started
+		if u.recv {
+			recv = true
+			packetTS = maxTime(packetTS, u.packetTS)
+		}
+		rtcpTS = maxTime(rtcpTS, u.rtcpTS)
+	}
+	c.udpMu.Unlock()
+
+	if c.mode == core.ModeActiveProducer && recv && packetTS.IsZero() && time.Since(started) > timeout {
+		return c.fallbackTCP()
+	}
+
+	var lastTS time.Time
+	if recv && c.mode != core.ModePassiveConsumer {
+		lastTS = maxTime(started, packetTS)
+	} else {
+		// only backchannel or consumer, check keepalive requests and RTCP reports
+		lastTS = maxTime(started, maxTime(readTS, rtcpTS))
+	}
+
+	if time.Since(lastTS) > timeout {
+		return errors.New("rtsp: UDP timeout")
+	}
+
+	return nil
+}
+
+// fallbackTCP - UDP packets can be blocked by firewall or NAT
+func (c *Conn) fallbackTCP() error {
+	c.Fire("RTSP UDP timeout, fallback to TCP")
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	if c.state != StatePlay {
+		return nil
+	}
+
+	c.Transport = ""
+
+	if err := c.Reconnect(); err != nil {
+		return err
+	}
+
+	c.state = StateSetup
+	return nil
+}
+
+func maxTime(t1, t2 time.Time) time.Time {
+	if t2.After(t1) {
+		return t2
+	}
+	return t1
+}
+
+func (c *Conn) findUDP(channel byte) *udpConn {
+	c.udpMu.Lock()
+	defer c.udpMu.Unlock()
+
+	for _, u := range c.udp {
+		if u.channel == channel {
+			return u
+		}
+	}
+	return nil
+}
+
+func (c *Conn) hasUDP() bool {
+	c.udpMu.Lock()
+	defer c.udpMu.Unlock()
+
+	return c.udp != nil
+}
+
+func (c *Conn) closeUDP() {
+	c.udpMu.Lock()
+	udp := c.udp
+	c.udp = nil
+	c.udpMu.Unlock()
+
+	for _, u := range udp {
+		_ = u.Close()
+	}
+}
+
+// udpStats - info about UDP transport for each media
+type udpStats struct {
+	Channel byte   `json:"channel"`
+	Packets uint32 `json:"packets"`
+	Lost    uint32 `json:"packets_lost"`
+	Late    int    `json:"packets_late"` // late or duplicate packets, dropped by reorder buffer
+	Skipped int    `json:"packets_skipped"`
+	Jitter  uint32 `json:"jitter"` // in RTP timestamp units
+}
+
+func (c *Conn) udpStats() []udpStats {
+	c.udpMu.Lock()
+	defer c.udpMu.Unlock()
+
+	stats := make([]udpStats, len(c.udp))
+	for i, u := range c.udp {
+		stats[i] = udpStats{
+			Channel: u.channel,
+			Packets: u.stats.received,
+			Lost:    u.stats.lost(),
+			Late:    u.reorder.late,
+			Skipped: u.reorder.skipped,
+			Jitter:  uint32(u.stats.jitter),
+		}
+	}
+	return stats
+}
+
+// transportParam - value of param from Transport header
+func transportParam(transport, key string) string {
+	for _, s := range strings.Split(transport, ";") {
+		if v, ok := strings.CutPrefix(s, key+"="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// reorder - restore order of UDP packets, wait missed packet no longer than
+// reorderDelay or reorderSize packets
+type reorder struct {
+	packets [reorderSize]*rtp.Packet // index - sequence number % reorderSize
+	count   int
+	next    uint16 // next sequence number for output
+	waitTS  time.Time
+	init    bool
+
+	late    int // dropped late or duplicate packets
+	skipped int // skipped missed sequence numbers
+}
+
+func (r *reorder) push(packet *rtp.Packet, now time.Time, handler func(*rtp.Packet)) {
+	if !r.init {
+		r.init = true
+		r.next = packet.SequenceNumber
+	}
+
+	diff := int16(packet.SequenceNumber - r.next)
+	if diff < 0 && diff >= -reorderSize {
+		r.late++
+		return
+	}
+
+	// big jump, ex. camera restart
+	if diff < 0 || diff >= reorderSize {
+		for r.count > 0 {
+			r.skip()
+			r.pop(now, handler)
+		}
+		r.next = packet.SequenceNumber
+	}
+
+	i := packet.SequenceNumber % reorderSize
+	if r.packets[i] != nil {
+		r.late++
+		return
+	}
+
+	r.packets[i] = packet
+	r.count++
+
+	r.pop(now, handler)
+
+	if r.count > 0 && now.Sub(r.waitTS) > reorderDelay {
+		r.skip()
+		r.pop(now, handler)
+	}
+}
+
+// pop - output all packets in sequence order
+func (r *reorder) pop(now time.Time, handler func(*rtp.Packet)) {
+	for {
+		i := r.next % reorderSize
+		packet := r.packets[i]
+		if packet == nil {
+			break
+		}
+
+		r.packets[i] = nil
+		r.count--
+		r.next++
+		r.waitTS = time.Time{}
+
+		handler(packet)
+	}
+
+	if r.count > 0 && r.waitTS.IsZero() {
+		r.waitTS = now
+	}
+}
+
+// skip - move next sequence number to first received packet
+func (r *reorder) skip() {
+	for r.packets[r.next%reorderSize] == nil {
+		r.next++
+		r.skipped++
+	}
+	r.waitTS = time.Time{}
+}
+
+// rtpStats - RTP reception statistics, RFC 3550 A.1, A.3, A.8
+type rtpStats struct {
+	ssrc     uint32
+	baseSeq  uint16
+	maxSeq   uint16
+	cycles   uint32
+	received uint32
+	init     bool
+
+	expectedPrior uint32
+	receivedPrior uint32
+
+	transit uint32
+	jitter  float64
+}
+
+func (s *rtpStats) update(packet *rtp.Packet, arrival uint32) {
+	seq := packet.SequenceNumber
+	s.ssrc = packet.SSRC
+
+	if !s.init {
+		s.init = true
+		s.baseSeq = seq
+		s.maxSeq = seq
+		s.transit = arrival - packet.Timestamp
+	} else if diff := seq - s.maxSeq; diff > 0 && diff < 0x8000 {
+		if seq < s.maxSeq {
+			s.cycles += 1 << 16 // sequence number wrapped
+		}
+		s.maxSeq = seq
+	}
+
+	s.received++
+
+	transit := arrival - packet.Timestamp
+	d := int32(transit - s.transit)
+	if d < 0 {
+		d = -d
+	}
+	s.transit = transit
+	s.jitter += (float64(d) - s.jitter) / 16
+}
+
+func (s *rtpStats) expected() uint32 {
+	return s.cycles + uint32(s.maxSeq) - uint32(s.baseSeq) + 1
+}
+
+func (s *rtpStats) lost() uint32 {
+	if !s.init {
+		return 0
+	}
+	if expected := s.expected(); expected > s.received {
+		return expected - s.received
+	}
+	return 0 // duplicates
+}
+
+func (s *rtpStats) report() rtcp.ReceptionReport {
+	expected := s.expected()
+
+	expectedInterval := expected - s.expectedPrior
+	receivedInterval := s.received - s.receivedPrior
+	s.expectedPrior = expected
+	s.receivedPrior = s.received
+
+	var fraction uint8
+	if expectedInterval > receivedInterval {
+		fraction = uint8((expectedInterval - receivedInterval) << 8 / expectedInterval)
+	}
+
+	return rtcp.ReceptionReport{
+		SSRC:               s.ssrc,
+		FractionLost:       fraction,
+		TotalLost:          min(s.lost(), 0x7FFFFF),
+		LastSequenceNumber: s.cycles + uint32(s.maxSeq),
+		Jitter:             uint32(s.jitter),
+	}
+}
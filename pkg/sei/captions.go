@@ -0,0 +1,244 @@
+package sei
+
+import (
+	"strings"
+)
+
+// https://en.wikipedia.org/wiki/EIA-608
+// https://en.wikipedia.org/wiki/CEA-708
+
+// cea608 - simple text decoder for the CC1 channel, returns the whole caption
+// on the end of caption (pop-on) or carriage return (roll-up, paint-on)
+type cea608 struct {
+	channel byte    // 1 or 2, from the last control code
+	last    [2]byte // control codes are usually sent twice
+	buf     []rune
+}
+
+var chars608 = map[byte]rune{
+	0x2A: 'á', 0x5C: 'é', 0x5E: 'í', 0x5F: 'ó', 0x60: 'ú',
+	0x7B: 'ç', 0x7C: '÷', 0x7D: 'Ñ', 0x7E: 'ñ', 0x7F: '█',
+}
+
+var special608 = []rune("®°½¿™¢£♪à èâêîôû")
+
+func (c *cea608) decode(b1, b2 byte) (text string) {
+	b1 &= 0x7F // remove parity bit
+	b2 &= 0x7F
+
+	switch {
+	case b1 == 0 && b2 == 0: // padding
+		return
+
+	case b1 >= 0x10 && b1 <= 0x1F: // control codes
+		if c.last == [2]byte{b1, b2} {
+			c.last = [2]byte{} // skip repeated code
+			return
+		}
+		c.last = [2]byte{b1, b2}
+
+		if b1 < 0x18 {
+			c.channel = 1
+		} else {
+			c.channel = 2
+			b1 -= 0x08
+		}
+
+		if c.channel != 1 {
+			return
+		}
+
+		switch {
+		case b1 == 0x11 && b2 >= 0x30 && b2 <= 0x3F: // special characters
+			c.buf = append(c.buf, special608[b2-0x30])
+		case (b1 == 0x14 || b1 == 0x15) && b2 >= 0x20 && b2 <= 0x2F: // misc control codes
+			switch b2 {
+			case 0x2C: // erase displayed memory
+			case 0x2D, 0x2F: // carriage return, end of caption
+				text = c.flush()
+			case 0x2E: // erase non-displayed memory
+				c.buf = c.buf[:0]
+			}
+		case b2 >= 0x40: // preamble address code (new row)
+			if n := len(c.buf); n > 0 && c.buf[n-1] != '\n' {
+				c.buf = append(c.buf, '\n')
+			}
+		}
+
+	default: // basic characters
+		c.last = [2]byte{}
+
+		if c.channel == 2 {
+			return
+		}
+
+		c.buf = appendChar608(c.buf, b1)
+		c.buf = appendChar608(c.buf, b2)
+	}
+
+	return
+}
+
+func (c *cea608) flush() string {
+	text := strings.TrimSpace(string(c.buf))
+	c.buf = c.buf[:0]
+	return text
+}
+
+func appendChar608(buf []rune, b byte) []rune {
+	if b < 0x20 {
+		return buf
+	}
+	if r, ok := chars608[b]; ok {
+		return append(buf, r)
+	}
+	return append(buf, rune(b))
+}
+
+// cea708 - simple text decoder for the first service of DTVCC packets
+type cea708 struct {
+	packet []byte
+	size   int
+	buf    []rune
+}
+
+func (c *cea708) decode(valid, start bool, b1, b2 byte) (text string) {
+	if start {
+		if c.packet != nil {
+			text = c.service() // previous packet without the end
+		}
+		if !valid {
+			c.packet = nil
+			return
+		}
+
+		// packet_size_code in 2 bytes units, zero means 128 bytes
+		c.size = int(b1&0x3F) * 2
+		if c.size == 0 {
+			c.size = 128
+		}
+		c.packet = append(c.packet[:0], b1, b2)
+	} else if valid && c.packet != nil {
+		c.packet = append(c.packet, b1, b2)
+	} else {
+		return
+	}
+
+	if len(c.packet) >= c.size {
+		text = c.service()
+	}
+
+	return
+}
+
+// service - parse service blocks of the packet
+func (c *cea708) service() (text string) {
+	b := c.packet[1:] // skip sequence number and packet size
+	c.packet = nil
+
+	for len(b) > 0 {
+		number := b[0] >> 5
+		size := int(b[0] & 0x1F)
+		b = b[1:]
+
+		if number == 0 {
+			break // null service block, the rest is padding
+		}
+		if number == 7 && len(b) > 0 {
+			number = b[0] & 0x3F // extended service number
+			b = b[1:]
+		}
+		if size > len(b) {
+			break
+		}
+
+		if number == 1 {
+			if s := c.decodeBlock(b[:size]); s != "" {
+				text = s
+			}
+		}
+
+		b = b[size:]
+	}
+
+	return
+}
+
+// decodeBlock - G0 and G1 characters, C0 and C1 control codes
+func (c *cea708) decodeBlock(b []byte) (text string) {
+	for i := 0; i < len(b); i++ {
+		switch v := b[i]; {
+		case v == 0x03 || v == 0x0D: // end of text, carriage return
+			text = c.flush()
+		case v == 0x08: // backspace
+			if n := len(c.buf); n > 0 {
+				c.buf = c.buf[:n-1]
+			}
+		case v == 0x0C: // form feed (clear window)
+			c.buf = c.buf[:0]
+		case v == 0x10: // extended code set, skip it with parameters
+			if i++; i < len(b) {
+				switch ext := b[i]; {
+				case ext < 0x08:
+				case ext < 0x10:
+					i++
+				case ext < 0x18:
+					i += 2
+				case ext < 0x20:
+					i += 3
+				case ext >= 0x80 && ext < 0x88:
+					i += 4
+				case ext >= 0x88 && ext < 0x90:
+					i += 5
+				}
+			}
+		case v >= 0x11 && v <= 0x17:
+			i++
+		case v >= 0x18 && v <= 0x1F:
+			i += 2
+		case v < 0x20:
+		case v == 0x7F:
+			c.buf = append(c.buf, '♪')
+		case v < 0x80: // G0 (ASCII)
+			c.buf = append(c.buf, rune(v))
+		case v < 0xA0: // C1 commands
+			switch v {
+			case 0x89, 0x8B: // display windows, toggle windows (pop-on captions)
+				text = c.flush()
+			case 0x88, 0x8C: // clear windows, delete windows
+				c.buf = c.buf[:0]
+			}
+			i += params708(v)
+		default: // G1 (Latin-1)
+			c.buf = append(c.buf, rune(v))
+		}
+	}
+	return
+}
+
+func (c *cea708) flush() string {
+	text := strings.TrimSpace(string(c.buf))
+	c.buf = c.buf[:0]
+	return text
+}
+
+// params708 - number of parameter bytes for the C1 command
+func params708(v byte) int {
+	switch {
+	case v < 0x88: // set current window
+		return 0
+	case v < 0x8E: // windows commands and delay
+		return 1
+	case v < 0x90: // delay cancel, reset
+		return 0
+	case v == 0x90 || v == 0x92: // set pen attributes, set pen location
+		return 2
+	case v == 0x91: // set pen color
+		return 3
+	case v == 0x97: // set window attributes
+		return 4
+	case v >= 0x98: // define window
+		return 6
+	}
+	return 0
+}
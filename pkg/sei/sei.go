@@ -0,0 +1,193 @@
+// Package sei - metadata from H264/H265 SEI messages: time codes, user data and
+// closed captions (CEA-608/708)
+package sei
+
+import (
+	"bytes"
+	"encoding/hex"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
+)
+
+// Scheme - scheme_id_uri for the metadata inside fMP4 emsg boxes
+const Scheme = "urn:go2rtc:sei:2024"
+
+type Metadata struct {
+	Time      int64       `json:"time"`      // server time in milliseconds
+	Timestamp uint32      `json:"timestamp"` // RTP timestamp of the frame
+	TimeCode  string      `json:"timecode,omitempty"`
+	UserData  []*UserData `json:"user_data,omitempty"`
+	Captions  string      `json:"captions,omitempty"` // CEA-608 CC1 or CEA-708 service 1 text
+}
+
+// UserData - user_data_unregistered SEI, printable data as text, other as base64
+type UserData struct {
+	UUID string `json:"uuid"`
+	Text string `json:"text,omitempty"`
+	Data []byte `json:"data,omitempty"`
+}
+
+// Parser - stateful parser for one video track in AVCC format
+type Parser struct {
+	codec string
+	sps   *h264.SPS
+
+	cc608  cea608
+	cc708  cea708
+	has708 bool // ignore CEA-608 when the stream has CEA-708
+}
+
+func NewParser(codec *core.Codec) *Parser {
+	p := &Parser{codec: codec.Name}
+	if codec.Name == core.CodecH264 {
+		if sps, _ := h264.GetParameterSet(codec.FmtpLine); sps != nil {
+			p.sps = h264.DecodeSPS(h264.RBSP(sps))
+		}
+	}
+	return p
+}
+
+// Parse - metadata from the frame, nil if the frame doesn't have it
+func (p *Parser) Parse(avcc []byte) *Metadata {
+	if len(avcc) < 5 {
+		return nil
+	}
+
+	var msgs []*h264.SEIMessage
+
+	switch p.codec {
+	case core.CodecH264:
+		p.updateSPS(avcc)
+		msgs = h264.GetSEI(avcc)
+	case core.CodecH265:
+		msgs = h265.GetSEI(avcc)
+	}
+
+	if msgs == nil {
+		return nil
+	}
+
+	md := &Metadata{}
+
+	for _, msg := range msgs {
+		switch msg.Type {
+		case h264.SEITypePicTiming:
+			if p.codec == core.CodecH264 && p.sps != nil {
+				if tc := p.sps.PicTiming(msg.Payload); tc != "" {
+					md.TimeCode = tc
+				}
+			}
+		case h265.SEITypeTimeCode:
+			if p.codec == core.CodecH265 {
+				if tc := h265.TimeCode(msg.Payload); tc != "" {
+					md.TimeCode = tc
+				}
+			}
+		case h264.SEITypeUserDataRegistered:
+			if s := p.captions(msg.Payload); s != "" {
+				md.Captions += s
+			}
+		case h264.SEITypeUserDataUnregistered:
+			if ud := decodeUserData(msg.Payload); ud != nil {
+				md.UserData = append(md.UserData, ud)
+			}
+		}
+	}
+
+	if md.TimeCode == "" && md.UserData == nil && md.Captions == "" {
+		return nil
+	}
+
+	md.Time = time.Now().UnixMilli()
+
+	return md
+}
+
+func (p *Parser) updateSPS(avcc []byte) {
+	for _, nalu := range h264.SplitNALU(avcc) {
+		if h264.NALUType(nalu) == h264.NALUTypeSPS {
+			if sps := h264.DecodeSPS(h264.RBSP(nalu[4:])); sps != nil {
+				p.sps = sps
+			}
+			return
+		}
+	}
+}
+
+// captions - ATSC A/53 closed captions from the ITU-T T.35 user data
+func (p *Parser) captions(payload []byte) string {
+	// country code (USA), provider code (ATSC), user identifier (GA94), cc_data type
+	if len(payload) < 10 || payload[0] != 0xB5 || payload[1] != 0 || payload[2] != 0x31 ||
+		string(payload[3:7]) != "GA94" || payload[7] != 0x03 {
+		return ""
+	}
+
+	count := int(payload[8] & 0x1F)
+	b := payload[10:] // skip flags and em_data
+
+	var text608, text708 string
+
+	for i := 0; i < count && len(b) >= 3; i++ {
+		valid := b[0]&0x04 != 0
+		typ := b[0] & 0x03
+		data1, data2 := b[1], b[2]
+		b = b[3:]
+
+		switch typ {
+		case 0: // CEA-608 field 1
+			if valid {
+				text608 += p.cc608.decode(data1, data2)
+			}
+		case 2, 3: // CEA-708 DTVCC packet data and packet start
+			p.has708 = true
+			text708 += p.cc708.decode(valid, typ == 3, data1, data2)
+		}
+	}
+
+	if p.has708 {
+		return text708
+	}
+	return text608
+}
+
+func decodeUserData(payload []byte) *UserData {
+	if len(payload) < 16 {
+		return nil
+	}
+
+	uuid := hex.EncodeToString(payload[:16])
+	ud := &UserData{
+		UUID: uuid[:8] + "-" + uuid[8:12] + "-" + uuid[12:16] + "-" + uuid[16:20] + "-" + uuid[20:],
+	}
+
+	data := payload[16:]
+	// some encoders (x264) write zero terminated strings
+	for len(data) > 0 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+
+	if isPrintable(data) {
+		ud.Text = string(data)
+	} else {
+		ud.Data = bytes.Clone(data) // depay may reuse the buffer
+	}
+
+	return ud
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
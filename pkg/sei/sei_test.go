@@ -0,0 +1,67 @@
+package sei
+
+import (
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/bits"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseH264(t *testing.T) {
+	uuid := []byte{0xDC, 0x45, 0xE9, 0xBD, 0xE6, 0xD9, 0x48, 0xB7, 0x96, 0x2C, 0xD8, 0x20, 0xD9, 0x23, 0xEE, 0xEF}
+	unregistered := append(uuid, "hello"...)
+
+	// ATSC A/53 CEA-608: preamble, "HI", end of caption (sent twice)
+	registered := []byte{0xB5, 0x00, 0x31, 'G', 'A', '9', '4', 0x03, 0x40 | 4, 0xFF}
+	registered = append(registered,
+		0xFC, 0x14, 0x70,
+		0xFC, 'H', 'I',
+		0xFC, 0x14, 0x2F,
+		0xFC, 0x14, 0x2F,
+		0xFF,
+	)
+
+	nalu := []byte{h264.NALUTypeSEI}
+	nalu = append(nalu, h264.SEITypeUserDataUnregistered, byte(len(unregistered)))
+	nalu = append(nalu, unregistered...)
+	nalu = append(nalu, h264.SEITypeUserDataRegistered, byte(len(registered)))
+	nalu = append(nalu, registered...)
+	nalu = append(nalu, 0x80) // rbsp_trailing_bits
+
+	frame := h264.JoinNALU(nalu, []byte{0x65, 0x88, 0x84})
+
+	p := NewParser(&core.Codec{Name: core.CodecH264})
+	md := p.Parse(frame)
+	require.NotNil(t, md)
+	require.Equal(t, "dc45e9bd-e6d9-48b7-962c-d820d923eeef", md.UserData[0].UUID)
+	require.Equal(t, "hello", md.UserData[0].Text)
+	require.Equal(t, "HI", md.Captions)
+
+	require.Nil(t, p.Parse(h264.JoinNALU([]byte{0x65, 0x88, 0x84})))
+}
+
+func TestParseH265(t *testing.T) {
+	w := bits.NewWriter(nil)
+	w.WriteBits8(1, 2)   // num_clock_ts
+	w.WriteBit(1)        // clock_timestamp_flag
+	w.WriteBits8(0, 6)   // units_field_based_flag, counting_type
+	w.WriteBits8(1, 1)   // full_timestamp_flag
+	w.WriteBits8(0, 2)   // discontinuity_flag, cnt_dropped_flag
+	w.WriteBits16(12, 9) // n_frames
+	w.WriteBits8(30, 6)
+	w.WriteBits8(15, 6)
+	w.WriteBits8(10, 5)
+	w.WriteBits8(0, 5) // time_offset_length
+	timeCode := w.Bytes()
+
+	nalu := []byte{39 << 1, 1, 136, byte(len(timeCode))} // prefix SEI
+	nalu = append(nalu, timeCode...)
+	nalu = append(nalu, 0x80)
+
+	p := NewParser(&core.Codec{Name: core.CodecH265})
+	md := p.Parse(h264.JoinNALU(nalu))
+	require.NotNil(t, md)
+	require.Equal(t, "10:15:30:12", md.TimeCode)
+}
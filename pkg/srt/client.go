@@ -0,0 +1,274 @@
+package srt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+const (
+	ModeCaller   = "caller"
+	ModeListener = "listener"
+
+	// ListenerTimeout - default wait for the caller in listener mode
+	ListenerTimeout = 15 * time.Second
+
+	handshakeRetry = 250 * time.Millisecond
+)
+
+// Dial - srt://host:port?mode=caller&streamid=...&latency=120&passphrase=...&pbkeylen=16
+// listener mode waits for one caller on the host:port
+func Dial(rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	query := u.Query()
+
+	latency := DefaultLatency
+	if s := query.Get("latency"); s != "" {
+		ms, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, errors.New("srt: wrong latency: " + s)
+		}
+		latency = time.Duration(ms) * time.Millisecond
+	}
+
+	passphrase := query.Get("passphrase")
+	if passphrase != "" {
+		if err = CheckPassphrase(passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	keySize := 16
+	if s := query.Get("pbkeylen"); s != "" {
+		if keySize, _ = strconv.Atoi(s); keySize != 16 && keySize != 24 && keySize != 32 {
+			return nil, errors.New("srt: wrong pbkeylen: " + s)
+		}
+	}
+
+	var timeout time.Duration
+	if s := query.Get("timeout"); s != "" {
+		sec, _ := strconv.Atoi(s)
+		timeout = time.Duration(sec) * time.Second
+	}
+
+	switch mode := query.Get("mode"); mode {
+	case "", ModeCaller:
+		if timeout == 0 {
+			timeout = core.ConnDialTimeout
+		}
+		return call(u.Host, query.Get("streamid"), latency, passphrase, keySize, timeout)
+	case ModeListener:
+		if timeout == 0 {
+			timeout = ListenerTimeout
+		}
+		return listenOne(u.Host, latency, passphrase, timeout)
+	default:
+		return nil, errors.New("srt: unsupported mode: " + mode)
+	}
+}
+
+// call - caller side of the HSv5 handshake: induction and conclusion
+func call(address, streamID string, latency time.Duration, passphrase string, keySize int, timeout time.Duration) (*Conn, error) {
+	if len(streamID) > maxStreamID {
+		return nil, errors.New("srt: stream ID too long")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := newConn(pc, addr, newSocketID(), 0, newSocketID())
+	conn.StreamID = streamID
+	conn.latency = latency
+	conn.passphrase = passphrase
+	conn.release = func() { _ = pc.Close() }
+
+	if err = conn.handshake(keySize, time.Now().Add(timeout)); err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+
+	go conn.readLoop()
+	go conn.run()
+
+	return conn, nil
+}
+
+func (c *Conn) handshake(keySize int, deadline time.Time) error {
+	req := &handshake{
+		version:   hsVersion4,
+		extension: hsDgram,
+		seq:       c.sendSeq,
+		mtu:       mtuSize,
+		window:    flowWindow,
+		typ:       hsTypeInduction,
+		socketID:  c.socketID,
+		peerIP:    addrIP(c.addr),
+	}
+
+	res, err := c.exchange(req, deadline)
+	if err != nil {
+		return err
+	}
+
+	if res.version != hsVersion5 || res.extension != hsMagic {
+		return errors.New("srt: peer doesn't support HSv5")
+	}
+
+	req.version = hsVersion5
+	req.extension = hsExtHSREQ
+	req.typ = hsTypeConclusion
+	req.cookie = res.cookie
+	req.hsType = extTypeHSREQ
+	req.flags = srtFlags
+	req.recvDelay = uint16(c.latency.Milliseconds())
+	req.sendDelay = req.recvDelay
+	req.streamID = c.StreamID
+
+	if c.StreamID != "" {
+		req.extension |= hsExtConfig
+	}
+
+	if c.passphrase != "" {
+		if c.crypto, err = newCrypto(keySize); err != nil {
+			return err
+		}
+		if req.km, err = c.crypto.marshalKM(c.passphrase); err != nil {
+			return err
+		}
+		req.encryption = uint16(keySize / 8)
+		req.extension |= hsExtKMREQ
+		req.kmType = extTypeKMREQ
+	}
+
+	if res, err = c.exchange(req, deadline); err != nil {
+		return err
+	}
+
+	if res.typ != hsTypeConclusion {
+		return fmt.Errorf("srt: connection rejected: %d", res.typ-hsTypeRejectBase)
+	}
+
+	if c.passphrase != "" && (res.kmType != extTypeKMRSP || len(res.km) <= 4) {
+		return ErrPassphrase
+	}
+
+	c.peerID = res.socketID
+	c.latency = max(c.latency, time.Duration(res.recvDelay)*time.Millisecond, time.Duration(res.sendDelay)*time.Millisecond)
+
+	return nil
+}
+
+// exchange - send handshake request until response or deadline
+func (c *Conn) exchange(req *handshake, deadline time.Time) (*handshake, error) {
+	p := &packet{
+		control: true,
+		typ:     typeHandshake,
+		payload: req.marshal(),
+	}
+	b := p.marshal()
+
+	buf := make([]byte, mtuSize)
+	for {
+		if time.Now().After(deadline) {
+			return nil, ErrTimeout
+		}
+
+		if _, err := c.pc.WriteTo(b, c.addr); err != nil {
+			return nil, err
+		}
+
+		retry := time.Now().Add(handshakeRetry)
+		if retry.After(deadline) {
+			retry = deadline
+		}
+		_ = c.pc.SetReadDeadline(retry)
+
+		for {
+			n, addr, err := c.pc.ReadFrom(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					break
+				}
+				return nil, err
+			}
+
+			if addr.String() != c.addr.String() {
+				continue
+			}
+
+			res, err := parsePacket(buf[:n])
+			if err != nil || !res.control || res.typ != typeHandshake || res.socketID != c.socketID {
+				continue
+			}
+
+			hs, err := parseHandshake(res.payload)
+			if err != nil || (req.typ == hsTypeConclusion && hs.typ == hsTypeInduction) {
+				continue
+			}
+
+			_ = c.pc.SetReadDeadline(time.Time{})
+			return hs, nil
+		}
+	}
+}
+
+func (c *Conn) readLoop() {
+	b := make([]byte, mtuSize)
+	for {
+		n, addr, err := c.pc.ReadFrom(b)
+		if err != nil {
+			c.closeWithError(err)
+			return
+		}
+
+		if addr.String() != c.addr.String() {
+			continue
+		}
+
+		p, err := parsePacket(append([]byte(nil), b[:n]...))
+		if err != nil || p.socketID != c.socketID {
+			continue
+		}
+
+		if p.control && p.typ == typeHandshake {
+			continue // retransmitted conclusion response
+		}
+
+		c.handle(p)
+	}
+}
+
+// listenOne - listener for single caller, socket closes with the connection
+func listenOne(address string, latency time.Duration, passphrase string, timeout time.Duration) (*Conn, error) {
+	ln, err := listen(address, Config{Latency: latency, Passphrase: passphrase}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.AfterFunc(timeout, func() { _ = ln.Close() })
+
+	conn, err := ln.Accept()
+	if err != nil || !timer.Stop() {
+		_ = ln.Close()
+		return nil, ErrTimeout
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,577 @@
+package srt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLatency = 120 * time.Millisecond
+
+	PayloadSize = 1316 // 7 MPEG-TS packets
+
+	mtuSize    = 1500
+	flowWindow = 8192
+
+	// recvQueueSize - max payloads for the slow reader, old payloads are dropped
+	recvQueueSize = 8192
+
+	ackInterval       = 10 * time.Millisecond
+	nakInterval       = 20 * time.Millisecond
+	keepaliveInterval = time.Second
+	idleTimeout       = 5 * time.Second
+)
+
+var (
+	ErrTimeout = errors.New("srt: connection timeout")
+	errClosed  = errors.New("srt: connection closed")
+)
+
+// Conn - SRT connection in live mode (message API). Payloads are delivered in sequence
+// order as soon as they are received, lost packets are skipped after the latency.
+// There is no timestamp-based delivery (TSBPD).
+type Conn struct {
+	StreamID string
+
+	pc      net.PacketConn
+	addr    net.Addr
+	release func() // close or unregister socket
+
+	socketID uint32
+	peerID   uint32
+	start    time.Time
+	latency  time.Duration
+
+	passphrase string
+	crypto     *crypto
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// receiver
+	recvNext  uint32 // next sequence number for reading
+	recvMax   uint32 // next after the highest received sequence number
+	recvBuf   map[uint32]*recvPacket
+	recvQueue [][]byte
+	losses    map[uint32]time.Time // missing packets with last NAK time
+	lastRecv  time.Time
+	ackSeq    uint32
+	ackNo     uint32
+	ackTimes  map[uint32]time.Time
+	rtt       time.Duration
+	rttVar    time.Duration
+
+	// sender
+	sendSeq  uint32
+	msgNo    uint32
+	sendBuf  []*sendPacket // not acknowledged packets in sequence order
+	lastSend time.Time
+
+	err       error
+	done      chan struct{}
+	closeOnce sync.Once
+
+	Recv, Send int // payload bytes
+}
+
+type recvPacket struct {
+	payload []byte
+	ts      time.Time
+}
+
+type sendPacket struct {
+	seq uint32
+	raw []byte
+	ts  time.Time
+}
+
+func newConn(pc net.PacketConn, addr net.Addr, socketID, peerID, seq uint32) *Conn {
+	now := time.Now()
+	c := &Conn{
+		pc:       pc,
+		addr:     addr,
+		socketID: socketID,
+		peerID:   peerID,
+		start:    now,
+		latency:  DefaultLatency,
+		recvNext: seq,
+		recvMax:  seq,
+		recvBuf:  map[uint32]*recvPacket{},
+		losses:   map[uint32]time.Time{},
+		lastRecv: now,
+		ackSeq:   seq,
+		ackTimes: map[uint32]time.Time{},
+		rtt:      100 * time.Millisecond,
+		rttVar:   50 * time.Millisecond,
+		sendSeq:  seq,
+		lastSend: now,
+		done:     make(chan struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.pc.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// Latency - negotiated latency of the connection
+func (c *Conn) Latency() time.Duration {
+	return c.latency
+}
+
+// Read - payloads of the data packets in sequence order, lost packets are skipped
+// after the latency
+func (c *Conn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.recvQueue) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.cond.Wait()
+	}
+
+	n := copy(b, c.recvQueue[0])
+	if n < len(c.recvQueue[0]) {
+		c.recvQueue[0] = c.recvQueue[0][n:]
+	} else {
+		c.recvQueue = c.recvQueue[1:]
+	}
+	return n, nil
+}
+
+// Write - split data to the packets with max PayloadSize
+func (c *Conn) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		size := min(len(b), PayloadSize)
+		if err = c.writePacket(b[:size]); err != nil {
+			return
+		}
+		n += size
+		b = b[size:]
+	}
+	return
+}
+
+func (c *Conn) writePacket(payload []byte) error {
+	c.mu.Lock()
+
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+
+	now := time.Now()
+
+	c.msgNo = c.msgNo%msgNoMask + 1
+
+	p := &packet{
+		seq:       c.sendSeq,
+		flags:     flagSolo | c.msgNo,
+		payload:   append([]byte(nil), payload...),
+		timestamp: c.timestamp(now),
+		socketID:  c.peerID,
+	}
+
+	if c.crypto != nil {
+		p.flags |= uint32(c.crypto.kk) << flagKKShift
+		if err := c.crypto.xor(p.seq, c.crypto.kk, p.payload); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+	}
+
+	raw := p.marshal()
+	c.sendBuf = append(c.sendBuf, &sendPacket{seq: p.seq, raw: raw, ts: now})
+	c.sendSeq = seqAdd(c.sendSeq, 1)
+	c.lastSend = now
+	c.Send += len(payload)
+
+	c.mu.Unlock()
+
+	_, err := c.pc.WriteTo(raw, c.addr)
+	return err
+}
+
+// Close - send shutdown to the peer and release the socket
+func (c *Conn) Close() error {
+	c.sendControl(typeShutdown, 0, 0, make([]byte, 4))
+	c.closeWithError(errClosed)
+	return nil
+}
+
+func (c *Conn) closeWithError(err error) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.cond.Broadcast()
+		c.mu.Unlock()
+
+		close(c.done)
+
+		if c.release != nil {
+			c.release()
+		}
+	})
+}
+
+func (c *Conn) timestamp(now time.Time) uint32 {
+	return uint32(now.Sub(c.start).Microseconds())
+}
+
+func (c *Conn) sendControl(typ, subtype uint16, info uint32, payload []byte) {
+	now := time.Now()
+
+	p := &packet{
+		control:   true,
+		typ:       typ,
+		subtype:   subtype,
+		info:      info,
+		payload:   payload,
+		timestamp: c.timestamp(now),
+		socketID:  c.peerID,
+	}
+
+	c.mu.Lock()
+	c.lastSend = now
+	c.mu.Unlock()
+
+	_, _ = c.pc.WriteTo(p.marshal(), c.addr)
+}
+
+// run - periodic ACK, NAK, keepalive and timeouts until connection close
+func (c *Conn) run() {
+	ticker := time.NewTicker(ackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := c.tick(now); err != nil {
+				c.closeWithError(err)
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Conn) tick(now time.Time) error {
+	c.mu.Lock()
+
+	if now.Sub(c.lastRecv) > idleTimeout {
+		c.mu.Unlock()
+		return ErrTimeout
+	}
+
+	c.deliver(now)
+
+	var ack []byte
+	if c.ackSeq != c.recvNext {
+		c.ackSeq = c.recvNext
+		c.ackNo++
+		c.ackTimes[c.ackNo] = now
+		delete(c.ackTimes, c.ackNo-64)
+
+		ack = make([]byte, 28)
+		binary.BigEndian.PutUint32(ack, c.ackSeq)
+		binary.BigEndian.PutUint32(ack[4:], uint32(c.rtt.Microseconds()))
+		binary.BigEndian.PutUint32(ack[8:], uint32(c.rttVar.Microseconds()))
+		binary.BigEndian.PutUint32(ack[12:], uint32(max(flowWindow-len(c.recvBuf), 2)))
+	}
+	ackNo := c.ackNo
+
+	// periodic NAK report for the packets that are still missing
+	var nak []byte
+	interval := max(c.rtt+4*c.rttVar, nakInterval)
+	for seq, ts := range c.losses {
+		if now.Sub(ts) >= interval && len(nak) < mtuSize-headerSize-28 {
+			nak = binary.BigEndian.AppendUint32(nak, seq)
+			c.losses[seq] = now
+		}
+	}
+
+	// sender drops packets that are too late for the peer
+	for len(c.sendBuf) > 0 && now.Sub(c.sendBuf[0].ts) > c.latency+time.Second {
+		c.sendBuf = c.sendBuf[1:]
+	}
+
+	keepalive := now.Sub(c.lastSend) >= keepaliveInterval
+
+	c.mu.Unlock()
+
+	if ack != nil {
+		c.sendControl(typeACK, 0, ackNo, ack)
+	}
+	if nak != nil {
+		c.sendControl(typeNAK, 0, 0, nak)
+	}
+	if keepalive {
+		c.sendControl(typeKeepalive, 0, 0, make([]byte, 4))
+	}
+
+	return nil
+}
+
+// deliver - move packets in sequence order to the read queue, skip missing packets
+// when the next received packet waits longer than the latency
+func (c *Conn) deliver(now time.Time) {
+	n := len(c.recvQueue)
+
+	for c.recvNext != c.recvMax {
+		if p := c.recvBuf[c.recvNext]; p != nil {
+			c.recvQueue = append(c.recvQueue, p.payload)
+			delete(c.recvBuf, c.recvNext)
+			c.recvNext = seqAdd(c.recvNext, 1)
+			continue
+		}
+
+		// find first received packet after the gap
+		seq := seqAdd(c.recvNext, 1)
+		for ; seq != c.recvMax; seq = seqAdd(seq, 1) {
+			if c.recvBuf[seq] != nil {
+				break
+			}
+		}
+
+		if seq == c.recvMax || now.Sub(c.recvBuf[seq].ts) < c.latency {
+			break
+		}
+
+		for ; c.recvNext != seq; c.recvNext = seqAdd(c.recvNext, 1) {
+			delete(c.losses, c.recvNext)
+		}
+	}
+
+	if len(c.recvQueue) > n {
+		// reader is too slow, drop old payloads
+		if i := len(c.recvQueue) - recvQueueSize; i > 0 {
+			c.recvQueue = c.recvQueue[i:]
+		}
+		c.cond.Broadcast()
+	}
+}
+
+func (c *Conn) handle(p *packet) {
+	now := time.Now()
+
+	c.mu.Lock()
+	c.lastRecv = now
+	c.mu.Unlock()
+
+	if !p.control {
+		c.handleData(p, now)
+		return
+	}
+
+	switch p.typ {
+	case typeACK:
+		c.handleACK(p)
+	case typeNAK:
+		c.handleNAK(p)
+	case typeACKACK:
+		c.mu.Lock()
+		if ts, ok := c.ackTimes[p.info]; ok {
+			delete(c.ackTimes, p.info)
+			c.updateRTT(now.Sub(ts))
+		}
+		c.mu.Unlock()
+	case typeShutdown:
+		// packets after the gaps will never be completed
+		c.mu.Lock()
+		c.deliver(now.Add(c.latency))
+		c.mu.Unlock()
+		c.closeWithError(io.EOF)
+	case typeUserExt:
+		if p.subtype == extKMREQ {
+			c.handleKM(p.payload)
+		}
+	}
+}
+
+func (c *Conn) handleData(p *packet, now time.Time) {
+	c.mu.Lock()
+
+	if kk := p.key(); kk != 0 {
+		if c.crypto == nil || c.crypto.xor(p.seq, kk, p.payload) != nil {
+			c.mu.Unlock()
+			return
+		}
+	} else if c.crypto != nil {
+		c.mu.Unlock()
+		return // plaintext packet on the encrypted connection
+	}
+
+	if seqDiff(p.seq, c.recvNext) < 0 || c.recvBuf[p.seq] != nil {
+		c.mu.Unlock()
+		return // too late or duplicate
+	}
+
+	var lost []uint32
+
+	if d := seqDiff(p.seq, c.recvMax); d >= 0 {
+		if d > flowWindow {
+			// peer restarted sequence or lost too many packets
+			clear(c.recvBuf)
+			clear(c.losses)
+			c.recvNext = p.seq
+		} else {
+			for seq := c.recvMax; seq != p.seq; seq = seqAdd(seq, 1) {
+				c.losses[seq] = now
+				lost = append(lost, seq)
+			}
+		}
+		c.recvMax = seqAdd(p.seq, 1)
+	} else {
+		delete(c.losses, p.seq)
+	}
+
+	c.recvBuf[p.seq] = &recvPacket{payload: p.payload, ts: now}
+	c.Recv += len(p.payload)
+
+	c.deliver(now)
+
+	c.mu.Unlock()
+
+	if lost != nil {
+		// immediate loss report
+		c.sendControl(typeNAK, 0, 0, encodeLossList(lost))
+	}
+}
+
+func (c *Conn) handleACK(p *packet) {
+	if len(p.payload) < 4 {
+		return
+	}
+
+	seq := binary.BigEndian.Uint32(p.payload) & seqMask
+
+	c.mu.Lock()
+	i := 0
+	for ; i < len(c.sendBuf) && seqDiff(c.sendBuf[i].seq, seq) < 0; i++ {
+	}
+	c.sendBuf = c.sendBuf[i:]
+	if len(p.payload) >= 8 {
+		if rtt := binary.BigEndian.Uint32(p.payload[4:]); rtt > 0 {
+			c.rtt = time.Duration(rtt) * time.Microsecond
+		}
+	}
+	c.mu.Unlock()
+
+	// light ACK doesn't need ACKACK
+	if len(p.payload) > 4 {
+		c.sendControl(typeACKACK, 0, p.info, make([]byte, 4))
+	}
+}
+
+func (c *Conn) handleNAK(p *packet) {
+	var resend [][]byte
+
+	c.mu.Lock()
+	for _, seq := range decodeLossList(p.payload) {
+		if len(c.sendBuf) == 0 {
+			break
+		}
+		i := int(seqDiff(seq, c.sendBuf[0].seq))
+		if i < 0 || i >= len(c.sendBuf) {
+			continue
+		}
+
+		// same packet with retransmitted flag and new timestamp
+		raw := append([]byte(nil), c.sendBuf[i].raw...)
+		raw[4] |= flagRexmit >> 24
+		binary.BigEndian.PutUint32(raw[8:], c.timestamp(time.Now()))
+		resend = append(resend, raw)
+	}
+	c.mu.Unlock()
+
+	for _, raw := range resend {
+		_, _ = c.pc.WriteTo(raw, c.addr)
+	}
+}
+
+// handleKM - keys refresh from the sender
+func (c *Conn) handleKM(b []byte) {
+	if c.passphrase == "" {
+		return
+	}
+
+	cr, err := parseKM(b, c.passphrase)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.crypto = cr
+	c.mu.Unlock()
+
+	c.sendControl(typeUserExt, extKMRSP, 0, b)
+}
+
+func (c *Conn) updateRTT(sample time.Duration) {
+	diff := c.rtt - sample
+	if diff < 0 {
+		diff = -diff
+	}
+	c.rttVar = (c.rttVar*3 + diff) / 4
+	c.rtt = (c.rtt*7 + sample) / 8
+}
+
+// encodeLossList - ranges are start with the highest bit and end
+func encodeLossList(seqs []uint32) []byte {
+	var b []byte
+	for i := 0; i < len(seqs); {
+		j := i
+		for j+1 < len(seqs) && seqs[j+1] == seqAdd(seqs[j], 1) {
+			j++
+		}
+		if i == j {
+			b = binary.BigEndian.AppendUint32(b, seqs[i])
+		} else {
+			b = binary.BigEndian.AppendUint32(b, seqs[i]|1<<31)
+			b = binary.BigEndian.AppendUint32(b, seqs[j])
+		}
+		i = j + 1
+	}
+	return b
+}
+
+func decodeLossList(b []byte) (seqs []uint32) {
+	for len(b) >= 4 {
+		seq := binary.BigEndian.Uint32(b)
+		b = b[4:]
+
+		if seq&(1<<31) == 0 {
+			seqs = append(seqs, seq)
+			continue
+		}
+
+		if len(b) < 4 {
+			break
+		}
+
+		seq &= seqMask
+		end := binary.BigEndian.Uint32(b) & seqMask
+		b = b[4:]
+
+		for n := 0; n <= flowWindow; n++ {
+			seqs = append(seqs, seq)
+			if seq == end {
+				break
+			}
+			seq = seqAdd(seq, 1)
+		}
+	}
+	return
+}
@@ -0,0 +1,234 @@
+package srt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// https://datatracker.ietf.org/doc/html/draft-sharabayko-srt#section-6
+
+const (
+	kmVersion   = 0x12 // S=0, V=1, PT=2 (KMmsg)
+	kmSign      = 0x2029
+	kmCipherCTR = 2
+	kmSE        = 2 // stream encapsulation: MPEG-TS/SRT
+	kmSaltSize  = 16
+
+	keyEven = 1
+	keyOdd  = 2
+	keyBoth = keyEven | keyOdd
+
+	pbkdf2Iterations = 2048
+)
+
+var (
+	ErrPassphrase = errors.New("srt: wrong passphrase")
+	errKM         = errors.New("srt: wrong KM message")
+)
+
+// CheckPassphrase - SRT passphrase should be from 10 to 79 characters
+func CheckPassphrase(passphrase string) error {
+	if n := len(passphrase); n < 10 || n > 79 {
+		return errors.New("srt: passphrase should be 10-79 characters")
+	}
+	return nil
+}
+
+// crypto - AES-CTR payload encryption with even and odd keys
+type crypto struct {
+	salt   []byte
+	keys   [2][]byte // even, odd
+	blocks [2]cipher.Block
+	kk     byte // key for sending
+}
+
+// newCrypto - new random salt and even key with AES-128/192/256 key size
+func newCrypto(keySize int) (*crypto, error) {
+	b := make([]byte, kmSaltSize+keySize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	c := &crypto{salt: b[:kmSaltSize], kk: keyEven}
+	if err := c.setKey(0, b[kmSaltSize:]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *crypto) setKey(i int, key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	c.keys[i] = key
+	c.blocks[i] = block
+	return nil
+}
+
+// xor - encrypt or decrypt the data packet payload in place
+func (c *crypto) xor(seq uint32, kk byte, payload []byte) error {
+	if kk != keyEven && kk != keyOdd {
+		return errKM
+	}
+
+	block := c.blocks[kk-1]
+	if block == nil {
+		return errKM
+	}
+
+	// IV: packet index in bytes 10..13, xored with 112 bits of the salt
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint32(iv[10:], seq)
+	subtle.XORBytes(iv[:14], iv[:14], c.salt[:14])
+
+	cipher.NewCTR(block, iv).XORKeyStream(payload, payload)
+	return nil
+}
+
+// kek - key encrypting key from the passphrase and 64 LSB of the salt
+func kek(passphrase string, salt []byte, keySize int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt[8:], pbkdf2Iterations, keySize, sha1.New)
+}
+
+// marshalKM - KM message with all keys wrapped by the passphrase
+func (c *crypto) marshalKM(passphrase string) ([]byte, error) {
+	var kk byte
+	var keys []byte
+	for i, key := range c.keys {
+		if key != nil {
+			kk |= 1 << i
+			keys = append(keys, key...)
+		}
+	}
+
+	keySize := len(c.keys[0])
+	if keySize == 0 {
+		keySize = len(c.keys[1])
+	}
+
+	wrapped, err := keyWrap(kek(passphrase, c.salt, keySize), keys)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16, 16+kmSaltSize+len(wrapped))
+	b[0] = kmVersion
+	binary.BigEndian.PutUint16(b[1:], kmSign)
+	b[3] = kk
+	// b[4:8] - KEKI, zero for default stream associated key
+	b[8] = kmCipherCTR
+	b[10] = kmSE
+	b[14] = kmSaltSize / 4
+	b[15] = byte(keySize / 4)
+	b = append(b, c.salt...)
+	b = append(b, wrapped...)
+	return b, nil
+}
+
+// parseKM - unwrap keys from the KM message with the passphrase
+func parseKM(b []byte, passphrase string) (*crypto, error) {
+	if len(b) < 16 || b[0] != kmVersion || binary.BigEndian.Uint16(b[1:]) != kmSign || b[8] != kmCipherCTR {
+		return nil, errKM
+	}
+
+	kk := b[3] & keyBoth
+	saltSize := int(b[14]) * 4
+	keySize := int(b[15]) * 4
+	if kk == 0 || saltSize != kmSaltSize || (keySize != 16 && keySize != 24 && keySize != 32) {
+		return nil, errKM
+	}
+
+	n := keySize
+	if kk == keyBoth {
+		n *= 2
+	}
+
+	if len(b) < 16+saltSize+8+n {
+		return nil, errKM
+	}
+
+	salt := b[16 : 16+saltSize]
+	keys, err := keyUnwrap(kek(passphrase, salt, keySize), b[16+saltSize:16+saltSize+8+n])
+	if err != nil {
+		return nil, err
+	}
+
+	c := &crypto{salt: append([]byte(nil), salt...)}
+	for i := 0; i < 2; i++ {
+		if kk&(1<<i) == 0 {
+			continue
+		}
+		if err = c.setKey(i, keys[:keySize]); err != nil {
+			return nil, err
+		}
+		keys = keys[keySize:]
+		c.kk = 1 << i
+	}
+	return c, nil
+}
+
+var keyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// keyWrap - AES Key Wrap (RFC 3394)
+func keyWrap(kek, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(plain) / 8
+	b := make([]byte, 8+len(plain))
+	copy(b, keyWrapIV)
+	copy(b[8:], plain)
+
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, b[:8])
+			copy(buf[8:], b[i*8:])
+			block.Encrypt(buf, buf)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b, binary.BigEndian.Uint64(buf)^t)
+			copy(b[i*8:], buf[8:])
+		}
+	}
+	return b, nil
+}
+
+// keyUnwrap - AES Key Unwrap (RFC 3394) with integrity check
+func keyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	b := append([]byte(nil), wrapped...)
+
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(b)^t)
+			copy(buf[8:], b[i*8:])
+			block.Decrypt(buf, buf)
+
+			copy(b, buf[:8])
+			copy(b[i*8:], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(b[:8], keyWrapIV) != 1 {
+		return nil, ErrPassphrase
+	}
+	return b[8:], nil
+}
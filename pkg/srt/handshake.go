@@ -0,0 +1,198 @@
+package srt
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	hsSize = 48
+
+	hsVersion4 = 4
+	hsVersion5 = 5
+	hsMagic    = 0x4A17 // extension field of the HSv5 induction response
+	hsDgram    = 2      // extension field of the HSv4 induction request
+
+	hsTypeInduction  = 1
+	hsTypeConclusion = 0xFFFFFFFF
+	hsTypeRejectBase = 1000
+
+	// extension field flags of the conclusion
+	hsExtHSREQ  = 0x1
+	hsExtKMREQ  = 0x2
+	hsExtConfig = 0x4
+
+	// extension block types
+	extTypeHSREQ = 1
+	extTypeHSRSP = 2
+	extTypeKMREQ = 3
+	extTypeKMRSP = 4
+	extTypeSID   = 5
+
+	srtVersion = 0x00010502
+
+	// SRT flags of HSREQ/HSRSP: TSBPDSND | TSBPDRCV | HAICRYPT | TLPKTDROP | NAKREPORT | REXMITFLG
+	srtFlags = 0x3F
+
+	maxStreamID = 512
+)
+
+// RejectReason - handshake rejection code, below 1000 - SRT, 1000+ - HTTP-like
+// predefined by access control, 2000+ - user defined
+type RejectReason uint32
+
+const (
+	RejectPeer      RejectReason = 2
+	RejectResource  RejectReason = 3
+	RejectBadSecret RejectReason = 10
+	RejectUnsecure  RejectReason = 11
+	RejectForbidden RejectReason = 1403
+	RejectNotFound  RejectReason = 1404
+)
+
+type handshake struct {
+	version    uint32
+	encryption uint16 // key size / 8
+	extension  uint16
+	seq        uint32
+	mtu        uint32
+	window     uint32
+	typ        uint32
+	socketID   uint32
+	cookie     uint32
+	peerIP     net.IP
+
+	// SRT extensions
+	hsType    uint16 // extTypeHSREQ or extTypeHSRSP
+	flags     uint32
+	recvDelay uint16 // latency in ms
+	sendDelay uint16 // latency in ms
+	kmType    uint16 // extTypeKMREQ or extTypeKMRSP
+	km        []byte
+	streamID  string
+}
+
+func parseHandshake(b []byte) (*handshake, error) {
+	if len(b) < hsSize {
+		return nil, errShortPacket
+	}
+
+	hs := &handshake{
+		version:    binary.BigEndian.Uint32(b),
+		encryption: binary.BigEndian.Uint16(b[4:]),
+		extension:  binary.BigEndian.Uint16(b[6:]),
+		seq:        binary.BigEndian.Uint32(b[8:]),
+		mtu:        binary.BigEndian.Uint32(b[12:]),
+		window:     binary.BigEndian.Uint32(b[16:]),
+		typ:        binary.BigEndian.Uint32(b[20:]),
+		socketID:   binary.BigEndian.Uint32(b[24:]),
+		cookie:     binary.BigEndian.Uint32(b[28:]),
+		peerIP:     parseIP(b[32:48]),
+	}
+
+	for b = b[hsSize:]; len(b) >= 4; {
+		typ := binary.BigEndian.Uint16(b)
+		size := int(binary.BigEndian.Uint16(b[2:])) * 4
+		if len(b) < 4+size {
+			return nil, errShortPacket
+		}
+
+		data := b[4 : 4+size]
+		b = b[4+size:]
+
+		switch typ {
+		case extTypeHSREQ, extTypeHSRSP:
+			if len(data) < 12 {
+				return nil, errShortPacket
+			}
+			hs.hsType = typ
+			hs.flags = binary.BigEndian.Uint32(data[4:])
+			hs.recvDelay = binary.BigEndian.Uint16(data[8:])
+			hs.sendDelay = binary.BigEndian.Uint16(data[10:])
+		case extTypeKMREQ, extTypeKMRSP:
+			hs.kmType = typ
+			hs.km = data
+		case extTypeSID:
+			hs.streamID = string(swapWords(data))
+		}
+	}
+
+	return hs, nil
+}
+
+func (hs *handshake) marshal() []byte {
+	b := make([]byte, hsSize, 256)
+	binary.BigEndian.PutUint32(b, hs.version)
+	binary.BigEndian.PutUint16(b[4:], hs.encryption)
+	binary.BigEndian.PutUint16(b[6:], hs.extension)
+	binary.BigEndian.PutUint32(b[8:], hs.seq)
+	binary.BigEndian.PutUint32(b[12:], hs.mtu)
+	binary.BigEndian.PutUint32(b[16:], hs.window)
+	binary.BigEndian.PutUint32(b[20:], hs.typ)
+	binary.BigEndian.PutUint32(b[24:], hs.socketID)
+	binary.BigEndian.PutUint32(b[28:], hs.cookie)
+	marshalIP(b[32:48], hs.peerIP)
+
+	if hs.hsType != 0 {
+		data := make([]byte, 12)
+		binary.BigEndian.PutUint32(data, srtVersion)
+		binary.BigEndian.PutUint32(data[4:], hs.flags)
+		binary.BigEndian.PutUint16(data[8:], hs.recvDelay)
+		binary.BigEndian.PutUint16(data[10:], hs.sendDelay)
+		b = appendExtension(b, hs.hsType, data)
+	}
+
+	if hs.km != nil {
+		b = appendExtension(b, hs.kmType, hs.km)
+	}
+
+	if hs.streamID != "" {
+		b = appendExtension(b, extTypeSID, swapWords([]byte(hs.streamID)))
+	}
+
+	return b
+}
+
+func appendExtension(b []byte, typ uint16, data []byte) []byte {
+	size := (len(data) + 3) / 4
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint16(b, uint16(size))
+	b = append(b, data...)
+	return append(b, make([]byte, size*4-len(data))...)
+}
+
+// swapWords - stream ID is sent as 32-bit little endian words, padded with zeros
+func swapWords(b []byte) []byte {
+	n := (len(b) + 3) / 4 * 4
+	out := make([]byte, n)
+	copy(out, b)
+	for i := 0; i < n; i += 4 {
+		out[i], out[i+1], out[i+2], out[i+3] = out[i+3], out[i+2], out[i+1], out[i]
+	}
+	// remove padding
+	for n > 0 && out[n-1] == 0 {
+		n--
+	}
+	return out[:n]
+}
+
+// peer IP is 4 little endian 32-bit words, IPv4 uses only first word
+func parseIP(b []byte) net.IP {
+	ip := make(net.IP, 16)
+	for i := 0; i < 16; i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+	if b[4]|b[5]|b[6]|b[7]|b[8]|b[9]|b[10]|b[11]|b[12]|b[13]|b[14]|b[15] == 0 {
+		return ip[:4]
+	}
+	return ip
+}
+
+func marshalIP(b []byte, ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for i := 0; i+4 <= len(ip); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+}
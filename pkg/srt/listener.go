@@ -0,0 +1,312 @@
+package srt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config - listener options
+type Config struct {
+	Latency    time.Duration
+	Passphrase string
+
+	// Validate - check stream ID of the caller before the handshake response,
+	// non zero reason rejects the connection
+	Validate func(streamID string, addr net.Addr) RejectReason
+}
+
+// Listener - SRT server, all connections use one UDP socket and demuxed by socket ID
+type Listener struct {
+	config Config
+
+	pc     net.PacketConn
+	secret []byte
+	single bool // close socket with the first connection
+
+	mu      sync.Mutex
+	conns   map[uint32]*Conn  // by local socket ID
+	answers map[string][]byte // conclusion responses by peer address and socket ID
+
+	accept    chan *Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func Listen(address string, config Config) (*Listener, error) {
+	return listen(address, config, false)
+}
+
+func listen(address string, config Config, single bool) (*Listener, error) {
+	pc, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 16)
+	if _, err = rand.Read(secret); err != nil {
+		_ = pc.Close()
+		return nil, err
+	}
+
+	l := &Listener{
+		config:  config,
+		pc:      pc,
+		secret:  secret,
+		single:  single,
+		conns:   map[uint32]*Conn{},
+		answers: map[string][]byte{},
+		accept:  make(chan *Conn, 8),
+		done:    make(chan struct{}),
+	}
+
+	go l.serve()
+
+	return l, nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return l.pc.LocalAddr()
+}
+
+// Accept - wait for the connection with completed handshake
+func (l *Listener) Accept() (*Conn, error) {
+	select {
+	case conn := <-l.accept:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close - close UDP socket and all connections
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+
+	l.mu.Lock()
+	conns := make([]*Conn, 0, len(l.conns))
+	for _, conn := range l.conns {
+		conns = append(conns, conn)
+	}
+	l.mu.Unlock()
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+
+	return l.pc.Close()
+}
+
+func (l *Listener) serve() {
+	b := make([]byte, mtuSize)
+	for {
+		n, addr, err := l.pc.ReadFrom(b)
+		if err != nil {
+			_ = l.Close()
+			return
+		}
+
+		p, err := parsePacket(append([]byte(nil), b[:n]...))
+		if err != nil {
+			continue
+		}
+
+		if p.socketID == 0 {
+			if p.control && p.typ == typeHandshake {
+				l.handshake(p, addr)
+			}
+			continue
+		}
+
+		l.mu.Lock()
+		conn := l.conns[p.socketID]
+		l.mu.Unlock()
+
+		if conn != nil && conn.addr.String() == addr.String() {
+			conn.handle(p)
+		}
+	}
+}
+
+func (l *Listener) handshake(p *packet, addr net.Addr) {
+	req, err := parseHandshake(p.payload)
+	if err != nil {
+		return
+	}
+
+	res := &handshake{
+		version: hsVersion5,
+		seq:     req.seq,
+		mtu:     mtuSize,
+		window:  flowWindow,
+		typ:     req.typ,
+		peerIP:  addrIP(addr),
+	}
+
+	switch req.typ {
+	case hsTypeInduction:
+		res.extension = hsMagic
+		res.cookie = l.cookie(addr, time.Now())
+
+	case hsTypeConclusion:
+		key := addr.String() + "/" + strconv.FormatUint(uint64(req.socketID), 10)
+
+		// retransmitted conclusion, our response was lost
+		l.mu.Lock()
+		answer := l.answers[key]
+		l.mu.Unlock()
+
+		if answer != nil {
+			_, _ = l.pc.WriteTo(answer, addr)
+			return
+		}
+
+		if !l.checkCookie(req.cookie, addr) {
+			return
+		}
+
+		conn, reason := l.conclusion(req, res, addr)
+		if reason != 0 {
+			res = &handshake{version: hsVersion5, typ: hsTypeRejectBase + uint32(reason)}
+			break
+		}
+
+		answer = l.marshalHandshake(res, req.socketID)
+
+		l.mu.Lock()
+		l.conns[conn.socketID] = conn
+		l.answers[key] = answer
+		l.mu.Unlock()
+
+		conn.release = func() {
+			l.mu.Lock()
+			delete(l.conns, conn.socketID)
+			delete(l.answers, key)
+			l.mu.Unlock()
+
+			if l.single {
+				l.closeOnce.Do(func() { close(l.done) })
+				_ = l.pc.Close()
+			}
+		}
+
+		_, _ = l.pc.WriteTo(answer, addr)
+
+		go conn.run()
+
+		select {
+		case l.accept <- conn:
+		default:
+			conn.closeWithError(errClosed) // nobody accepts connections
+		}
+		return
+
+	default:
+		return
+	}
+
+	_, _ = l.pc.WriteTo(l.marshalHandshake(res, req.socketID), addr)
+}
+
+// conclusion - negotiate latency and encryption, fill response and create connection
+func (l *Listener) conclusion(req, res *handshake, addr net.Addr) (*Conn, RejectReason) {
+	if req.version != hsVersion5 || req.hsType != extTypeHSREQ {
+		return nil, RejectPeer
+	}
+
+	var cr *crypto
+	if l.config.Passphrase != "" {
+		if req.km == nil {
+			return nil, RejectUnsecure
+		}
+		var err error
+		if cr, err = parseKM(req.km, l.config.Passphrase); err != nil {
+			return nil, RejectBadSecret
+		}
+		res.encryption = req.encryption
+		res.extension = hsExtKMREQ
+		res.kmType = extTypeKMRSP
+		res.km = req.km
+	} else if req.km != nil {
+		return nil, RejectUnsecure
+	}
+
+	if l.single {
+		l.mu.Lock()
+		n := len(l.conns)
+		l.mu.Unlock()
+		if n > 0 {
+			return nil, RejectResource
+		}
+	}
+
+	if l.config.Validate != nil {
+		if reason := l.config.Validate(req.streamID, addr); reason != 0 {
+			return nil, reason
+		}
+	}
+
+	latency := l.config.Latency
+	if latency == 0 {
+		latency = DefaultLatency
+	}
+	latency = max(latency, time.Duration(req.recvDelay)*time.Millisecond, time.Duration(req.sendDelay)*time.Millisecond)
+
+	conn := newConn(l.pc, addr, newSocketID(), req.socketID, req.seq)
+	conn.StreamID = req.streamID
+	conn.latency = latency
+	conn.passphrase = l.config.Passphrase
+	conn.crypto = cr
+
+	res.extension |= hsExtHSREQ
+	res.socketID = conn.socketID
+	res.hsType = extTypeHSRSP
+	res.flags = srtFlags
+	res.recvDelay = uint16(latency.Milliseconds())
+	res.sendDelay = res.recvDelay
+
+	return conn, 0
+}
+
+func (l *Listener) marshalHandshake(hs *handshake, socketID uint32) []byte {
+	p := &packet{
+		control:  true,
+		typ:      typeHandshake,
+		payload:  hs.marshal(),
+		socketID: socketID,
+	}
+	return p.marshal()
+}
+
+// cookie - SYN cookie from the peer address and current minute
+func (l *Listener) cookie(addr net.Addr, ts time.Time) uint32 {
+	h := hmac.New(sha256.New, l.secret)
+	h.Write([]byte(addr.String()))
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(ts.Unix()/60)))
+	return binary.BigEndian.Uint32(h.Sum(nil))
+}
+
+func (l *Listener) checkCookie(cookie uint32, addr net.Addr) bool {
+	now := time.Now()
+	return cookie == l.cookie(addr, now) || cookie == l.cookie(addr, now.Add(-time.Minute))
+}
+
+func newSocketID() uint32 {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return binary.BigEndian.Uint32(b)&seqMask | 1 // non zero
+}
+
+func addrIP(addr net.Addr) net.IP {
+	if udp, ok := addr.(*net.UDPAddr); ok {
+		return udp.IP
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package srt
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// https://datatracker.ietf.org/doc/html/draft-sharabayko-srt
+
+const headerSize = 16
+
+// control packet types
+const (
+	typeHandshake = 0x0
+	typeKeepalive = 0x1
+	typeACK       = 0x2
+	typeNAK       = 0x3
+	typeShutdown  = 0x5
+	typeACKACK    = 0x6
+	typeUserExt   = 0x7FFF
+)
+
+// user-defined control subtypes
+const (
+	extKMREQ = 3
+	extKMRSP = 4
+)
+
+// data packet flags (second header word)
+const (
+	flagSolo    = 0b11 << 30 // PP: solo message packet
+	flagKKShift = 27
+	flagKKMask  = 0b11 << flagKKShift
+	flagRexmit  = 1 << 26
+	msgNoMask   = 1<<26 - 1
+)
+
+const seqMask = 1<<31 - 1
+
+type packet struct {
+	control bool
+
+	// data packet
+	seq     uint32
+	flags   uint32 // second header word with message number
+	payload []byte
+
+	// control packet
+	typ     uint16
+	subtype uint16
+	info    uint32 // type-specific information
+
+	timestamp uint32 // microseconds from connection start
+	socketID  uint32 // destination socket ID
+}
+
+var errShortPacket = errors.New("srt: short packet")
+
+func parsePacket(b []byte) (*packet, error) {
+	if len(b) < headerSize {
+		return nil, errShortPacket
+	}
+
+	p := &packet{
+		timestamp: binary.BigEndian.Uint32(b[8:]),
+		socketID:  binary.BigEndian.Uint32(b[12:]),
+		payload:   b[headerSize:],
+	}
+
+	word0 := binary.BigEndian.Uint32(b)
+	if word0&(1<<31) != 0 {
+		p.control = true
+		p.typ = uint16(word0>>16) & 0x7FFF
+		p.subtype = uint16(word0)
+		p.info = binary.BigEndian.Uint32(b[4:])
+	} else {
+		p.seq = word0
+		p.flags = binary.BigEndian.Uint32(b[4:])
+	}
+
+	return p, nil
+}
+
+func (p *packet) marshal() []byte {
+	b := make([]byte, headerSize+len(p.payload))
+	if p.control {
+		binary.BigEndian.PutUint32(b, 1<<31|uint32(p.typ)<<16|uint32(p.subtype))
+		binary.BigEndian.PutUint32(b[4:], p.info)
+	} else {
+		binary.BigEndian.PutUint32(b, p.seq&seqMask)
+		binary.BigEndian.PutUint32(b[4:], p.flags)
+	}
+	binary.BigEndian.PutUint32(b[8:], p.timestamp)
+	binary.BigEndian.PutUint32(b[12:], p.socketID)
+	copy(b[headerSize:], p.payload)
+	return b
+}
+
+// key - encryption key index of the data packet: 0 - none, 1 - even, 2 - odd
+func (p *packet) key() byte {
+	return byte((p.flags & flagKKMask) >> flagKKShift)
+}
+
+// seqAdd - sequence number arithmetic (31 bit with wrap around)
+func seqAdd(seq uint32, n int32) uint32 {
+	return (seq + uint32(n)) & seqMask
+}
+
+// seqDiff - signed distance from b to a
+func seqDiff(a, b uint32) int32 {
+	return int32((a-b)<<1) >> 1
+}
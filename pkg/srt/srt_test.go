@@ -0,0 +1,154 @@
+package srt
+
+import (
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyWrap(t *testing.T) {
+	// RFC 3394 4.1 Wrap 128 bits of Key Data with a 128-bit KEK
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF")
+
+	wrapped, err := keyWrap(kek, key)
+	require.Nil(t, err)
+	require.Equal(t, "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5", hex.EncodeToString(wrapped))
+
+	unwrapped, err := keyUnwrap(kek, wrapped)
+	require.Nil(t, err)
+	require.Equal(t, key, unwrapped)
+
+	kek[0] = 1
+	_, err = keyUnwrap(kek, wrapped)
+	require.ErrorIs(t, err, ErrPassphrase)
+}
+
+func TestStreamID(t *testing.T) {
+	hs := &handshake{typ: hsTypeConclusion, streamID: "#!::r=camera1,m=publish"}
+	b := hs.marshal()
+	require.Equal(t, "::!#", string(b[hsSize+4:hsSize+8]))
+
+	hs, err := parseHandshake(b)
+	require.Nil(t, err)
+	require.Equal(t, "#!::r=camera1,m=publish", hs.streamID)
+}
+
+func TestDial(t *testing.T) {
+	const passphrase = "0123456789"
+
+	ln, err := Listen("127.0.0.1:0", Config{
+		Passphrase: passphrase,
+		Validate: func(streamID string, _ net.Addr) RejectReason {
+			if streamID != "camera1" {
+				return RejectNotFound
+			}
+			return 0
+		},
+	})
+	require.Nil(t, err)
+	defer ln.Close()
+
+	// proxy loses every 7th data packet on first transmission
+	proxy := lossyProxy(t, ln.Addr().String(), 7)
+
+	_, err = Dial("srt://" + proxy + "?streamid=camera2&passphrase=" + passphrase)
+	require.EqualError(t, err, "srt: connection rejected: 1404")
+
+	_, err = Dial("srt://" + proxy + "?streamid=camera1&passphrase=9876543210")
+	require.EqualError(t, err, "srt: connection rejected: 10")
+
+	client, err := Dial("srt://" + proxy + "?streamid=camera1&latency=200&passphrase=" + passphrase)
+	require.Nil(t, err)
+	defer client.Close()
+
+	server, err := ln.Accept()
+	require.Nil(t, err)
+	require.Equal(t, "camera1", server.StreamID)
+	require.Equal(t, 200*time.Millisecond, server.Latency())
+
+	go func() {
+		b := make([]byte, PayloadSize)
+		for i := 0; i < 100; i++ {
+			b[0] = byte(i)
+			_, _ = client.Write(b)
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond) // wait retransmits
+		_ = client.Close()
+	}()
+
+	b := make([]byte, PayloadSize)
+	for i := 0; i < 100; i++ {
+		n, err := io.ReadFull(server, b)
+		require.Nil(t, err)
+		require.Equal(t, PayloadSize, n)
+		require.Equal(t, byte(i), b[0])
+	}
+
+	_, err = server.Read(b)
+	require.Equal(t, io.EOF, err)
+}
+
+func lossyProxy(t *testing.T, address string, lossEvery int) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = pc.Close() })
+
+	server, err := net.ResolveUDPAddr("udp", address)
+	require.Nil(t, err)
+
+	go func() {
+		var client net.Addr
+		var count int
+
+		b := make([]byte, mtuSize)
+		for {
+			n, addr, err := pc.ReadFrom(b)
+			if err != nil {
+				return
+			}
+
+			if addr.String() == server.String() {
+				_, _ = pc.WriteTo(b[:n], client)
+				continue
+			}
+
+			client = addr
+
+			// data packet without retransmitted flag
+			if b[0]&0x80 == 0 && b[4]&(flagRexmit>>24) == 0 {
+				if count++; count%lossEvery == 0 {
+					continue
+				}
+			}
+
+			_, _ = pc.WriteTo(b[:n], server)
+		}
+	}()
+
+	return pc.LocalAddr().String()
+}
+
+func TestHandleData(t *testing.T) {
+	c := newConn(nil, nil, 1, 2, 100)
+	now := time.Now()
+
+	// plaintext packet on the encrypted connection
+	c.crypto = &crypto{}
+	c.handleData(&packet{seq: 100, payload: []byte{1}}, now)
+	require.Len(t, c.recvQueue, 0)
+
+	c.crypto = nil
+	for i := 0; i < recvQueueSize+10; i++ {
+		c.handleData(&packet{seq: uint32(100 + i), payload: []byte{byte(i)}}, now)
+	}
+
+	// slow reader loses old payloads
+	require.Len(t, c.recvQueue, recvQueueSize)
+	require.Equal(t, []byte{10}, c.recvQueue[0])
+}
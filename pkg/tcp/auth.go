@@ -85,7 +85,8 @@ func (a *Auth) Write(req *Request) {
 	}
 }
 
-func (a *Auth) Validate(req *Request) (valid, empty bool) {
+// Validate - check Basic or Digest credentials of the server request, realm from the server challenge
+func (a *Auth) Validate(req *Request, realm string) (valid, empty bool) {
 	if a == nil {
 		return true, true
 	}
@@ -95,6 +96,10 @@ func (a *Auth) Validate(req *Request) (valid, empty bool) {
 		return false, true
 	}
 
+	if strings.HasPrefix(header, "Digest ") {
+		return ValidateDigest(req, realm, a.user, a.pass), false
+	}
+
 	if a.Method == AuthUnknown {
 		a.Method = AuthBasic
 		a.header = "Basic " + B64(a.user, a.pass)
@@ -0,0 +1,119 @@
+package tcp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NonceTTL - lifetime of the server Digest nonce, client should repeat request
+// with new nonce after stale=TRUE response
+var NonceTTL = time.Minute
+
+// nonceKey - secret for stateless nonce signature, nonce is valid until restart
+var nonceKey = func() []byte {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// newNonce - hex unix time and its signature
+func newNonce(now time.Time) string {
+	ts := strconv.FormatInt(now.Unix(), 16)
+	return ts + signNonce(ts)
+}
+
+func signNonce(ts string) string {
+	h := hmac.New(sha256.New, nonceKey)
+	h.Write([]byte(ts))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// checkNonce - nonce from this server and not expired
+func checkNonce(nonce string, now time.Time) (valid, stale bool) {
+	const sigSize = 32 // hex of 16 bytes
+	if len(nonce) <= sigSize {
+		return false, false
+	}
+
+	ts := nonce[:len(nonce)-sigSize]
+	if !hmac.Equal([]byte(nonce[len(ts):]), []byte(signNonce(ts))) {
+		return false, false
+	}
+
+	sec, err := strconv.ParseInt(ts, 16, 64)
+	if err != nil {
+		return false, false
+	}
+
+	if now.Sub(time.Unix(sec, 0)) > NonceTTL {
+		return false, true
+	}
+
+	return true, false
+}
+
+// DigestChallenge - WWW-Authenticate header for the server response
+func DigestChallenge(realm string, stale bool) string {
+	s := fmt.Sprintf(`Digest realm="%s", nonce="%s", algorithm="MD5"`, realm, newNonce(time.Now()))
+	if stale {
+		s += ", stale=TRUE"
+	}
+	return s
+}
+
+// DigestUsername - username from Digest Authorization header
+func DigestUsername(header string) string {
+	if !strings.HasPrefix(header, "Digest ") {
+		return ""
+	}
+	return Between(header, `username="`, `"`)
+}
+
+// DigestStale - Authorization header with valid but expired nonce
+func DigestStale(header string) bool {
+	if !strings.HasPrefix(header, "Digest ") {
+		return false
+	}
+	_, stale := checkNonce(Between(header, `nonce="`, `"`), time.Now())
+	return stale
+}
+
+// ValidateDigest - check Digest Authorization header of the server request (RFC 2069
+// without qop), nonce should be from this server, realm should be from the server
+// challenge and uri should be for the request path
+func ValidateDigest(req *Request, realm, username, password string) bool {
+	header := req.Header.Get("Authorization")
+	if DigestUsername(header) != username {
+		return false
+	}
+
+	if Between(header, `realm="`, `"`) != realm {
+		return false
+	}
+
+	nonce := Between(header, `nonce="`, `"`)
+	if valid, _ := checkNonce(nonce, time.Now()); !valid {
+		return false
+	}
+
+	uri := Between(header, `uri="`, `"`)
+	if u, err := url.Parse(uri); err != nil || u.Path != req.URL.Path {
+		return false
+	}
+
+	h1 := HexMD5(username, realm, password)
+	h2 := HexMD5(req.Method, uri)
+	response := HexMD5(h1, nonce, h2)
+
+	return subtle.ConstantTimeCompare([]byte(response), []byte(Between(header, `response="`, `"`))) == 1
+}
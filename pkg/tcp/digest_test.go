@@ -0,0 +1,55 @@
+package tcp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	// client reads server challenge
+	client := NewAuth(url.UserPassword("admin", "pass"))
+	res := &Response{Header: map[string][]string{
+		"Www-Authenticate": {DigestChallenge("go2rtc", false), `Basic realm="go2rtc"`},
+	}}
+	require.True(t, client.Read(res))
+	require.Equal(t, AuthDigest, client.Method)
+
+	u, _ := url.Parse("rtsp://192.168.1.123:8554/camera1")
+	req := &Request{Method: "DESCRIBE", URL: u, Header: map[string][]string{}}
+	client.Write(req)
+
+	header := req.Header.Get("Authorization")
+	require.Equal(t, "admin", DigestUsername(header))
+	require.False(t, DigestStale(header))
+
+	require.True(t, ValidateDigest(req, "go2rtc", "admin", "pass"))
+	require.False(t, ValidateDigest(req, "go2rtc", "admin", "wrong"))
+	require.False(t, ValidateDigest(req, "go2rtc", "user", "pass"))
+
+	// response for the realm from the client, not from the server
+	require.False(t, ValidateDigest(req, "other", "admin", "pass"))
+
+	// same response for another stream
+	req.URL, _ = url.Parse("rtsp://192.168.1.123:8554/camera2")
+	require.False(t, ValidateDigest(req, "go2rtc", "admin", "pass"))
+
+	// server validation with Auth
+	server := NewAuth(url.UserPassword("admin", "pass"))
+	req.URL = u
+	valid, _ := server.Validate(req, "go2rtc")
+	require.True(t, valid)
+
+	// expired nonce
+	nonce := Between(header, `nonce="`, `"`)
+	valid, stale := checkNonce(nonce, time.Now().Add(NonceTTL+time.Second))
+	require.False(t, valid)
+	require.True(t, stale)
+
+	// fake nonce
+	valid, stale = checkNonce("1"+nonce, time.Now())
+	require.False(t, valid)
+	require.False(t, stale)
+}
@@ -26,7 +26,9 @@ type Response struct {
 func (r Response) String() string {
 	s := r.Proto + " " + r.Status + EndLine
 	for k, v := range r.Header {
-		s += k + ": " + v[0] + EndLine
+		for _, v := range v {
+			s += k + ": " + v + EndLine
+		}
 	}
 	s += EndLine
 	if r.Body != nil {
@@ -95,7 +97,9 @@ type Request struct {
 func (r *Request) String() string {
 	s := r.Method + " " + r.URL.String() + " " + r.Proto + EndLine
 	for k, v := range r.Header {
-		s += k + ": " + v[0] + EndLine
+		for _, v := range v {
+			s += k + ": " + v + EndLine
+		}
 	}
 	s += EndLine
 	if r.Body != nil {
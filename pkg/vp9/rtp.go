@@ -0,0 +1,206 @@
+package vp9
+
+import (
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc9628
+
+const (
+	flagI = 0x80 // picture ID present
+	flagP = 0x40 // inter-picture predicted frame
+	flagL = 0x20 // layer indices present
+	flagF = 0x10 // flexible mode
+	flagB = 0x08 // start of a frame
+	flagE = 0x04 // end of a frame
+	flagV = 0x02 // scalability structure present
+)
+
+// RTPDepay - RTP payload to the frame, frames of the picture (spatial layers)
+// are joined to the superframe
+func RTPDepay(handler core.HandlerFunc) core.HandlerFunc {
+	buf := make([]byte, 0, 512*1024) // 512K
+	frameStart := -1                 // start of the frame in progress
+	var sizes []int                  // frames of the picture
+
+	return func(packet *rtp.Packet) {
+		i := descriptorSize(packet.Payload)
+		if i == 0 {
+			return
+		}
+
+		flags := packet.Payload[0]
+
+		if flags&flagB != 0 {
+			if frameStart >= 0 {
+				buf = buf[:frameStart] // lost end of the previous frame
+			}
+			frameStart = len(buf)
+		} else if frameStart < 0 {
+			return // lost start of the frame
+		}
+
+		buf = append(buf, packet.Payload[i:]...)
+
+		if flags&flagE != 0 {
+			sizes = append(sizes, len(buf)-frameStart)
+			frameStart = -1
+		}
+
+		if !packet.Marker {
+			return
+		}
+
+		if frameStart >= 0 {
+			buf = buf[:frameStart]
+			frameStart = -1
+		}
+
+		if len(sizes) > 1 {
+			buf = AppendSuperframeIndex(buf, sizes)
+		}
+
+		if len(buf) > 0 {
+			clone := *packet
+			clone.Payload = buf
+			handler(&clone)
+		}
+
+		buf = buf[:0]
+		sizes = sizes[:0]
+	}
+}
+
+// descriptorSize - VP9 payload descriptor size, zero for wrong data
+func descriptorSize(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+
+	flags := b[0]
+	i := 1
+
+	if flags&flagI != 0 {
+		if b[i]&0x80 != 0 {
+			i += 2 // 15 bit picture ID
+		} else {
+			i++
+		}
+	}
+
+	if flags&flagL != 0 {
+		i++
+		if flags&flagF == 0 {
+			i++ // TL0PICIDX
+		}
+	}
+
+	if flags&flagF != 0 && flags&flagP != 0 {
+		// up to 3 reference indices
+		for j := 0; j < 3; j++ {
+			if i >= len(b) {
+				return 0
+			}
+			more := b[i]&1 != 0
+			i++
+			if !more {
+				break
+			}
+		}
+	}
+
+	if flags&flagV != 0 {
+		if i >= len(b) {
+			return 0
+		}
+
+		ss := b[i]
+		i++
+
+		if ss&0x10 != 0 {
+			i += 4 * (int(ss>>5) + 1) // width and height for each spatial layer
+		}
+
+		if ss&0x08 != 0 {
+			if i >= len(b) {
+				return 0
+			}
+			groups := int(b[i])
+			i++
+
+			for j := 0; j < groups; j++ {
+				if i >= len(b) {
+					return 0
+				}
+				i += 1 + int(b[i]>>2)&0b11 // reference indices
+			}
+		}
+	}
+
+	if i >= len(b) {
+		return 0
+	}
+
+	return i
+}
+
+// RTPPay - frame (or superframe) to RTP packets, with 15 bit picture ID
+// in non-flexible mode without layers
+func RTPPay(mtu uint16, handler core.HandlerFunc) core.HandlerFunc {
+	if mtu == 0 {
+		mtu = 1472
+	}
+
+	sequencer := rtp.NewRandomSequencer()
+	size := int(mtu) - 12 - 3 // rtp.Header and descriptor size
+
+	var pictureID uint16
+
+	return func(packet *rtp.Packet) {
+		frames := SplitFrames(packet.Payload)
+
+		for n, frame := range frames {
+			flags := byte(flagI | flagB)
+			if !IsKeyframe(frame) {
+				flags |= flagP
+			}
+
+			for len(frame) > 0 {
+				b := make([]byte, 3, 3+min(size, len(frame)))
+				b[1] = 0x80 | byte(pictureID>>8)
+				b[2] = byte(pictureID)
+
+				if len(frame) > size {
+					b = append(b, frame[:size]...)
+					frame = frame[size:]
+				} else {
+					b = append(b, frame...)
+					frame = nil
+					flags |= flagE
+				}
+
+				b[0] = flags
+				flags &^= flagB
+
+				clone := rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						Marker:         frame == nil && n == len(frames)-1,
+						SequenceNumber: sequencer.NextSequenceNumber(),
+						Timestamp:      packet.Timestamp,
+					},
+					Payload: b,
+				}
+				handler(&clone)
+			}
+		}
+
+		pictureID = (pictureID + 1) & 0x7FFF
+	}
+}
+
+// IsKeyframeRTP - start of the frame without inter-picture prediction
+func IsKeyframeRTP(payload []byte) bool {
+	return len(payload) > 0 && payload[0]&flagB != 0 && payload[0]&flagP == 0
+}
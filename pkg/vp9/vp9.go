@@ -0,0 +1,261 @@
+// Package vp9 - VP9 frames (and superframes) related functions
+package vp9
+
+import (
+	"fmt"
+
+	"github.com/AlexxIT/go2rtc/pkg/bits"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// https://storage.googleapis.com/downloads.webmproject.org/docs/vp9/vp9-bitstream-specification-v0.7-20170222-draft.pdf
+
+const syncCode = 0x498342
+
+const colorSpaceRGB = 7
+
+type Header struct {
+	Profile      byte
+	BitDepth     byte
+	ColorSpace   byte
+	ColorRange   byte
+	SubsamplingX byte
+	SubsamplingY byte
+	Width        uint16
+	Height       uint16
+}
+
+// IsKeyframe - first frame of the frame (superframe) is the key frame
+func IsKeyframe(data []byte) bool {
+	r := bits.NewReader(data)
+	if r.ReadBits8(2) != 2 {
+		return false // frame_marker
+	}
+	if profile := r.ReadBit() | r.ReadBit()<<1; profile == 3 {
+		_ = r.ReadBit() // reserved_zero
+	}
+	if r.ReadBit() != 0 {
+		return false // show_existing_frame
+	}
+	return r.ReadBit() == 0 && !r.EOF // frame_type
+}
+
+// DecodeHeader - uncompressed header of the key frame, nil for other frames
+func DecodeHeader(data []byte) *Header {
+	if !IsKeyframe(data) {
+		return nil
+	}
+
+	r := bits.NewReader(data)
+	_ = r.ReadBits8(2) // frame_marker
+
+	h := &Header{BitDepth: 8}
+	h.Profile = r.ReadBit() | r.ReadBit()<<1
+	if h.Profile == 3 {
+		_ = r.ReadBit() // reserved_zero
+	}
+
+	_ = r.ReadBits8(4) // show_existing_frame, frame_type, show_frame, error_resilient_mode
+
+	if r.ReadBits(24) != syncCode {
+		return nil
+	}
+
+	// color_config
+	if h.Profile >= 2 {
+		if r.ReadBit() != 0 {
+			h.BitDepth = 12
+		} else {
+			h.BitDepth = 10
+		}
+	}
+
+	h.ColorSpace = r.ReadBits8(3)
+	if h.ColorSpace != colorSpaceRGB {
+		h.ColorRange = r.ReadBit()
+		if h.Profile == 1 || h.Profile == 3 {
+			h.SubsamplingX = r.ReadBit()
+			h.SubsamplingY = r.ReadBit()
+			_ = r.ReadBit() // reserved_zero
+		} else {
+			h.SubsamplingX, h.SubsamplingY = 1, 1
+		}
+	} else {
+		h.ColorRange = 1
+		if h.Profile == 1 || h.Profile == 3 {
+			_ = r.ReadBit() // reserved_zero
+		}
+	}
+
+	// frame_size
+	h.Width = r.ReadBits16(16) + 1
+	h.Height = r.ReadBits16(16) + 1
+
+	if r.EOF {
+		return nil
+	}
+
+	return h
+}
+
+// SplitFrames - frames from the superframe or the frame itself
+func SplitFrames(data []byte) [][]byte {
+	n := len(data)
+	if n == 0 {
+		return nil
+	}
+
+	marker := data[n-1]
+	if marker&0b1110_0000 != 0b1100_0000 {
+		return [][]byte{data}
+	}
+
+	frames := int(marker&0b111) + 1
+	mag := int(marker>>3&0b11) + 1
+
+	size := 2 + mag*frames
+	if n < size || data[n-size] != marker {
+		return [][]byte{data}
+	}
+
+	index := data[n-size+1:]
+	data = data[:n-size]
+
+	var res [][]byte
+	for i := 0; i < frames; i++ {
+		var frameSize int
+		for j := 0; j < mag; j++ {
+			frameSize |= int(index[i*mag+j]) << (8 * j)
+		}
+		if frameSize > len(data) {
+			return nil
+		}
+		res = append(res, data[:frameSize])
+		data = data[frameSize:]
+	}
+	return res
+}
+
+// AppendSuperframeIndex - join frames of the picture (spatial layers) to the superframe
+func AppendSuperframeIndex(data []byte, sizes []int) []byte {
+	mag := 1
+	for _, size := range sizes {
+		for mag < 4 && size >= 1<<(8*mag) {
+			mag++
+		}
+	}
+
+	marker := 0b1100_0000 | byte(mag-1)<<3 | byte(len(sizes)-1)
+
+	data = append(data, marker)
+	for _, size := range sizes {
+		for j := 0; j < mag; j++ {
+			data = append(data, byte(size>>(8*j)))
+		}
+	}
+	return append(data, marker)
+}
+
+// EncodeConfig - VPCodecConfigurationRecord (vpcC) version 1, with version and flags
+func EncodeConfig(h *Header) []byte {
+	chroma := byte(1) // 4:2:0 colocated with luma
+	switch {
+	case h.SubsamplingX == 0:
+		chroma = 3 // 4:4:4
+	case h.SubsamplingY == 0:
+		chroma = 2 // 4:2:2
+	}
+
+	return []byte{
+		1, 0, 0, 0, // version and flags
+		h.Profile,
+		level(h.Width, h.Height),
+		h.BitDepth<<4 | chroma<<1 | h.ColorRange,
+		2, 2, // colour primaries and transfer characteristics (unspecified)
+		matrix(h.ColorSpace),
+		0, 0, // codec initialization data size
+	}
+}
+
+// FmtpToConfig - vpcC from SDP fmtp, unknown values are default for the profile
+func FmtpToConfig(fmtp string) []byte {
+	return EncodeConfig(fmtpHeader(fmtp))
+}
+
+// GetProfileLevelID - codec string for MIME type (PP.LL.DD) from SDP fmtp
+func GetProfileLevelID(fmtp string) string {
+	h := fmtpHeader(fmtp)
+	return fmt.Sprintf("%02d.%02d.%02d", h.Profile, level(h.Width, h.Height), h.BitDepth)
+}
+
+func fmtpHeader(fmtp string) *Header {
+	h := &Header{BitDepth: 8, SubsamplingX: 1, SubsamplingY: 1}
+
+	if s := core.Between(fmtp, "profile-id=", ";"); s != "" {
+		h.Profile = byte(core.Atoi(s)) & 0b11
+	}
+	if h.Profile >= 2 {
+		h.BitDepth = 10
+	}
+	if h.Profile == 1 || h.Profile == 3 {
+		h.SubsamplingX, h.SubsamplingY = 0, 0
+	}
+
+	return h
+}
+
+// level - by the picture size, level 4.1 (1080p) for unknown size
+func level(width, height uint16) byte {
+	size := int(width) * int(height)
+	switch {
+	case size == 0:
+		return 41
+	case size <= 36864:
+		return 10
+	case size <= 73728:
+		return 11
+	case size <= 122880:
+		return 20
+	case size <= 245760:
+		return 21
+	case size <= 552960:
+		return 30
+	case size <= 983040:
+		return 31
+	case size <= 2228224:
+		return 41
+	case size <= 8912896:
+		return 51
+	}
+	return 61
+}
+
+// matrix - matrix coefficients (ISO/IEC 23091-4) for the color space
+func matrix(colorSpace byte) byte {
+	switch colorSpace {
+	case 1, 3: // BT.601, SMPTE-170
+		return 6
+	case 2: // BT.709
+		return 1
+	case 4: // SMPTE-240
+		return 7
+	case 5: // BT.2020
+		return 9
+	case colorSpaceRGB:
+		return 0
+	}
+	return 2 // unspecified
+}
+
+// ConfigToCodec - codec from vpcC
+func ConfigToCodec(conf []byte) *core.Codec {
+	codec := &core.Codec{
+		Name:        core.CodecVP9,
+		ClockRate:   90000,
+		PayloadType: core.PayloadTypeRAW,
+	}
+	if len(conf) >= 5 {
+		codec.FmtpLine = fmt.Sprintf("profile-id=%d", conf[4])
+	}
+	return codec
+}
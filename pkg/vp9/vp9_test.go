@@ -0,0 +1,70 @@
+package vp9
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/bits"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func keyframe(width, height uint16) []byte {
+	w := bits.NewWriter(nil)
+	w.WriteBits8(0b10_00_0010, 8) // frame_marker, profile 0, key frame, show_frame
+	w.WriteBits(syncCode, 24)
+	w.WriteBits8(2, 3) // BT.709
+	w.WriteBit(0)      // color_range
+	w.WriteBits16(width-1, 16)
+	w.WriteBits16(height-1, 16)
+	w.WriteBits8(0, 8)
+	return w.Bytes()
+}
+
+func TestDecodeHeader(t *testing.T) {
+	frame := keyframe(1280, 720)
+	require.True(t, IsKeyframe(frame))
+	require.False(t, IsKeyframe([]byte{0b10_00_0110})) // inter frame
+
+	h := DecodeHeader(frame)
+	require.NotNil(t, h)
+	require.Equal(t, uint16(1280), h.Width)
+	require.Equal(t, uint16(720), h.Height)
+
+	conf := EncodeConfig(h)
+	require.Equal(t, "01000000001f820202010000", hex.EncodeToString(conf))
+
+	require.Equal(t, "profile-id=0", ConfigToCodec(conf).FmtpLine)
+	require.Equal(t, "00.41.08", GetProfileLevelID(""))
+}
+
+func TestRTP(t *testing.T) {
+	// two spatial layers in the superframe
+	frame1 := keyframe(640, 360)
+	frame2 := append([]byte{0b10_00_0110}, make([]byte, 2000)...)
+	data := AppendSuperframeIndex(append(frame1, frame2...), []int{len(frame1), len(frame2)})
+
+	frames := SplitFrames(data)
+	require.Equal(t, [][]byte{frame1, frame2}, frames)
+
+	var packets []*rtp.Packet
+	pay := RTPPay(1200, func(packet *rtp.Packet) {
+		packets = append(packets, packet)
+	})
+	pay(&rtp.Packet{Payload: data})
+
+	require.Len(t, packets, 3)
+	require.True(t, IsKeyframeRTP(packets[0].Payload))
+	require.False(t, IsKeyframeRTP(packets[1].Payload))
+	require.True(t, packets[2].Marker)
+
+	var result []byte
+	depay := RTPDepay(func(packet *rtp.Packet) {
+		result = append([]byte{}, packet.Payload...)
+	})
+	for _, packet := range packets {
+		depay(packet)
+	}
+
+	require.Equal(t, data, result)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/AlexxIT/go2rtc/pkg/xnet"
 	"github.com/pion/ice/v4"
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/nack"
 	"github.com/pion/webrtc/v4"
 )
 
@@ -38,7 +39,10 @@ func NewServerAPI(network, address string, filters *Filters) (*webrtc.API, error
 	}
 
 	i := &interceptor.Registry{}
-	if err := webrtc.RegisterDefaultInterceptors(m, i); err != nil {
+	if err := registerBWE(m, i); err != nil {
+		return nil, err
+	}
+	if err := registerInterceptors(m, i); err != nil {
 		return nil, err
 	}
 
@@ -145,6 +149,29 @@ func NewServerAPI(network, address string, filters *Filters) (*webrtc.API, error
 	), nil
 }
 
+// registerInterceptors - same as webrtc.RegisterDefaultInterceptors, but without NACK responder,
+// because retransmissions are sent from the Track history
+func registerInterceptors(m *webrtc.MediaEngine, i *interceptor.Registry) error {
+	generator, err := nack.NewGeneratorInterceptor()
+	if err != nil {
+		return err
+	}
+
+	m.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack"}, webrtc.RTPCodecTypeVideo)
+	m.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack", Parameter: "pli"}, webrtc.RTPCodecTypeVideo)
+	i.Add(generator)
+
+	if err = webrtc.ConfigureRTCPReports(i); err != nil {
+		return err
+	}
+
+	if err = webrtc.ConfigureSimulcastExtensionHeaders(m); err != nil {
+		return err
+	}
+
+	return webrtc.ConfigureTWCCSender(m, i)
+}
+
 func RegisterDefaultCodecs(m *webrtc.MediaEngine) error {
 	for _, codec := range []webrtc.RTPCodecParameters{
 		{
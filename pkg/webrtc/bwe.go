@@ -0,0 +1,117 @@
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+)
+
+// Bandwidth estimation to the remote peer: send-side (TWCC feedback) with the
+// Google Congestion Control and receive-side (REMB) from the remote peer.
+
+const bweInitialBitrate = 10_000_000 // don't limit the peer until the first feedback
+
+var (
+	pcMu       sync.Mutex // serialize PeerConnection creation for estimator binding
+	bweMu      sync.Mutex
+	bweLast    cc.BandwidthEstimator
+	estimators sync.Map // *webrtc.PeerConnection => cc.BandwidthEstimator
+)
+
+func registerBWE(m *webrtc.MediaEngine, i *interceptor.Registry) error {
+	f, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+		// packets are sent as is, the estimate is used only for layer selection
+		return gcc.NewSendSideBWE(
+			gcc.SendSideBWEPacer(gcc.NewNoOpPacer()),
+			gcc.SendSideBWEInitialBitrate(bweInitialBitrate),
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	// called synchronously from the api.NewPeerConnection
+	f.OnNewPeerConnection(func(_ string, estimator cc.BandwidthEstimator) {
+		bweMu.Lock()
+		bweLast = estimator
+		bweMu.Unlock()
+	})
+
+	i.Add(f)
+
+	// TWCC sequence numbers for the outgoing packets, so the remote peer can send feedback
+	return webrtc.ConfigureTWCCHeaderExtensionSender(m, i)
+}
+
+func takeEstimator() cc.BandwidthEstimator {
+	bweMu.Lock()
+	estimator := bweLast
+	bweLast = nil
+	bweMu.Unlock()
+	return estimator
+}
+
+// NewPeerConnection - create PeerConnection and bind its bandwidth estimator for the NewConn
+func NewPeerConnection(api *webrtc.API, conf webrtc.Configuration) (*webrtc.PeerConnection, error) {
+	pcMu.Lock()
+	defer pcMu.Unlock()
+
+	_ = takeEstimator() // skip estimator from PeerConnection created without this func
+
+	pc, err := api.NewPeerConnection(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if estimator := takeEstimator(); estimator != nil {
+		estimators.Store(pc, estimator)
+	}
+
+	return pc, nil
+}
+
+func (c *Conn) bindEstimator() {
+	v, ok := estimators.LoadAndDelete(c.pc)
+	if !ok {
+		return
+	}
+	v.(cc.BandwidthEstimator).OnTargetBitrateChange(func(bitrate int) {
+		c.twcc.Store(int64(bitrate))
+	})
+}
+
+// readRTCP - RTCP from the remote peer for the sender, also necessary for the
+// interceptors (TWCC estimator, sender reports)
+func (c *Conn) readRTCP(mid string, sender *webrtc.RTPSender) {
+	for {
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		for _, packet := range packets {
+			switch packet := packet.(type) {
+			case *rtcp.ReceiverEstimatedMaximumBitrate:
+				c.remb.Store(int64(packet.Bitrate))
+			case *rtcp.TransportLayerNack:
+				if track, ok := sender.Track().(*Track); ok {
+					track.retransmit(packet)
+				}
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				c.requestSourceKeyframe(mid)
+			}
+		}
+	}
+}
+
+// Bandwidth - estimated bitrate to the remote peer in bits per second, zero if unknown
+func (c *Conn) Bandwidth() int {
+	twcc, remb := int(c.twcc.Load()), int(c.remb.Load())
+	if twcc == 0 || (remb != 0 && remb < twcc) {
+		return remb
+	}
+	return twcc
+}
@@ -0,0 +1,70 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+const (
+	congestionNone  = iota
+	congestionLight // drop non-reference frames
+	congestionHeavy // drop frames up to the next keyframe
+)
+
+// congestion - compare the bandwidth estimate to the remote peer with the send bitrate
+func (c *Conn) congestion() int {
+	estimate := c.Bandwidth()
+	if estimate == 0 {
+		return congestionNone
+	}
+
+	c.mu.Lock()
+	if now := time.Now(); now.Sub(c.rateTime) >= time.Second {
+		if !c.rateTime.IsZero() {
+			c.sendRate = (c.Send - c.rateBytes) * 8 * int(time.Second) / int(now.Sub(c.rateTime))
+		}
+		c.rateTime = now
+		c.rateBytes = c.Send
+	}
+	bitrate := c.sendRate
+	c.mu.Unlock()
+
+	switch {
+	case estimate < bitrate*7/10:
+		return congestionHeavy
+	case estimate < bitrate:
+		return congestionLight
+	}
+	return congestionNone
+}
+
+// dropFrames - drop whole video frames when the link to the remote peer is congested,
+// so the viewer gets lower FPS or short freeze instead of broken frames from random packet loss
+func (c *Conn) dropFrames(sender *core.Sender, isKeyframe, isReference func([]byte) bool, handler core.HandlerFunc) core.HandlerFunc {
+	var dropGOP, requested bool
+
+	return func(packet *rtp.Packet) {
+		level := c.congestion()
+
+		switch {
+		case isKeyframe(packet.Payload):
+			dropGOP, requested = false, false
+		case dropGOP:
+			// ask for the new keyframe when the link is OK again
+			if level == congestionNone && !requested {
+				requested = true
+				sender.RequestKeyframe()
+			}
+			return
+		case level == congestionHeavy:
+			dropGOP = true
+			return
+		case level == congestionLight && !isReference(packet.Payload):
+			return
+		}
+
+		handler(packet)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -13,6 +14,9 @@ import (
 	"github.com/pion/webrtc/v4"
 )
 
+// keyframeInterval - minimal interval between keyframe requests to the remote peer
+const keyframeInterval = 500 * time.Millisecond
+
 type Conn struct {
 	core.Connection
 	core.Listener
@@ -26,7 +30,22 @@ type Conn struct {
 
 	// Pause/resume functionality
 	paused   atomic.Bool
-	ringType atomic.Value // "main" or "sub" for stream quality switching
+	muted    atomic.Bool  // skip media from the remote peer (talkback)
+	ringType atomic.Value // current layer: "main", "sub" or simulcast RID
+
+	// simulcast RIDs for each media ID in the offer order
+	rids   map[string][]string
+	layers []*layer
+	mu     sync.Mutex
+
+	// bandwidth estimate to the remote peer
+	twcc atomic.Int64
+	remb atomic.Int64
+
+	// send bitrate for the congestion control
+	sendRate  int
+	rateBytes int
+	rateTime  time.Time
 
 	// Stream source for motion detection mapping
 	StreamSource string `json:"stream_source,omitempty"`
@@ -51,6 +70,8 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 		pc: pc,
 	}
 
+	c.bindEstimator()
+
 	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
 		// last candidate will be empty
 		if candidate != nil {
@@ -86,14 +107,34 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 	})
 
 	pc.OnTrack(func(remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
-		media, codec := c.getMediaCodec(remote)
+		media, codec := c.getMediaCodec(remote, receiver)
 		if media == nil {
 			return
 		}
 
-		track, err := c.GetTrack(media, codec)
-		if err != nil {
-			return
+		var track *core.Receiver
+		if rid := remote.RID(); rid != "" && rid != c.defaultRID(media.ID) {
+			// additional simulcast encodings are not the stream tracks
+			track = c.addLayer(rid, media, codec)
+		} else {
+			var err error
+			if track, err = c.GetTrack(media, codec); err != nil {
+				return
+			}
+		}
+
+		pkts := []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}}
+		if remote.Kind() == webrtc.RTPCodecTypeVideo {
+			var last atomic.Int64
+			track.OnKeyframeRequest(func() {
+				// many viewers may ask for the keyframe at the same time
+				now := time.Now().UnixNano()
+				if now-last.Load() < int64(keyframeInterval) {
+					return
+				}
+				last.Store(now)
+				_ = pc.WriteRTCP(pkts)
+			})
 		}
 
 		switch c.Mode {
@@ -106,7 +147,6 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 
 		if c.Mode == core.ModePassiveProducer && remote.Kind() == webrtc.RTPCodecTypeVideo {
 			go func() {
-				pkts := []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}}
 				for range time.NewTicker(time.Second * 2).C {
 					if err := pc.WriteRTCP(pkts); err != nil {
 						return
@@ -124,6 +164,10 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 
 			c.Recv += n
 
+			if c.muted.Load() {
+				continue
+			}
+
 			packet := &rtp.Packet{}
 			if err := packet.Unmarshal(b[:n]); err != nil {
 				return
@@ -198,10 +242,10 @@ func (c *Conn) getSenderTrack(mid string) *Track {
 	return nil
 }
 
-func (c *Conn) getMediaCodec(remote *webrtc.TrackRemote) (*core.Media, *core.Codec) {
+func (c *Conn) getMediaCodec(remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) (*core.Media, *core.Codec) {
 	for _, tr := range c.pc.GetTransceivers() {
-		// search Transeiver for this TrackRemote
-		if tr.Receiver() == nil || tr.Receiver().Track() != remote {
+		// search Transeiver for this TrackRemote (receiver has one track for each simulcast RID)
+		if tr.Receiver() == nil || tr.Receiver() != receiver {
 			continue
 		}
 
@@ -254,7 +298,30 @@ func (c *Conn) IsPaused() bool {
 	return c.paused.Load()
 }
 
-// SetRingType sets the stream quality type
+// SetTalkback enables or disables media from the remote peer to the stream (backchannel)
+func (c *Conn) SetTalkback(enabled bool) {
+	c.muted.Store(!enabled)
+}
+
+// Talkback returns the current talkback state
+func (c *Conn) Talkback() bool {
+	return !c.muted.Load()
+}
+
+// OnVideoFrame sets handler for the RTP timestamp of each video frame sent to the remote peer
+func (c *Conn) OnVideoFrame(handler func(ts uint32)) {
+	for _, tr := range c.pc.GetTransceivers() {
+		if sender := tr.Sender(); sender != nil {
+			if track, ok := sender.Track().(*Track); ok && track.kind == "video" {
+				track.mu.Lock()
+				track.onFrame = handler
+				track.mu.Unlock()
+			}
+		}
+	}
+}
+
+// SetRingType sets the stream quality type (current layer)
 func (c *Conn) SetRingType(ringType string) {
 	c.ringType.Store(ringType)
 }
@@ -269,8 +336,22 @@ func (c *Conn) GetRingType() string {
 	return "main" // default
 }
 
+// requestSourceKeyframe - pass viewer PLI/FIR to the source of the media
+func (c *Conn) requestSourceKeyframe(mid string) {
+	for _, sender := range c.Senders {
+		if sender.Media.ID == mid {
+			sender.RequestKeyframe()
+		}
+	}
+}
+
 // requestKeyframe sends a PLI (Picture Loss Indication) to request a keyframe
 func (c *Conn) requestKeyframe() {
+	// the viewer needs keyframe from the stream source
+	for _, sender := range c.Senders {
+		sender.RequestKeyframe()
+	}
+
 	for _, receiver := range c.pc.GetReceivers() {
 		if receiver.Track() == nil {
 			continue
@@ -3,10 +3,12 @@ package webrtc
 import (
 	"errors"
 
+	"github.com/AlexxIT/go2rtc/pkg/av1"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/AlexxIT/go2rtc/pkg/h264"
 	"github.com/AlexxIT/go2rtc/pkg/h265"
 	"github.com/AlexxIT/go2rtc/pkg/pcm"
+	"github.com/AlexxIT/go2rtc/pkg/vp9"
 	"github.com/pion/rtp"
 )
 
@@ -57,6 +59,7 @@ func (c *Conn) AddTrack(media *core.Media, codec *core.Codec, track *core.Receiv
 	switch track.Codec.Name {
 	case core.CodecH264:
 		sender.Handler = h264.RTPPay(1200, sender.Handler)
+		sender.Handler = c.dropFrames(sender, h264.IsKeyframe, h264.IsReference, sender.Handler)
 		if track.Codec.IsRTP() {
 			sender.Handler = h264.RTPDepay(track.Codec, sender.Handler)
 		} else {
@@ -65,12 +68,23 @@ func (c *Conn) AddTrack(media *core.Media, codec *core.Codec, track *core.Receiv
 
 	case core.CodecH265:
 		sender.Handler = h265.RTPPay(1200, sender.Handler)
+		sender.Handler = c.dropFrames(sender, h265.IsKeyframe, h265.IsReference, sender.Handler)
 		if track.Codec.IsRTP() {
 			sender.Handler = h265.RTPDepay(track.Codec, sender.Handler)
 		} else {
 			sender.Handler = h265.RepairAVCC(track.Codec, sender.Handler)
 		}
 
+	case core.CodecAV1:
+		if !track.Codec.IsRTP() {
+			sender.Handler = av1.RTPPay(1200, sender.Handler)
+		}
+
+	case core.CodecVP9:
+		if !track.Codec.IsRTP() {
+			sender.Handler = vp9.RTPPay(1200, sender.Handler)
+		}
+
 	case core.CodecPCMA, core.CodecPCMU, core.CodecPCM, core.CodecPCML:
 		// Fix audio quality https://github.com/AlexxIT/WebRTC/issues/500
 		// should be before ResampleToG711, because it will be called last
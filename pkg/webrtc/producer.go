@@ -47,3 +47,61 @@ func (c *Conn) Start() error {
 	c.closed.Wait()
 	return nil
 }
+
+// layer - additional simulcast encoding from the remote peer
+type layer struct {
+	rid   string
+	media *core.Media
+	track *core.Receiver
+}
+
+// defaultRID - first simulcast encoding from the offer is the stream track,
+// so the publisher should list the best quality first
+func (c *Conn) defaultRID(mid string) string {
+	if rids := c.rids[mid]; len(rids) > 0 {
+		return rids[0]
+	}
+	return ""
+}
+
+func (c *Conn) addLayer(rid string, media *core.Media, codec *core.Codec) *core.Receiver {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := &layer{rid: rid, media: media, track: core.NewReceiver(media, codec)}
+	c.layers = append(c.layers, l)
+	return l.track
+}
+
+// Layers - RIDs of the simulcast encodings from the remote peer
+func (c *Conn) Layers() []string {
+	var rids []string
+	for _, media := range c.Medias {
+		for _, rid := range c.rids[media.ID] {
+			if !core.Contains(rids, rid) {
+				rids = append(rids, rid)
+			}
+		}
+	}
+	return rids
+}
+
+// GetLayer - track of the simulcast encoding with the same codec, nil if no such track yet
+func (c *Conn) GetLayer(rid string, codec *core.Codec) *core.Receiver {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, l := range c.layers {
+		if l.rid == rid && l.track.Codec.Match(codec) {
+			return l.track
+		}
+	}
+
+	for _, track := range c.Receivers {
+		if c.defaultRID(track.Media.ID) == rid && track.Codec.Match(codec) {
+			return track
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package webrtc
 
 import (
+	"strings"
+
 	"github.com/AlexxIT/go2rtc/pkg/core"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v4"
@@ -17,6 +19,7 @@ func (c *Conn) SetOffer(offer string) (err error) {
 	// create transceivers with opposite direction
 	for _, md := range sd.MediaDescriptions {
 		var mid string
+		var rids []string
 		var tr *webrtc.RTPTransceiver
 		for _, attr := range md.Attributes {
 			switch attr.Key {
@@ -34,12 +37,28 @@ func (c *Conn) SetOffer(offer string) (err error) {
 				)
 			case "mid":
 				mid = attr.Value
+			case "rid":
+				// a=rid:<id> send [restrictions]
+				if fields := strings.Fields(attr.Value); len(fields) > 1 && fields[1] == "send" {
+					rids = append(rids, fields[0])
+				}
 			}
 		}
 
 		if mid != "" && tr != nil {
 			_ = tr.SetMid(mid)
 		}
+
+		if len(rids) > 0 {
+			if c.rids == nil {
+				c.rids = map[string][]string{}
+			}
+			c.rids[mid] = rids
+		}
+
+		if tr != nil && tr.Sender() != nil {
+			go c.readRTCP(mid, tr.Sender())
+		}
 	}
 
 	c.Medias = UnmarshalMedias(sd.MediaDescriptions)
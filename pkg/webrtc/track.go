@@ -3,26 +3,42 @@ package webrtc
 import (
 	"sync"
 
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 )
 
+const (
+	maxJumpTS = 1 << 19 // ~6 seconds for 90000 clock rate
+	frameTS   = 3000    // ~1 frame for 90000 clock rate
+
+	historySize = 1024 // sent video packets for NACK retransmissions, ~1 second of 4K video
+)
+
 type Track struct {
 	kind     string
 	id       string
 	streamID string
 	sequence uint16
 	ssrc     uint32
+	lastTS   uint32
+	offsetTS uint32 // keeps timestamps continuous when the source changes
+	history  []*rtp.Packet
+	onFrame  func(ts uint32)
 	writer   webrtc.TrackLocalWriter
 	mu       sync.Mutex
 }
 
 func NewTrack(kind string) *Track {
-	return &Track{
+	t := &Track{
 		kind:     kind,
 		id:       "go2rtc-" + kind,
 		streamID: "go2rtc",
 	}
+	if kind == "video" {
+		t.history = make([]*rtp.Packet, historySize)
+	}
+	return t
 }
 
 func (t *Track) Bind(context webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
@@ -63,6 +79,9 @@ func (t *Track) Kind() webrtc.RTPCodecType {
 }
 
 func (t *Track) WriteRTP(payloadType uint8, packet *rtp.Packet) (err error) {
+	var onFrame func(ts uint32)
+	var ts uint32
+
 	// using mutex because Unbind https://github.com/AlexxIT/go2rtc/issues/994
 	t.mu.Lock()
 
@@ -71,13 +90,59 @@ func (t *Track) WriteRTP(payloadType uint8, packet *rtp.Packet) (err error) {
 		// important to have internal counter if input packets from different sources
 		t.sequence++
 
+		ts = packet.Timestamp + t.offsetTS
+		if t.sequence > 1 {
+			// big jump means new source (layer switch or reconnect), continue from the last timestamp
+			if d := int32(ts - t.lastTS); d > maxJumpTS || d < -maxJumpTS {
+				t.offsetTS += t.lastTS + frameTS - ts
+				ts = t.lastTS + frameTS
+			}
+		}
+		if t.sequence == 1 || ts != t.lastTS {
+			onFrame = t.onFrame
+		}
+		t.lastTS = ts
+
 		header := packet.Header
 		header.SSRC = t.ssrc
 		header.PayloadType = payloadType
 		header.SequenceNumber = t.sequence
+		header.Timestamp = ts
+		// source extensions have IDs from another session, also interceptors add own extensions
+		header.Extension = false
+		header.ExtensionProfile = 0
+		header.Extensions = nil
+
+		if t.history != nil {
+			t.history[t.sequence%historySize] = &rtp.Packet{Header: header, Payload: packet.Payload}
+		}
+
 		_, err = t.writer.WriteRTP(&header, packet.Payload)
 	}
 
 	t.mu.Unlock()
+
+	if onFrame != nil {
+		onFrame(ts)
+	}
 	return
 }
+
+// retransmit - send lost packets from the history again with the same sequence numbers
+func (t *Track) retransmit(nack *rtcp.TransportLayerNack) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.writer == nil || t.history == nil {
+		return
+	}
+
+	for _, pair := range nack.Nacks {
+		for _, seq := range pair.PacketList() {
+			if packet := t.history[seq%historySize]; packet != nil && packet.SequenceNumber == seq {
+				header := packet.Header
+				_, _ = t.writer.WriteRTP(&header, packet.Payload)
+			}
+		}
+	}
+}
@@ -3,6 +3,8 @@ package webrtc
 import (
 	"testing"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 	"github.com/stretchr/testify/require"
 )
@@ -69,3 +71,37 @@ a=fingerprint:sha-256 A2:93:53:50:E4:2F:C5:4E:DF:7C:70:99:5A:A7:39:50:1A:63:E5:B
 	_, err = conn.GetAnswer()
 	require.Nil(t, err)
 }
+
+type writerFunc func(header *rtp.Header, payload []byte) (int, error)
+
+func (f writerFunc) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	return f(header, payload)
+}
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func TestRetransmit(t *testing.T) {
+	var sent []uint16
+
+	track := NewTrack("video")
+	track.writer = writerFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		sent = append(sent, header.SequenceNumber)
+		return len(payload), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Timestamp: uint32(i * 3000), Extension: true, Extensions: []rtp.Extension{{}}},
+			Payload: []byte{byte(i)},
+		}
+		require.Nil(t, track.WriteRTP(96, packet))
+	}
+	require.Equal(t, []uint16{1, 2, 3}, sent)
+
+	// packet 2 lost, packet 5 was never sent
+	track.retransmit(&rtcp.TransportLayerNack{Nacks: []rtcp.NackPair{{PacketID: 2}, {PacketID: 5}}})
+	require.Equal(t, []uint16{1, 2, 3, 2}, sent)
+	require.False(t, track.history[2].Extension)
+}
@@ -26,6 +26,8 @@ export class VideoRTC extends HTMLElement {
             'avc1.64002A',      // H.264 high 4.2 (Chromecast 3rd Gen)
             'avc1.640033',      // H.264 high 5.1 (Chromecast with Google TV)
             'hvc1.1.6.L153.B0', // H.265 main 5.1 (Chromecast Ultra)
+            'av01.0.08M.08',    // AV1 main 4.0
+            'vp09.00.41.08',    // VP9 profile 0 4.1
             'mp4a.40.2',        // AAC LC
             'mp4a.40.5',        // AAC HE
             'flac',             // FLAC (PCM compatible)
@@ -185,7 +187,7 @@ export class VideoRTC extends HTMLElement {
     /** @param {Function} isSupported */
     codecs(isSupported) {
         return this.CODECS
-            .filter(codec => this.media.indexOf(codec.indexOf('vc1') > 0 ? 'video' : 'audio') >= 0)
+            .filter(codec => this.media.indexOf(/^(avc1|hvc1|av01|vp09)\./.test(codec) ? 'video' : 'audio') >= 0)
             .filter(codec => isSupported(`video/mp4; codecs="${codec}"`)).join();
     }
 
@@ -517,6 +519,14 @@ export class VideoRTC extends HTMLElement {
 
         this.onmessage['webrtc'] = msg => {
             switch (msg.type) {
+                case 'webrtc/ice_servers':
+                    if (msg.value && msg.value.length) {
+                        pc.setConfiguration({...this.pcConfig, iceServers: msg.value});
+                    }
+                    this.createOffer(pc).then(offer => {
+                        this.send({type: 'webrtc/offer', value: offer.sdp});
+                    });
+                    break;
                 case 'webrtc/candidate':
                     if (this.mode.indexOf('webrtc/tcp') >= 0 && msg.value.indexOf(' udp ') > 0) return;
 
@@ -555,9 +565,8 @@ export class VideoRTC extends HTMLElement {
             }
         };
 
-        this.createOffer(pc).then(offer => {
-            this.send({type: 'webrtc/offer', value: offer.sdp});
-        });
+        // ask for the ICE servers (ex. embedded TURN server) before the offer
+        this.send({type: 'webrtc/ice_servers'});
 
         this.pcState = WebSocket.CONNECTING;
         this.pc = pc;